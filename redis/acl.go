@@ -0,0 +1,247 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AclRules are the rules of ACL SETUSER, built by chaining:
+//
+//	rules := redis.AclRules{}.On().AddPassword("secret").KeyPattern("app:*").AllowCategory("read").AllowCommand("set")
+//	client.AclSetUser("app", rules)
+//
+// The rules are applied in order.
+type AclRules []string
+
+// Rule returns the rules with a rule as it is, e.g. "%R~cache:*".
+func (r AclRules) Rule(rule ...string) AclRules {
+	return append(r, rule...)
+}
+
+// Reset returns the rules with reset, which removes the passwords, the commands, the keys and the channels
+// of the user and disables it.
+func (r AclRules) Reset() AclRules {
+	return append(r, "reset")
+}
+
+// On returns the rules with on, which enables the user.
+func (r AclRules) On() AclRules {
+	return append(r, "on")
+}
+
+// Off returns the rules with off, which disables the user.
+func (r AclRules) Off() AclRules {
+	return append(r, "off")
+}
+
+// NoPass returns the rules with nopass, any password authenticates the user.
+func (r AclRules) NoPass() AclRules {
+	return append(r, "nopass")
+}
+
+// ResetPass returns the rules with resetpass, which removes the passwords of the user.
+func (r AclRules) ResetPass() AclRules {
+	return append(r, "resetpass")
+}
+
+// AddPassword returns the rules with >password.
+func (r AclRules) AddPassword(password string) AclRules {
+	return append(r, ">"+password)
+}
+
+// RemovePassword returns the rules with <password.
+func (r AclRules) RemovePassword(password string) AclRules {
+	return append(r, "<"+password)
+}
+
+// AddHashedPassword returns the rules with #hash, the hex SHA-256 of a password.
+func (r AclRules) AddHashedPassword(hash string) AclRules {
+	return append(r, "#"+hash)
+}
+
+// KeyPattern returns the rules with ~pattern, the keys matching pattern are read and written.
+func (r AclRules) KeyPattern(pattern string) AclRules {
+	return append(r, "~"+pattern)
+}
+
+// ReadKeyPattern returns the rules with %R~pattern, the keys matching pattern are only read.
+func (r AclRules) ReadKeyPattern(pattern string) AclRules {
+	return append(r, "%R~"+pattern)
+}
+
+// WriteKeyPattern returns the rules with %W~pattern, the keys matching pattern are only written.
+func (r AclRules) WriteKeyPattern(pattern string) AclRules {
+	return append(r, "%W~"+pattern)
+}
+
+// AllKeys returns the rules with allkeys.
+func (r AclRules) AllKeys() AclRules {
+	return append(r, "allkeys")
+}
+
+// ResetKeys returns the rules with resetkeys.
+func (r AclRules) ResetKeys() AclRules {
+	return append(r, "resetkeys")
+}
+
+// ChannelPattern returns the rules with &pattern.
+func (r AclRules) ChannelPattern(pattern string) AclRules {
+	return append(r, "&"+pattern)
+}
+
+// AllChannels returns the rules with allchannels.
+func (r AclRules) AllChannels() AclRules {
+	return append(r, "allchannels")
+}
+
+// ResetChannels returns the rules with resetchannels.
+func (r AclRules) ResetChannels() AclRules {
+	return append(r, "resetchannels")
+}
+
+// AllowCommand returns the rules with +command for each command, e.g. "get" or "client|id".
+func (r AclRules) AllowCommand(command ...string) AclRules {
+	for _, c := range command {
+		r = append(r, "+"+c)
+	}
+	return r
+}
+
+// DenyCommand returns the rules with -command for each command.
+func (r AclRules) DenyCommand(command ...string) AclRules {
+	for _, c := range command {
+		r = append(r, "-"+c)
+	}
+	return r
+}
+
+// AllowCategory returns the rules with +@category for each category, e.g. "read".
+func (r AclRules) AllowCategory(category ...string) AclRules {
+	for _, c := range category {
+		r = append(r, "+@"+c)
+	}
+	return r
+}
+
+// DenyCategory returns the rules with -@category for each category.
+func (r AclRules) DenyCategory(category ...string) AclRules {
+	for _, c := range category {
+		r = append(r, "-@"+c)
+	}
+	return r
+}
+
+// AllCommands returns the rules with allcommands.
+func (r AclRules) AllCommands() AclRules {
+	return append(r, "allcommands")
+}
+
+// NoCommands returns the rules with nocommands.
+func (r AclRules) NoCommands() AclRules {
+	return append(r, "nocommands")
+}
+
+// AclUser is the reply of ACL GETUSER.
+type AclUser struct {
+	// Flags are e.g. on, off or nopass.
+	Flags []string
+	// Passwords are the hex SHA-256 of the passwords.
+	Passwords []string
+	// Commands, Keys and Channels are the rules of the user, e.g. "+@all -flushall", "~app:*" and "&*".
+	Commands string
+	Keys     string
+	Channels string
+}
+
+// Enabled reports whether the user has the on flag.
+func (u *AclUser) Enabled() bool {
+	for _, f := range u.Flags {
+		if f == "on" {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAclUser(reply interface{}) (*AclUser, error) {
+	a, err := Values(reply)
+	if err != nil {
+		return nil, err
+	}
+	u := &AclUser{}
+	for i := 0; i+1 < len(a); i += 2 {
+		name, _ := String(a[i])
+		v := a[i+1]
+		switch name {
+		case "flags":
+			u.Flags, err = StringSlice(v)
+		case "passwords":
+			u.Passwords, err = StringSlice(v)
+		case "commands":
+			u.Commands, err = aclRuleText(v)
+		case "keys":
+			u.Keys, err = aclRuleText(v)
+		case "channels":
+			u.Channels, err = aclRuleText(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// aclRuleText returns a rule of ACL GETUSER, a string since Redis 7.0, an array of patterns before.
+func aclRuleText(v interface{}) (string, error) {
+	if _, ok := v.([]interface{}); ok {
+		s, err := StringSlice(v)
+		return strings.Join(s, " "), err
+	}
+	return String(v)
+}
+
+// AclLogEntry is an entry of the reply of ACL LOG, a denied command or a failed authentication.
+type AclLogEntry struct {
+	// Count is the number of the denials of the entry.
+	Count int64 `redis:"count"`
+	// Reason is auth, command, key or channel.
+	Reason string `redis:"reason"`
+	// Context is toplevel, multi or lua.
+	Context string `redis:"context"`
+	// Object is the command, the key or the channel denied, AUTH for an authentication.
+	Object   string `redis:"object"`
+	Username string `redis:"username"`
+	Age      time.Duration
+	// ClientInfo is the client of the last denial.
+	ClientInfo  ClientInfo
+	EntryID     int64 `redis:"entry-id"`
+	Created     time.Time
+	LastUpdated time.Time
+}
+
+func parseAclLog(reply interface{}) ([]AclLogEntry, error) {
+	return Slice(reply, func(p interface{}) (AclLogEntry, error) {
+		var e AclLogEntry
+		fields, err := StringMap(p)
+		if err != nil {
+			return e, err
+		}
+		scanFields(&e, fields)
+		if age, err := strconv.ParseFloat(fields["age-seconds"], 64); err == nil {
+			e.Age = time.Duration(age * float64(time.Second))
+		}
+		e.ClientInfo = parseClientInfo(fields["client-info"])
+		if ms, err := strconv.ParseInt(fields["timestamp-created"], 10, 64); err == nil {
+			e.Created = time.UnixMilli(ms)
+		}
+		if ms, err := strconv.ParseInt(fields["timestamp-last-updated"], 10, 64); err == nil {
+			e.LastUpdated = time.UnixMilli(ms)
+		}
+		return e, nil
+	})
+}
@@ -0,0 +1,36 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+// BitFieldOps are the operations of BITFIELD and BITFIELD_RO, built by chaining:
+//
+//	ops := redis.BitFieldOps{}.Overflow("SAT").IncrBy("u8", "#0", 1).Get("u8", "#1")
+//	values, err := client.BitField("counters", ops)
+//
+// encoding is i or u followed by the number of bits, e.g. "i5" or "u8", offset is a bit offset,
+// or a multiple of the width of the encoding when prefixed by #, e.g. "#2".
+// The operations are executed in order.
+type BitFieldOps []interface{}
+
+// Get returns the operations with GET encoding offset.
+func (ops BitFieldOps) Get(encoding string, offset interface{}) BitFieldOps {
+	return append(ops, "GET", encoding, offset)
+}
+
+// Set returns the operations with SET encoding offset value, which replies the old value.
+func (ops BitFieldOps) Set(encoding string, offset interface{}, value int64) BitFieldOps {
+	return append(ops, "SET", encoding, offset, value)
+}
+
+// IncrBy returns the operations with INCRBY encoding offset increment, which replies the new value.
+func (ops BitFieldOps) IncrBy(encoding string, offset interface{}, increment int64) BitFieldOps {
+	return append(ops, "INCRBY", encoding, offset, increment)
+}
+
+// Overflow returns the operations with OVERFLOW WRAP|SAT|FAIL, the behavior of the SET and INCRBY which follow:
+// WRAP wraps around, SAT saturates to the minimum or the maximum value, FAIL skips the operation, which replies nil.
+func (ops BitFieldOps) Overflow(behavior string) BitFieldOps {
+	return append(ops, "OVERFLOW", behavior)
+}
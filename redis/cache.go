@@ -0,0 +1,465 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOptions configures a client created by NewCachingClient.
+type CacheOptions struct {
+	LogOptions
+	// BCast enables the broadcasting mode of the tracking: the server invalidates every key
+	// matching Prefixes instead of remembering the keys read by the client.
+	BCast bool
+	// Prefixes restricts the broadcasting mode to the keys starting with one of the prefixes.
+	Prefixes []string
+	// MaxEntries bounds the number of cached replies, 10000 by default.
+	// The least recently used reply is evicted when the cache is full.
+	MaxEntries int
+	// TTL bounds the lifetime of a cached reply, 1 minute by default.
+	TTL time.Duration
+}
+
+// CacheStats are the counters of a local cache.
+type CacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Entries       int
+}
+
+// CachingClient is a Client which serves the cacheable reads from a local cache.
+type CachingClient struct {
+	Client
+	cache *cacheConn
+}
+
+// CacheStats returns the counters of the local cache.
+func (c *CachingClient) CacheStats() CacheStats {
+	return c.cache.stats()
+}
+
+// cacheableCommands are the single-key reads served from the local cache.
+var cacheableCommands = map[string]bool{
+	"GET": true, "GETRANGE": true, "STRLEN": true,
+	"HGET": true, "HGETALL": true, "HMGET": true, "HKEYS": true, "HVALS": true, "HLEN": true,
+	"LINDEX": true, "LLEN": true, "LRANGE": true,
+	"SCARD": true, "SISMEMBER": true, "SMEMBERS": true,
+	"ZCARD": true, "ZRANGE": true, "ZRANK": true, "ZSCORE": true,
+}
+
+const invalidateChannel = "__redis__:invalidate"
+
+type cacheEntry struct {
+	id      string
+	key     string
+	reply   interface{}
+	expires time.Time
+}
+
+// cacheConn caches the replies of the cacheable commands and evicts them on the invalidation messages
+// of CLIENT TRACKING, which are redirected to a second connection subscribed to __redis__:invalidate.
+type cacheConn struct {
+	cnMu    sync.Mutex // serializes the use of the data connection
+	mu      sync.Mutex // guards the cached replies
+	cn      Conn
+	inv     Conn // the current invalidation connection, replaced under cnMu
+	url     string
+	opt     CacheOptions
+	lru     *list.List
+	entries map[string]*list.Element
+	keys    map[string]map[string]*list.Element
+	seq     uint64
+	tracked bool   // the invalidations are received, the cache is bypassed otherwise
+	db      string // the database selected by the last SELECT, part of the identity of a cached reply
+	st      CacheStats
+	dial    dialFunc
+	log     *slog.Logger
+	done    chan struct{}
+}
+
+// NewCachingClient returns a client which caches the replies of the single-key reads (GET, HGETALL, ...)
+// in process memory. The cached replies are evicted by the server-assisted invalidation of CLIENT TRACKING,
+// in the default mode or in the broadcasting mode with opt.Prefixes.
+// The tracking uses the REDIRECT option so it works on the RESP2 protocol of the client.
+// The cache is flushed and bypassed while the data connection or the invalidation connection is re-established.
+func NewCachingClient(url string, opt CacheOptions) (CachingClient, error) {
+	if opt.MaxEntries <= 0 {
+		opt.MaxEntries = 10000
+	}
+	if opt.TTL <= 0 {
+		opt.TTL = time.Minute
+	}
+	cn, err := Dial(url)
+	if err != nil {
+		return CachingClient{}, err
+	}
+	cc := &cacheConn{
+		cn:      cn,
+		url:     url,
+		opt:     opt,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		keys:    make(map[string]map[string]*list.Element),
+		log:     opt.Logger,
+		db:      "0",
+		done:    make(chan struct{}),
+	}
+	if err := cc.track(); err != nil {
+		cn.Close()
+		return CachingClient{}, err
+	}
+	cc.tracked = true
+	go cc.listen(cc.inv)
+	cli := Client{cn: cc}
+	cli.SetLogger(opt.LogOptions)
+	return CachingClient{Client: cli, cache: cc}, nil
+}
+
+// track opens the invalidation connection and enables the tracking of the data connection, redirected to it.
+func (cc *cacheConn) track() error {
+	inv, err := dialWith(cc.dial, cc.url)
+	if err != nil {
+		return err
+	}
+	rsp, err := inv.Send("CLIENT", "ID")
+	if err != nil {
+		inv.Close()
+		return err
+	}
+	id, err := Int(rsp)
+	if err != nil {
+		inv.Close()
+		return fmt.Errorf("redis: CLIENT ID: %v", rsp)
+	}
+	if err := inv.Pipe("SUBSCRIBE", invalidateChannel); err != nil {
+		inv.Close()
+		return err
+	}
+	if err := inv.Flush(); err != nil {
+		inv.Close()
+		return err
+	}
+	if _, err := inv.Receive(); err != nil {
+		inv.Close()
+		return err
+	}
+
+	args := []interface{}{"TRACKING", "ON", "REDIRECT", id}
+	if cc.opt.BCast {
+		args = append(args, "BCAST")
+		for _, p := range cc.opt.Prefixes {
+			args = append(args, "PREFIX", p)
+		}
+	}
+	rsp, err = cc.cn.Send("CLIENT", args...)
+	if err == nil {
+		err, _ = rsp.(error)
+	}
+	if err != nil {
+		inv.Close()
+		return err
+	}
+	cc.inv = inv
+	return nil
+}
+
+// listen evicts the keys of the invalidation messages:
+//
+//	message __redis__:invalidate [key ...]
+//
+// A nil key list means that the server flushed its keyspace. When the invalidation connection is lost
+// the whole cache is flushed and bypassed until the tracking is enabled again on a new invalidation connection.
+func (cc *cacheConn) listen(inv Conn) {
+	for {
+		rsp, err := inv.Receive()
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				continue
+			}
+			logAttr(cc.log, slog.LevelWarn, "redis: invalidation connection lost, cache flushed", "error", err)
+			break
+		}
+		m, ok := rsp.([]interface{})
+		if !ok || len(m) != 3 {
+			continue
+		}
+		if k, _ := String(m[0]); k != "message" {
+			continue
+		}
+		keys, _ := m[2].([]interface{})
+		cc.mu.Lock()
+		cc.seq++
+		if m[2] == nil {
+			cc.flush()
+		}
+		for _, key := range keys {
+			k, _ := String(key)
+			cc.invalidate(k)
+		}
+		cc.mu.Unlock()
+	}
+	inv.Close()
+	cc.cnMu.Lock()
+	if cc.inv != inv {
+		// redial already enabled the tracking on a new invalidation connection.
+		cc.cnMu.Unlock()
+		return
+	}
+	cc.untrack()
+	cc.cnMu.Unlock()
+
+	for {
+		select {
+		case <-cc.done:
+			return
+		case <-time.After(time.Second):
+		}
+		cc.cnMu.Lock()
+		if cc.inv != inv {
+			cc.cnMu.Unlock()
+			return
+		}
+		if _, err := cc.cn.Send("CLIENT", "TRACKING", "OFF"); err != nil {
+			// The data connection is lost too.
+			if r, ok := cc.cn.(redialer); ok {
+				r.redial()
+			}
+		}
+		err := cc.retrack()
+		cc.cnMu.Unlock()
+		if err == nil {
+			logAttr(cc.log, slog.LevelInfo, "redis: tracking enabled again")
+			return
+		}
+	}
+}
+
+// retrack enables the tracking on a new invalidation connection and listens to it, the previous one is closed.
+// cnMu is held.
+func (cc *cacheConn) retrack() error {
+	old := cc.inv
+	if err := cc.track(); err != nil {
+		return err
+	}
+	cc.mu.Lock()
+	cc.tracked = true
+	cc.mu.Unlock()
+	old.Close()
+	go cc.listen(cc.inv)
+	return nil
+}
+
+// untrack flushes the cache and bypasses it until the tracking is enabled again. cnMu is held.
+func (cc *cacheConn) untrack() {
+	cc.mu.Lock()
+	cc.tracked = false
+	cc.seq++
+	cc.flush()
+	cc.mu.Unlock()
+}
+
+// failed drops the tracking after an error of the data connection, which loses its tracking with it.
+// The invalidation connection is closed so listen enables the tracking again. cnMu is held.
+func (cc *cacheConn) failed(err error) error {
+	if err != nil {
+		cc.untrack()
+		cc.inv.Close()
+	}
+	return err
+}
+
+// redial re-establishes the data connection after a network error and enables the tracking again,
+// the cache is flushed and bypassed meanwhile.
+func (cc *cacheConn) redial() error {
+	cc.cnMu.Lock()
+	defer cc.cnMu.Unlock()
+	cc.untrack()
+	if r, ok := cc.cn.(redialer); ok {
+		if err := r.redial(); err != nil {
+			cc.inv.Close()
+			return err
+		}
+	}
+	if err := cc.retrack(); err != nil {
+		// listen enables the tracking later.
+		cc.inv.Close()
+		return err
+	}
+	return nil
+}
+
+func (cc *cacheConn) notifyDial(fn dialFunc) {
+	cc.cnMu.Lock()
+	defer cc.cnMu.Unlock()
+	cc.dial = fn
+}
+
+func (cc *cacheConn) setLogger(l *slog.Logger) {
+	cc.cnMu.Lock()
+	defer cc.cnMu.Unlock()
+	cc.log = l
+	if s, ok := cc.cn.(loggerSetter); ok {
+		s.setLogger(l)
+	}
+}
+
+// invalidate removes all the cached replies of the key.
+func (cc *cacheConn) invalidate(key string) {
+	for _, el := range cc.keys[key] {
+		cc.remove(el)
+		cc.st.Invalidations++
+	}
+}
+
+func (cc *cacheConn) flush() {
+	cc.st.Invalidations += uint64(cc.lru.Len())
+	cc.lru.Init()
+	cc.entries = make(map[string]*list.Element)
+	cc.keys = make(map[string]map[string]*list.Element)
+}
+
+func (cc *cacheConn) remove(el *list.Element) {
+	e := el.Value.(*cacheEntry)
+	cc.lru.Remove(el)
+	delete(cc.entries, e.id)
+	if m := cc.keys[e.key]; m != nil {
+		delete(m, e.id)
+		if len(m) == 0 {
+			delete(cc.keys, e.key)
+		}
+	}
+}
+
+func (cc *cacheConn) add(id, key string, reply interface{}) {
+	if el, ok := cc.entries[id]; ok {
+		cc.remove(el)
+	}
+	for cc.lru.Len() >= cc.opt.MaxEntries {
+		cc.remove(cc.lru.Back())
+		cc.st.Evictions++
+	}
+	el := cc.lru.PushFront(&cacheEntry{id: id, key: key, reply: reply, expires: time.Now().Add(cc.opt.TTL)})
+	cc.entries[id] = el
+	if cc.keys[key] == nil {
+		cc.keys[key] = make(map[string]*list.Element)
+	}
+	cc.keys[key][id] = el
+}
+
+func (cc *cacheConn) stats() CacheStats {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	st := cc.st
+	st.Entries = cc.lru.Len()
+	return st
+}
+
+// copyReply returns a deep copy of a reply, so a cached reply is never modified by the parsers of the callers.
+func copyReply(p interface{}) interface{} {
+	switch v := p.(type) {
+	case []byte:
+		return append([]byte{}, v...)
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = copyReply(e)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+func (cc *cacheConn) Send(cmd string, args ...interface{}) (reply interface{}, err error) {
+	cc.cnMu.Lock()
+	defer cc.cnMu.Unlock()
+	c := strings.ToUpper(cmd)
+	if !cacheableCommands[c] || len(args) == 0 {
+		reply, err = cc.cn.Send(cmd, args...)
+		if _, e := reply.(error); c == "SELECT" && len(args) == 1 && err == nil && !e {
+			cc.mu.Lock()
+			cc.db = fmt.Sprint(args[0])
+			cc.mu.Unlock()
+		}
+		return reply, cc.failed(err)
+	}
+	key := fmt.Sprint(args[0])
+	if b, ok := args[0].([]byte); ok {
+		key = string(b)
+	}
+	cc.mu.Lock()
+	id := cc.db + " " + c + fmt.Sprintf("%q", args)
+	if !cc.tracked {
+		cc.mu.Unlock()
+		reply, err = cc.cn.Send(cmd, args...)
+		return reply, cc.failed(err)
+	}
+	if el, ok := cc.entries[id]; ok {
+		e := el.Value.(*cacheEntry)
+		if time.Now().Before(e.expires) {
+			cc.lru.MoveToFront(el)
+			cc.st.Hits++
+			cc.mu.Unlock()
+			return copyReply(e.reply), nil
+		}
+		cc.remove(el)
+	}
+	cc.st.Misses++
+
+	seq := cc.seq
+	cc.mu.Unlock()
+	reply, err = cc.cn.Send(cmd, args...)
+	cc.mu.Lock()
+	if _, e := reply.(error); err == nil && !e && seq == cc.seq {
+		cc.add(id, key, copyReply(reply))
+	}
+	cc.mu.Unlock()
+	return reply, cc.failed(err)
+}
+
+func (cc *cacheConn) Pipe(cmd string, args ...interface{}) error {
+	cc.cnMu.Lock()
+	defer cc.cnMu.Unlock()
+	return cc.failed(cc.cn.Pipe(cmd, args...))
+}
+
+func (cc *cacheConn) Flush() error {
+	cc.cnMu.Lock()
+	defer cc.cnMu.Unlock()
+	return cc.failed(cc.cn.Flush())
+}
+
+func (cc *cacheConn) Receive() (reply interface{}, err error) {
+	cc.cnMu.Lock()
+	defer cc.cnMu.Unlock()
+	reply, err = cc.cn.Receive()
+	return reply, cc.failed(err)
+}
+
+func (cc *cacheConn) Close() error {
+	cc.cnMu.Lock()
+	defer cc.cnMu.Unlock()
+	select {
+	case <-cc.done:
+		return errors.New("redis: client is closed.")
+	default:
+		close(cc.done)
+	}
+	cc.inv.Close()
+	cc.mu.Lock()
+	cc.flush()
+	cc.mu.Unlock()
+	return cc.cn.Close()
+}
@@ -0,0 +1,173 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"github.com/qqbuby/goredis/redis"
+	"testing"
+	"time"
+)
+
+func TestCachingClient(t *testing.T) {
+	const (
+		key    = "TEST:CACHE"
+		value1 = "value1"
+		value2 = "value2"
+	)
+	cc, err := redis.NewCachingClient(url, redis.CacheOptions{})
+	if err != nil {
+		t.Fatalf("NewCachingClient: %s", err.Error())
+	}
+	defer cc.Close()
+
+	client.Set(key, value1)
+	cc.Get(key)
+	v, _ := cc.Get(key)
+	if v != value1 {
+		t.Errorf("CachingClient did not work properly. E:%s, R:%v", value1, v)
+	}
+	if st := cc.CacheStats(); st.Hits != 1 || st.Misses != 1 {
+		t.Errorf("CachingClient did not work properly. Hits:%d, Misses:%d", st.Hits, st.Misses)
+	}
+
+	client.Set(key, value2)
+	for i := 0; i < 100 && cc.CacheStats().Invalidations == 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	v, _ = cc.Get(key)
+	if v != value2 {
+		t.Errorf("CachingClient did not work properly. E:%s, R:%v", value2, v)
+	}
+}
+
+func TestCachingClientReconnect(t *testing.T) {
+	const (
+		key    = "TEST:CACHE:RECONNECT"
+		value1 = "value1"
+		value2 = "value2"
+	)
+	srv, cli := newScratchClient(t)
+	cc, err := redis.NewCachingClient(srv.URL(), redis.CacheOptions{})
+	if err != nil {
+		t.Fatalf("NewCachingClient: %s", err.Error())
+	}
+	defer cc.Close()
+
+	cli.Set(key, value1)
+	cc.Get(key)
+	if st := cc.CacheStats(); st.Entries != 1 {
+		t.Fatalf("CachingClient did not work properly. E:%d entries, R:%d", 1, st.Entries)
+	}
+	// The invalidation connection is the only subscribed client.
+	if n, err := cli.ClientKill("TYPE", "pubsub"); n != 1 || err != nil {
+		t.Fatalf("ClientKill did not work properly. E:%d, R:%d %v", 1, n, err)
+	}
+	for i := 0; i < 100 && cc.CacheStats().Entries != 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// The cache is bypassed while the invalidations are lost.
+	cli.Set(key, value2)
+	v, _ := cc.Get(key)
+	if v != value2 {
+		t.Errorf("CachingClient did not work properly. E:%s, R:%v", value2, v)
+	}
+	if st := cc.CacheStats(); st.Entries != 0 || st.Hits != 0 {
+		t.Errorf("CachingClient did not work properly. Entries:%d, Hits:%d", st.Entries, st.Hits)
+	}
+
+	// The replies are cached again once the tracking is enabled again.
+	for i := 0; i < 500 && cc.CacheStats().Hits == 0; i++ {
+		cc.Get(key)
+		time.Sleep(time.Millisecond * 10)
+	}
+	if st := cc.CacheStats(); st.Hits == 0 {
+		t.Errorf("CachingClient did not work properly. E:hits, R:%d", st.Hits)
+	}
+	cli.Set(key, value1)
+	for i := 0; i < 100 && cc.CacheStats().Entries != 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	v, _ = cc.Get(key)
+	if v != value1 {
+		t.Errorf("CachingClient did not work properly. E:%s, R:%v", value1, v)
+	}
+}
+
+func TestCachingClientRedial(t *testing.T) {
+	const (
+		key    = "TEST:CACHE:REDIAL"
+		value1 = "value1"
+		value2 = "value2"
+	)
+	srv, cli := newScratchClient(t)
+	cc, err := redis.NewCachingClient(srv.URL(), redis.CacheOptions{})
+	if err != nil {
+		t.Fatalf("NewCachingClient: %s", err.Error())
+	}
+	defer cc.Close()
+
+	cli.Set(key, value1)
+	id, err := cc.ClientID()
+	if err != nil {
+		t.Fatalf("ClientID: %s", err.Error())
+	}
+	// The data connection is killed, its tracking is lost with it.
+	if n, err := cli.ClientKill("ID", id); n != 1 || err != nil {
+		t.Fatalf("ClientKill did not work properly. E:%d, R:%d %v", 1, n, err)
+	}
+	v, err := cc.Get(key)
+	if v != value1 || err != nil {
+		t.Errorf("CachingClient did not work properly. E:%s, R:%v %v", value1, v, err)
+	}
+
+	for i := 0; i < 500 && cc.CacheStats().Hits == 0; i++ {
+		cc.Get(key)
+		time.Sleep(time.Millisecond * 10)
+	}
+	if st := cc.CacheStats(); st.Hits == 0 {
+		t.Errorf("CachingClient did not work properly. E:hits, R:%d", st.Hits)
+	}
+	cli.Set(key, value2)
+	for i := 0; i < 100 && cc.CacheStats().Entries != 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	v, err = cc.Get(key)
+	if v != value2 || err != nil {
+		t.Errorf("CachingClient did not work properly. E:%s, R:%v %v", value2, v, err)
+	}
+}
+
+func TestCachingClientSelect(t *testing.T) {
+	const (
+		key    = "TEST:CACHE:SELECT"
+		value0 = "value0"
+		value1 = "value1"
+	)
+	srv, cli := newScratchClient(t)
+	cc, err := redis.NewCachingClient(srv.URL(), redis.CacheOptions{})
+	if err != nil {
+		t.Fatalf("NewCachingClient: %s", err.Error())
+	}
+	defer cc.Close()
+
+	cli.Set(key, value0)
+	cli.Select(1)
+	cli.Set(key, value1)
+	if v, _ := cc.Get(key); v != value0 {
+		t.Errorf("CachingClient did not work properly. E:%s, R:%v", value0, v)
+	}
+	cc.Select(1)
+	if v, _ := cc.Get(key); v != value1 {
+		t.Errorf("CachingClient did not work properly. E:%s, R:%v", value1, v)
+	}
+	cc.Select(0)
+	if v, _ := cc.Get(key); v != value0 {
+		t.Errorf("CachingClient did not work properly. E:%s, R:%v", value0, v)
+	}
+	if st := cc.CacheStats(); st.Hits != 1 {
+		t.Errorf("CachingClient did not work properly. E:%d hits, R:%d", 1, st.Hits)
+	}
+}
@@ -5,11 +5,20 @@
 package redis
 
 import (
+	"context"
 	"errors"
+	"github.com/qqbuby/goredis/redis/resp"
+	"io"
+	"strings"
+	"time"
 )
 
 type Client struct {
-	cn Conn
+	cn    Conn
+	retry *RetryPolicy
+	hooks *hooks
+	ctx   context.Context
+	codec Codec
 }
 
 func NewClient(url string) (Client, error) {
@@ -22,11 +31,26 @@ func (cli *Client) Close() (err error) {
 	return cli.cn.Close()
 }
 
+// Send sends a command and returns its reply, the command is retried by the retry policy of the client
+// and runs through the hooks of the client.
 func (cli *Client) Send(cmd string, args ...interface{}) (reply interface{}, err error) {
-	rsp, err := cli.cn.Send(cmd, args...)
+	rsp, err := cli.hooks.process(cli.Context(), cmd, args, cli.sendRetry)
 	return rsp, err
 }
 
+// SendCommand sends a command built by resp.Command and returns its reply. The command is written as it
+// is built, without converting its arguments to interfaces, and is retried as by Send. When the client
+// has hooks or its connection cannot write a built command, it is sent by Send with its Args.
+func (cli *Client) SendCommand(cmd *resp.Command) (reply interface{}, err error) {
+	cs, ok := cli.cn.(commandSender)
+	if !ok || len(cli.hooks.get()) > 0 {
+		return cli.Send(cmd.Name(), cmd.Args()...)
+	}
+	return cli.retryWith(cmd.Name(), func() (interface{}, error) {
+		return cs.sendCommand(cmd)
+	})
+}
+
 // CONNECTION:BEGIN
 
 // AUTH password
@@ -41,6 +65,144 @@ func (cli *Client) Auth(password string) (string, error) {
 	return v, e
 }
 
+// AUTH username password
+// Authenticate to the server as an ACL user
+// Simple string reply
+func (cli *Client) AuthUser(username, password string) (string, error) {
+	rsp, err := cli.Send("AUTH", username, password)
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// CLIENT GETNAME
+// Get the current connection name
+// Bulk string reply: The connection name, or an empty string if no name is set.
+func (cli *Client) ClientGetName() (string, error) {
+	rsp, err := cli.Send("CLIENT", "GETNAME")
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// CLIENT ID
+// Returns the client ID for the current connection
+// Integer reply: The id of the client.
+func (cli *Client) ClientID() (int64, error) {
+	rsp, err := cli.Send("CLIENT", "ID")
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int64(rsp)
+	return v, e
+}
+
+// CLIENT INFO
+// Returns information about the current client connection
+// Bulk string reply: a unique string, as for CLIENT LIST, parsed.
+func (cli *Client) ClientInfo() (*ClientInfo, error) {
+	rsp, err := cli.Send("CLIENT", "INFO")
+	if err != nil {
+		return nil, err
+	}
+	v, e := Bytes(rsp)
+	if e != nil {
+		return nil, e
+	}
+	c := parseClientInfo(strings.TrimSpace(string(v)))
+	return &c, nil
+}
+
+// CLIENT KILL [ip:port] [ID client-id] [TYPE normal|master|slave|pubsub] [USER username] [ADDR ip:port] [LADDR ip:port] [SKIPME yes/no] [MAXAGE seconds]
+// Kill the connections of the clients matching the filters, e.g. ClientKill("ID", 42) or ClientKill("127.0.0.1:50000").
+// Integer reply: the number of clients killed, 1 for the client of an address.
+func (cli *Client) ClientKill(filter ...interface{}) (int, error) {
+	rsp, err := cli.Send("CLIENT", append([]interface{}{"KILL"}, filter...)...)
+	if err != nil {
+		return -1, err
+	}
+	if b, ok := rsp.([]byte); ok && string(b) == "OK" {
+		return 1, nil
+	}
+	v, e := Int(rsp)
+	return v, e
+}
+
+// CLIENT LIST [TYPE normal|master|replica|pubsub] [ID client-id [client-id ...]]
+// Get the list of client connections
+// Bulk string reply: a unique string, formatted as a client per line, parsed.
+func (cli *Client) ClientList(p ...interface{}) ([]ClientInfo, error) {
+	rsp, err := cli.Send("CLIENT", append([]interface{}{"LIST"}, p...)...)
+	if err != nil {
+		return nil, err
+	}
+	v, e := Bytes(rsp)
+	if e != nil {
+		return nil, e
+	}
+	return ParseClientList(string(v)), nil
+}
+
+// CLIENT NO-EVICT ON|OFF
+// Set client eviction mode for the current connection
+// Simple string reply: OK.
+func (cli *Client) ClientNoEvict(on bool) (string, error) {
+	mode := "OFF"
+	if on {
+		mode = "ON"
+	}
+	rsp, err := cli.Send("CLIENT", "NO-EVICT", mode)
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// CLIENT PAUSE timeout [WRITE|ALL]
+// Stop processing commands from clients for some time, or only the write commands when writeOnly is true
+// Simple string reply: OK or an error if the timeout is invalid.
+func (cli *Client) ClientPause(timeout time.Duration, writeOnly bool) (string, error) {
+	args := []interface{}{"PAUSE", timeout.Milliseconds()}
+	if writeOnly {
+		args = append(args, "WRITE")
+	}
+	rsp, err := cli.Send("CLIENT", args...)
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// CLIENT SETNAME connection-name
+// Set the current connection name
+// Simple string reply: OK if the connection name was successfully set.
+func (cli *Client) ClientSetName(name string) (string, error) {
+	rsp, err := cli.Send("CLIENT", "SETNAME", name)
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// CLIENT UNPAUSE
+// Resume processing of clients that were paused
+// Simple string reply: OK.
+func (cli *Client) ClientUnpause() (string, error) {
+	rsp, err := cli.Send("CLIENT", "UNPAUSE")
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
 // ECHO message
 // Echo the given string
 // Simple string reply
@@ -91,6 +253,145 @@ func (cli *Client) Select(index int) (string, error) {
 
 // CONNECTION:END
 
+// GEO:BEGIN
+
+// GEOADD key [NX|XX] [CH] longitude latitude member [longitude latitude member ...]
+// Add one or more geospatial items in the geospatial index represented using a sorted set
+// Integer reply: the number of elements added to the sorted set, or changed with CH. condition is "", "NX" or "XX".
+func (cli *Client) GeoAdd(key interface{}, condition string, ch bool, location GeoLocation, locations ...GeoLocation) (int, error) {
+	args := []interface{}{key}
+	if condition != "" {
+		args = append(args, condition)
+	}
+	if ch {
+		args = append(args, "CH")
+	}
+	for _, l := range append([]GeoLocation{location}, locations...) {
+		args = append(args, l.Longitude, l.Latitude, l.Name)
+	}
+	rsp, err := cli.Send("GEOADD", args...)
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int(rsp)
+	return v, e
+}
+
+// GEODIST key member1 member2 [M|KM|FT|MI]
+// Returns the distance between two members of a geospatial index
+// Bulk string reply: the distance in the unit, meters when unit is "". Nil when a member does not exist.
+func (cli *Client) GeoDist(key, member1, member2 interface{}, unit string) (float64, error) {
+	args := []interface{}{key, member1, member2}
+	if unit != "" {
+		args = append(args, unit)
+	}
+	rsp, err := cli.Send("GEODIST", args...)
+	if err != nil {
+		return -1, err
+	}
+	v, e := Float64(rsp)
+	return v, e
+}
+
+// GEOHASH key member [member ...]
+// Returns members of a geospatial index as standard geohash strings
+// Array reply: the geohash of each member, "" when a member does not exist.
+func (cli *Client) GeoHash(key, member interface{}, members ...interface{}) ([]string, error) {
+	rsp, err := cli.Send("GEOHASH", MakeSlice(members, key, member)...)
+	if err != nil {
+		return nil, err
+	}
+	return Slice(rsp, func(p interface{}) (string, error) {
+		if p == nil {
+			return "", nil
+		}
+		return String(p)
+	})
+}
+
+// GEOPOS key member [member ...]
+// Returns longitude and latitude of members of a geospatial index
+// Array reply: the longitude and the latitude of each member, nil when a member does not exist.
+func (cli *Client) GeoPos(key, member interface{}, members ...interface{}) ([]*[2]float64, error) {
+	rsp, err := cli.Send("GEOPOS", MakeSlice(members, key, member)...)
+	if err != nil {
+		return nil, err
+	}
+	v, e := Positions(rsp)
+	return v, e
+}
+
+// GEOSEARCH key FROMMEMBER member|FROMLONLAT longitude latitude BYRADIUS radius unit|BYBOX width height unit
+//     [ASC|DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]
+// Query a geospatial index for members inside an area of a box or a circle
+// Array reply: the locations, with the position, the distance and the geohash requested by the query.
+func (cli *Client) GeoSearch(key interface{}, query GeoSearchQuery) ([]GeoLocation, error) {
+	rsp, err := cli.Send("GEOSEARCH", append([]interface{}{key}, query.args(true)...)...)
+	if err != nil {
+		return nil, err
+	}
+	return query.parseGeoLocations(rsp)
+}
+
+// GEOSEARCHSTORE destination source FROMMEMBER member|FROMLONLAT longitude latitude
+//     BYRADIUS radius unit|BYBOX width height unit [ASC|DESC] [COUNT count [ANY]] [STOREDIST]
+// Query a geospatial index for members inside an area of a box or a circle, and store the result in another key
+// Integer reply: the number of elements in the resulting set. storeDist stores the distances as the scores.
+func (cli *Client) GeoSearchStore(destination, source interface{}, query GeoSearchQuery, storeDist bool) (int, error) {
+	args := append([]interface{}{destination, source}, query.args(false)...)
+	if storeDist {
+		args = append(args, "STOREDIST")
+	}
+	rsp, err := cli.Send("GEOSEARCHSTORE", args...)
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int(rsp)
+	return v, e
+}
+
+// GEO:END
+
+// HYPERLOGLOG:BEGIN
+
+// PFADD key [element [element ...]]
+// Adds the specified elements to the specified HyperLogLog
+// Integer reply: 1 if the approximated cardinality was altered, 0 otherwise.
+func (cli *Client) PfAdd(key interface{}, elements ...interface{}) (int, error) {
+	rsp, err := cli.Send("PFADD", MakeSlice(elements, key)...)
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int(rsp)
+	return v, e
+}
+
+// PFCOUNT key [key ...]
+// Return the approximated cardinality of the set(s) observed by the HyperLogLog at key(s)
+// Integer reply: the approximated cardinality of the union of the HyperLogLogs.
+func (cli *Client) PfCount(key interface{}, keys ...interface{}) (int64, error) {
+	rsp, err := cli.Send("PFCOUNT", MakeSlice(keys, key)...)
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int64(rsp)
+	return v, e
+}
+
+// PFMERGE destkey sourcekey [sourcekey ...]
+// Merge N different HyperLogLogs into a single one
+// Simple string reply: OK.
+func (cli *Client) PfMerge(destkey, sourcekey interface{}, sourcekeys ...interface{}) (string, error) {
+	rsp, err := cli.Send("PFMERGE", MakeSlice(sourcekeys, destkey, sourcekey)...)
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// HYPERLOGLOG:END
+
 // KEYS:BEGIN
 
 // DEL key [key ...]
@@ -423,6 +724,390 @@ func (cli *Client) Unsubscribe(channel interface{}, channels ...interface{}) err
 
 // PUBSUB:BEGIN
 
+// SERVER:BEGIN
+
+// ACL CAT [categoryname]
+// List the ACL categories, or the commands of a category
+// Array reply: a list of categories or commands.
+func (cli *Client) AclCat(category ...string) ([]string, error) {
+	args := []interface{}{"CAT"}
+	if len(category) > 0 {
+		args = append(args, category[0])
+	}
+	rsp, err := cli.Send("ACL", args...)
+	if err != nil {
+		return nil, err
+	}
+	v, e := StringSlice(rsp)
+	return v, e
+}
+
+// ACL DELUSER username [username ...]
+// Remove the specified ACL users and the associated rules
+// Integer reply: The number of users that were deleted.
+func (cli *Client) AclDelUser(username string, usernames ...string) (int, error) {
+	args := []interface{}{"DELUSER", username}
+	for _, u := range usernames {
+		args = append(args, u)
+	}
+	rsp, err := cli.Send("ACL", args...)
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int(rsp)
+	return v, e
+}
+
+// ACL DRYRUN username command [arg [arg ...]]
+// Simulate the execution of a given command by a given user
+// Simple string reply: OK on success, Bulk string reply: the reason of the denial.
+func (cli *Client) AclDryRun(username, command string, args ...interface{}) (string, error) {
+	rsp, err := cli.Send("ACL", append([]interface{}{"DRYRUN", username, command}, args...)...)
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// ACL GETUSER username
+// Get the rules for a specific ACL user
+// Array reply: a list of ACL rule definitions for the user, parsed. Nil when the user does not exist.
+func (cli *Client) AclGetUser(username string) (*AclUser, error) {
+	rsp, err := cli.Send("ACL", "GETUSER", username)
+	if err != nil {
+		return nil, err
+	}
+	return parseAclUser(rsp)
+}
+
+// ACL LIST
+// List the current ACL rules in ACL config file format
+// Array reply: An array of strings.
+func (cli *Client) AclList() ([]string, error) {
+	rsp, err := cli.Send("ACL", "LIST")
+	if err != nil {
+		return nil, err
+	}
+	v, e := StringSlice(rsp)
+	return v, e
+}
+
+// ACL LOG [count]
+// List latest events denied because of ACLs in place, 10 by default
+// Array reply: the entries, parsed.
+func (cli *Client) AclLog(count ...int) ([]AclLogEntry, error) {
+	args := []interface{}{"LOG"}
+	if len(count) > 0 {
+		args = append(args, count[0])
+	}
+	rsp, err := cli.Send("ACL", args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseAclLog(rsp)
+}
+
+// ACL LOG RESET
+// Clear the ACL log
+// Simple string reply: OK.
+func (cli *Client) AclLogReset() (string, error) {
+	rsp, err := cli.Send("ACL", "LOG", "RESET")
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// ACL SETUSER username [rule [rule ...]]
+// Modify or create the rules for a specific ACL user
+// Simple string reply: OK on success.
+func (cli *Client) AclSetUser(username string, rules AclRules) (string, error) {
+	args := []interface{}{"SETUSER", username}
+	for _, r := range rules {
+		args = append(args, r)
+	}
+	rsp, err := cli.Send("ACL", args...)
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// ACL USERS
+// List the username of all the configured ACL rules
+// Array reply: list of existing ACL users.
+func (cli *Client) AclUsers() ([]string, error) {
+	rsp, err := cli.Send("ACL", "USERS")
+	if err != nil {
+		return nil, err
+	}
+	v, e := StringSlice(rsp)
+	return v, e
+}
+
+// ACL WHOAMI
+// Return the name of the user associated to the current connection
+// Bulk string reply: the username of the current connection.
+func (cli *Client) AclWhoAmI() (string, error) {
+	rsp, err := cli.Send("ACL", "WHOAMI")
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// BGSAVE
+// Asynchronously save the dataset to disk
+// Simple string reply: Background saving started.
+func (cli *Client) BgSave() (string, error) {
+	rsp, err := cli.Send("BGSAVE")
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// CONFIG GET parameter
+// Get the values of the configuration parameters matching a glob-style pattern
+// Array reply: the names and the values of the parameters, as a map.
+func (cli *Client) ConfigGet(parameter string) (map[string]string, error) {
+	rsp, err := cli.Send("CONFIG", "GET", parameter)
+	if err != nil {
+		return nil, err
+	}
+	v, e := StringMap(rsp)
+	return v, e
+}
+
+// CONFIG RESETSTAT
+// Reset the stats returned by INFO
+// Simple string reply: always OK.
+func (cli *Client) ConfigResetStat() (string, error) {
+	rsp, err := cli.Send("CONFIG", "RESETSTAT")
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// CONFIG REWRITE
+// Rewrite the configuration file with the in memory configuration
+// Simple string reply: OK when the configuration was rewritten properly.
+func (cli *Client) ConfigRewrite() (string, error) {
+	rsp, err := cli.Send("CONFIG", "REWRITE")
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// CONFIG SET parameter value
+// Set a configuration parameter to the given value
+// Simple string reply: OK when the configuration was set properly.
+func (cli *Client) ConfigSet(parameter string, value interface{}) (string, error) {
+	rsp, err := cli.Send("CONFIG", "SET", parameter, value)
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// DBSIZE
+// Return the number of keys in the selected database
+// Integer reply
+func (cli *Client) DbSize() (int, error) {
+	rsp, err := cli.Send("DBSIZE")
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int(rsp)
+	return v, e
+}
+
+// DEBUG OBJECT key
+// Get debugging information about a key
+// Simple string reply: the debugging information, parsed.
+func (cli *Client) DebugObject(key interface{}) (*ObjectInfo, error) {
+	rsp, err := cli.Send("DEBUG", "OBJECT", key)
+	if err != nil {
+		return nil, err
+	}
+	v, e := Bytes(rsp)
+	if e != nil {
+		return nil, e
+	}
+	return parseObjectInfo(string(v)), nil
+}
+
+// FLUSHALL [ASYNC]
+// Remove all keys from all databases
+// Simple string reply
+func (cli *Client) FlushAll(async bool) (string, error) {
+	return cli.flush("FLUSHALL", async)
+}
+
+// FLUSHDB [ASYNC]
+// Remove all keys from the current database
+// Simple string reply
+func (cli *Client) FlushDb(async bool) (string, error) {
+	return cli.flush("FLUSHDB", async)
+}
+
+func (cli *Client) flush(cmd string, async bool) (string, error) {
+	var args []interface{}
+	if async {
+		args = append(args, "ASYNC")
+	}
+	rsp, err := cli.Send(cmd, args...)
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// INFO [section [section ...]]
+// Get information and statistics about the server
+// Bulk string reply: as a collection of text lines, parsed by ParseInfo.
+func (cli *Client) Info(section ...string) (*Info, error) {
+	args := make([]interface{}, len(section))
+	for i, s := range section {
+		args[i] = s
+	}
+	rsp, err := cli.Send("INFO", args...)
+	if err != nil {
+		return nil, err
+	}
+	v, e := Bytes(rsp)
+	if e != nil {
+		return nil, e
+	}
+	return ParseInfo(string(v))
+}
+
+// LASTSAVE
+// Get the UNIX time stamp of the last successful save to disk
+// Integer reply: an UNIX time stamp, as a time.Time.
+func (cli *Client) LastSave() (time.Time, error) {
+	rsp, err := cli.Send("LASTSAVE")
+	if err != nil {
+		return time.Time{}, err
+	}
+	v, e := Int64(rsp)
+	if e != nil {
+		return time.Time{}, e
+	}
+	return time.Unix(v, 0), nil
+}
+
+// ROLE
+// Return the role of the instance in the context of replication
+// Array reply: the role and the state of the replication, parsed.
+func (cli *Client) Role() (*RoleInfo, error) {
+	rsp, err := cli.Send("ROLE")
+	if err != nil {
+		return nil, err
+	}
+	return parseRole(rsp)
+}
+
+// SAVE
+// Synchronously save the dataset to disk
+// Simple string reply: The commands returns OK on success.
+func (cli *Client) Save() (string, error) {
+	rsp, err := cli.Send("SAVE")
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// SHUTDOWN [NOSAVE|SAVE]
+// Synchronously save the dataset to disk and then shut down the server
+// Simple string reply on error. On success nothing is returned since the server quits and the connection is closed,
+// the closed connection is not an error.
+func (cli *Client) Shutdown(p ...interface{}) error {
+	rsp, err := cli.Send("SHUTDOWN", p...)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if e, ok := rsp.(error); ok {
+		return e
+	}
+	return nil
+}
+
+// SLOWLOG GET [count]
+// Get the entries of the slow log, the latest first, 10 entries by default and all of them for -1
+// Array reply: the entries, parsed.
+func (cli *Client) SlowLogGet(count ...int) ([]SlowLogEntry, error) {
+	args := []interface{}{"GET"}
+	if len(count) > 0 {
+		args = append(args, count[0])
+	}
+	rsp, err := cli.Send("SLOWLOG", args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseSlowLog(rsp)
+}
+
+// SLOWLOG LEN
+// Get the length of the slow log
+// Integer reply: the number of entries in the slow log.
+func (cli *Client) SlowLogLen() (int, error) {
+	rsp, err := cli.Send("SLOWLOG", "LEN")
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int(rsp)
+	return v, e
+}
+
+// SLOWLOG RESET
+// Clear all entries from the slow log
+// Simple string reply: OK.
+func (cli *Client) SlowLogReset() (string, error) {
+	rsp, err := cli.Send("SLOWLOG", "RESET")
+	if err != nil {
+		return "", err
+	}
+	v, e := String(rsp)
+	return v, e
+}
+
+// TIME
+// Return the current server time
+// Array reply: the UNIX time in seconds and the microseconds, as a time.Time.
+func (cli *Client) Time() (time.Time, error) {
+	rsp, err := cli.Send("TIME")
+	if err != nil {
+		return time.Time{}, err
+	}
+	v, e := Int64Slice(rsp)
+	if e != nil {
+		return time.Time{}, e
+	}
+	if len(v) != 2 {
+		return time.Time{}, errors.New("redis: the TIME reply is malformed.")
+	}
+	return time.Unix(v[0], v[1]*1000), nil
+}
+
+// SERVER:END
+
 // STRINGS:BEGIN
 
 // APPEND key value
@@ -443,6 +1128,9 @@ func (cli *Client) Append(key, value interface{}) (int, error) {
 func (cli *Client) BitCount(key interface{}, p ...int) (int, error) {
 	args := make([]interface{}, 1+len(p))
 	args[0] = key
+	for i := 0; i < len(p); i++ {
+		args[i+1] = p[i]
+	}
 	rsp, err := cli.Send("BITCOUNT", args...)
 	if err != nil {
 		return -1, err
@@ -451,6 +1139,52 @@ func (cli *Client) BitCount(key interface{}, p ...int) (int, error) {
 	return v, e
 }
 
+// BITCOUNT key start end [BYTE|BIT]
+// Count set bits in a range of a string, in bytes or in bits
+// Integer reply: The number of bits set to 1. unit is "", "BYTE" or "BIT", the default is BYTE.
+func (cli *Client) BitCountRange(key interface{}, start, end int, unit string) (int, error) {
+	args := []interface{}{key, start, end}
+	if unit != "" {
+		args = append(args, unit)
+	}
+	rsp, err := cli.Send("BITCOUNT", args...)
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int(rsp)
+	return v, e
+}
+
+// BITFIELD key [GET encoding offset] [SET encoding offset value] [INCRBY encoding offset increment]
+//     [OVERFLOW WRAP|SAT|FAIL]
+// Perform arbitrary bitfield integer operations on strings
+// Array reply: the result of each GET, SET and INCRBY, nil for an operation which failed on OVERFLOW FAIL.
+func (cli *Client) BitField(key interface{}, ops BitFieldOps) ([]*int64, error) {
+	rsp, err := cli.Send("BITFIELD", append([]interface{}{key}, ops...)...)
+	if err != nil {
+		return nil, err
+	}
+	return Slice(rsp, func(p interface{}) (*int64, error) {
+		if p == nil {
+			return nil, nil
+		}
+		v, err := Int64(p)
+		return &v, err
+	})
+}
+
+// BITFIELD_RO key [GET encoding offset [GET encoding offset ...]]
+// Perform arbitrary bitfield integer operations on strings, read-only variant of BITFIELD
+// Array reply: the result of each GET, ops may only hold GET operations.
+func (cli *Client) BitFieldRo(key interface{}, ops BitFieldOps) ([]int64, error) {
+	rsp, err := cli.Send("BITFIELD_RO", append([]interface{}{key}, ops...)...)
+	if err != nil {
+		return nil, err
+	}
+	v, e := Int64Slice(rsp)
+	return v, e
+}
+
 // BITOP operation destkey key [key ...]
 // Perform bitwise operations between strings
 // The BITOP command supports four bitwise operations: AND, OR, XOR and NOT.
@@ -468,12 +1202,35 @@ func (cli *Client) BitOp(operation, destkey, key interface{}, keys ...interface{
 // BITPOS key bit [start] [end]
 // Find first bit set or clear in a string
 // Integer reply: The command returns the position of the first bit set to 1 or 0 according to the request.
-func (cli *Client) BitPOs(key interface{}, bit int, p ...int) (int, error) {
+func (cli *Client) BitPos(key interface{}, bit int, p ...int) (int, error) {
 	args := make([]interface{}, 2+len(p))
 	args[0] = key
 	args[1] = bit
 	for i := 0; i < len(p); i++ {
-		args[i+1] = p[i]
+		args[i+2] = p[i]
+	}
+	rsp, err := cli.Send("BITPOS", args...)
+	if err != nil {
+		return -1, err
+	}
+	v, e := Int(rsp)
+	return v, e
+}
+
+// BitPOs is BitPos.
+//
+// Deprecated: use BitPos.
+func (cli *Client) BitPOs(key interface{}, bit int, p ...int) (int, error) {
+	return cli.BitPos(key, bit, p...)
+}
+
+// BITPOS key bit start end [BYTE|BIT]
+// Find first bit set or clear in a range of a string, in bytes or in bits
+// Integer reply: the position of the first bit set to 1 or 0, -1 if there is none. unit is "", "BYTE" or "BIT".
+func (cli *Client) BitPosRange(key interface{}, bit, start, end int, unit string) (int, error) {
+	args := []interface{}{key, bit, start, end}
+	if unit != "" {
+		args = append(args, unit)
 	}
 	rsp, err := cli.Send("BITPOS", args...)
 	if err != nil {
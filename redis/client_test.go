@@ -6,23 +6,44 @@ package redis_test
 
 import (
 	"github.com/qqbuby/goredis/redis"
+	"github.com/qqbuby/goredis/redis/redistest"
+	"github.com/qqbuby/goredis/redis/resp"
+	"math"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
-var client redis.Client
+var (
+	client redis.Client
+	server *redistest.Server
+)
 
 func setup() error {
 	var err error
+	if url = os.Getenv("REDIS_URL"); url == "" {
+		if server, err = redistest.NewServer(); err != nil {
+			return err
+		}
+		url = server.URL()
+	}
 	client, err = redis.NewClient(url)
 	return err
 }
 
 func tearDown() error {
 	keys, _ := client.Keys("TEST:*")
-	client.Del(keys[0], keys[1:]...)
+	if len(keys) > 0 {
+		client.Del(keys[0], keys[1:]...)
+	}
 	client.Quit()
-	return client.Close()
+	err := client.Close()
+	if server != nil {
+		server.Close()
+	}
+	return err
 }
 
 func TestMain(m *testing.M) {
@@ -35,6 +56,32 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+func TestSendCommand(t *testing.T) {
+	const key = "TEST:SENDCOMMAND"
+	cmd := resp.NewCommand("SET").AddString(key).AddInt(10)
+	rsp, _ := client.SendCommand(cmd)
+	if s, _ := redis.String(rsp); s != "OK" {
+		t.Errorf("SendCommand did not work properly. E:%s, R:%s", "OK", s)
+	}
+	rsp, _ = client.SendCommand(cmd.Reset("INCRBY").AddString(key).AddInt(5))
+	if n, _ := redis.Int(rsp); n != 15 {
+		t.Errorf("SendCommand did not work properly. E:%d, R:%d", 15, n)
+	}
+
+	// A client with hooks sends the command by Send.
+	hooked, err := redis.NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer hooked.Close()
+	h := &recordHook{}
+	hooked.AddHook(h)
+	rsp, _ = hooked.SendCommand(cmd.Reset("GET").AddString(key))
+	if s, _ := redis.String(rsp); s != "15" || len(h.before) != 1 || h.before[0] != "GET" {
+		t.Errorf("SendCommand did not work properly. R:%s, B:%v", s, h.before)
+	}
+}
+
 // [BEGIN] RESP CONNECTION
 
 func TestAuth(t *testing.T) {
@@ -49,6 +96,63 @@ func TestAuth(t *testing.T) {
 	}
 }
 
+func TestClient(t *testing.T) {
+	cli, err := redis.NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer cli.Close()
+
+	if rsp, err := cli.ClientSetName("TEST-CLIENT"); err != nil || rsp != "OK" {
+		t.Errorf("ClientSetName did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if name, err := cli.ClientGetName(); err != nil || name != "TEST-CLIENT" {
+		t.Errorf("ClientGetName did not work properly. E:%s, R:%s %v", "TEST-CLIENT", name, err)
+	}
+	id, err := cli.ClientID()
+	if err != nil || id <= 0 {
+		t.Fatalf("ClientID did not work properly. R:%d %v", id, err)
+	}
+	info, err := cli.ClientInfo()
+	if err != nil || info.ID != id || info.Name != "TEST-CLIENT" || info.Addr == "" || info.Fields["flags"] != info.Flags {
+		t.Errorf("ClientInfo did not work properly. R:%+v %v", info, err)
+	}
+	list, err := client.ClientList("ID", id)
+	if err != nil || len(list) != 1 || list[0].ID != id || list[0].Name != "TEST-CLIENT" {
+		t.Errorf("ClientList did not work properly. R:%+v %v", list, err)
+	}
+	if list, err := client.ClientList(); err != nil || len(list) < 2 {
+		t.Errorf("ClientList did not work properly. E:>=%d, R:%d %v", 2, len(list), err)
+	}
+
+	if rsp, err := cli.ClientNoEvict(true); err != nil || rsp != "OK" {
+		t.Errorf("ClientNoEvict did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	cli.ClientNoEvict(false)
+	if rsp, err := client.ClientPause(time.Millisecond*10, true); err != nil || rsp != "OK" {
+		t.Errorf("ClientPause did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if rsp, err := client.ClientUnpause(); err != nil || rsp != "OK" {
+		t.Errorf("ClientUnpause did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+
+	if n, err := client.ClientKill("ID", id, "SKIPME", "yes"); err != nil || n != 1 {
+		t.Errorf("ClientKill did not work properly. E:%d, R:%d %v", 1, n, err)
+	}
+	for i := 0; i < 100; i++ {
+		if list, _ = client.ClientList("ID", id); len(list) == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if len(list) != 0 {
+		t.Errorf("ClientKill did not work properly. R:%+v", list)
+	}
+	if _, err := client.ClientKill("127.0.0.1:1"); err == nil {
+		t.Errorf("ClientKill did not work properly. E:%s, R:%v", "ERR No such client", err)
+	}
+}
+
 func TestEcho(t *testing.T) {
 	const message = "Hello world!"
 	rsp, err := client.Echo(message)
@@ -97,6 +201,161 @@ func TestQuit(t *testing.T) {
 
 // [END] RESP CONNECTION
 
+// [BEGIN] RESP GEO
+
+func TestGeoAdd(t *testing.T) {
+	const key = "TEST:GEOADD"
+	client.Del(key)
+	palermo := redis.GeoLocation{Name: "Palermo", Longitude: 13.361389, Latitude: 38.115556}
+	catania := redis.GeoLocation{Name: "Catania", Longitude: 15.087269, Latitude: 37.502669}
+	if n, err := client.GeoAdd(key, "", false, palermo, catania); err != nil || n != 2 {
+		t.Errorf("GeoAdd did not work properly. E:%d, R:%d %v", 2, n, err)
+	}
+	catania.Longitude = 15.1
+	if n, err := client.GeoAdd(key, "NX", true, catania); err != nil || n != 0 {
+		t.Errorf("GeoAdd did not work properly. E:%d, R:%d %v", 0, n, err)
+	}
+	if n, err := client.GeoAdd(key, "XX", true, catania); err != nil || n != 1 {
+		t.Errorf("GeoAdd did not work properly. E:%d, R:%d %v", 1, n, err)
+	}
+	if _, err := client.GeoAdd(key, "", false, redis.GeoLocation{Name: "Nowhere", Longitude: 0, Latitude: 90}); err == nil {
+		t.Errorf("GeoAdd did not work properly. E:error, R:nil")
+	}
+}
+
+func TestGeoDist(t *testing.T) {
+	const key = "TEST:GEODIST"
+	client.Del(key)
+	client.GeoAdd(key, "", false, redis.GeoLocation{Name: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+		redis.GeoLocation{Name: "Catania", Longitude: 15.087269, Latitude: 37.502669})
+	if d, err := client.GeoDist(key, "Palermo", "Catania", ""); err != nil || d != 166274.1516 {
+		t.Errorf("GeoDist did not work properly. E:%v, R:%v %v", 166274.1516, d, err)
+	}
+	if d, err := client.GeoDist(key, "Palermo", "Catania", "km"); err != nil || d != 166.2742 {
+		t.Errorf("GeoDist did not work properly. E:%v, R:%v %v", 166.2742, d, err)
+	}
+	if _, err := client.GeoDist(key, "Palermo", "Nowhere", ""); err != redis.Nil {
+		t.Errorf("GeoDist did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+}
+
+func TestGeoHash(t *testing.T) {
+	const key = "TEST:GEOHASH"
+	client.Del(key)
+	client.GeoAdd(key, "", false, redis.GeoLocation{Name: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+		redis.GeoLocation{Name: "Catania", Longitude: 15.087269, Latitude: 37.502669})
+	hashes, err := client.GeoHash(key, "Palermo", "Catania", "Nowhere")
+	if e := []string{"sqc8b49rny0", "sqdtr74hyu0", ""}; err != nil || !reflect.DeepEqual(hashes, e) {
+		t.Errorf("GeoHash did not work properly. E:%v, R:%v %v", e, hashes, err)
+	}
+}
+
+func TestGeoPos(t *testing.T) {
+	const key = "TEST:GEOPOS"
+	client.Del(key)
+	client.GeoAdd(key, "", false, redis.GeoLocation{Name: "Palermo", Longitude: 13.361389, Latitude: 38.115556})
+	pos, err := client.GeoPos(key, "Palermo", "Nowhere")
+	if err != nil || len(pos) != 2 || pos[0] == nil || pos[1] != nil ||
+		math.Abs(pos[0][0]-13.361389) > 1e-5 || math.Abs(pos[0][1]-38.115556) > 1e-5 {
+		t.Errorf("GeoPos did not work properly. R:%v %v", pos, err)
+	}
+}
+
+func TestGeoSearch(t *testing.T) {
+	const (
+		key  = "TEST:GEOSEARCH"
+		dest = "TEST:GEOSEARCH:DEST"
+	)
+	client.Del(key)
+	client.GeoAdd(key, "", false, redis.GeoLocation{Name: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+		redis.GeoLocation{Name: "Catania", Longitude: 15.087269, Latitude: 37.502669},
+		redis.GeoLocation{Name: "edge1", Longitude: 12.758489, Latitude: 38.788135},
+		redis.GeoLocation{Name: "edge2", Longitude: 17.241510, Latitude: 38.788135})
+	q := redis.GeoSearchQuery{}.FromLonLat(15, 37).ByRadius(200, "km").Asc()
+	locs, err := client.GeoSearch(key, q)
+	if e := []redis.GeoLocation{{Name: "Catania"}, {Name: "Palermo"}}; err != nil || !reflect.DeepEqual(locs, e) {
+		t.Errorf("GeoSearch did not work properly. E:%v, R:%v %v", e, locs, err)
+	}
+	locs, err = client.GeoSearch(key, q.ByBox(400, 400, "km").WithCoord().WithDist().WithHash())
+	if err != nil || len(locs) != 4 {
+		t.Fatalf("GeoSearch did not work properly. E:%d, R:%v %v", 4, locs, err)
+	}
+	if l := locs[0]; l.Name != "Catania" || l.Dist != 56.4413 || l.GeoHash != 3479447370796909 || math.Abs(l.Longitude-15.087269) > 1e-5 {
+		t.Errorf("GeoSearch did not work properly. R:%+v", l)
+	}
+	if l := locs[3]; l.Name != "edge1" || l.Dist != 279.7405 {
+		t.Errorf("GeoSearch did not work properly. R:%+v", l)
+	}
+	locs, err = client.GeoSearch(key, redis.GeoSearchQuery{}.FromMember("Palermo").ByRadius(200, "km").Desc().Count(1, false).WithDist())
+	if err != nil || len(locs) != 1 || locs[0].Name != "Catania" || locs[0].Dist != 166.2742 {
+		t.Errorf("GeoSearch did not work properly. R:%+v %v", locs, err)
+	}
+	if n, err := client.GeoSearchStore(dest, key, q.WithDist(), true); err != nil || n != 2 {
+		t.Errorf("GeoSearchStore did not work properly. E:%d, R:%d %v", 2, n, err)
+	}
+	rsp, _ := client.Send("ZSCORE", dest, "Palermo")
+	if d, err := redis.Float64(rsp); err != nil || math.Abs(d-190.4424) > 1e-4 {
+		t.Errorf("GeoSearchStore did not work properly. E:%v, R:%v %v", 190.4424, d, err)
+	}
+	if _, err := client.GeoSearch(key, redis.GeoSearchQuery{}.FromLonLat(15, 37)); err == nil {
+		t.Errorf("GeoSearch did not work properly. E:error, R:nil")
+	}
+}
+
+// [END] RESP GEO
+
+// [BEGIN] RESP HYPERLOGLOG
+
+func TestPfAdd(t *testing.T) {
+	const key = "TEST:PFADD"
+	client.Del(key)
+	if n, err := client.PfAdd(key, "a", "b", "c"); err != nil || n != 1 {
+		t.Errorf("PfAdd did not work properly. E:%d, R:%d %v", 1, n, err)
+	}
+	if n, err := client.PfAdd(key, "a"); err != nil || n != 0 {
+		t.Errorf("PfAdd did not work properly. E:%d, R:%d %v", 0, n, err)
+	}
+	client.Set(key, "value")
+	if _, err := client.PfAdd(key, "a"); err == nil {
+		t.Errorf("PfAdd did not work properly. E:error, R:nil")
+	}
+}
+
+func TestPfCount(t *testing.T) {
+	const (
+		key1 = "TEST:PFCOUNT1"
+		key2 = "TEST:PFCOUNT2"
+	)
+	client.Del(key1, key2)
+	client.PfAdd(key1, "a", "b", "c")
+	client.PfAdd(key2, "c", "d")
+	if n, err := client.PfCount(key1); err != nil || n != 3 {
+		t.Errorf("PfCount did not work properly. E:%d, R:%d %v", 3, n, err)
+	}
+	if n, err := client.PfCount(key1, key2); err != nil || n != 4 {
+		t.Errorf("PfCount did not work properly. E:%d, R:%d %v", 4, n, err)
+	}
+}
+
+func TestPfMerge(t *testing.T) {
+	const (
+		dest = "TEST:PFMERGE"
+		key1 = "TEST:PFMERGE1"
+		key2 = "TEST:PFMERGE2"
+	)
+	client.Del(dest, key1, key2)
+	client.PfAdd(key1, "a", "b")
+	client.PfAdd(key2, "b", "c", "d")
+	if rsp, err := client.PfMerge(dest, key1, key2); err != nil || rsp != "OK" {
+		t.Errorf("PfMerge did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if n, _ := client.PfCount(dest); n != 4 {
+		t.Errorf("PfMerge did not work properly. E:%d, R:%d", 4, n)
+	}
+}
+
+// [END] RESP HYPERLOGLOG
+
 // [BEGIN] RESP KEYS
 
 func TestDel(t *testing.T) {
@@ -138,7 +397,7 @@ func TestExists(t *testing.T) {
 	client.Del(key2)
 	r, _ := client.Exists(key0, key1, key2)
 	if r != 2 {
-		t.Errorf("Exists did not work properly. E:%s, R:%s", 2, r)
+		t.Errorf("Exists did not work properly. E:%d, R:%d", 2, r)
 	}
 }
 
@@ -205,7 +464,7 @@ func TestMove(t *testing.T) {
 	client.Move(key, db)
 	v, _ := client.Get(key)
 	if v != nil {
-		t.Errorf("Move did not work properly. E:%s, R:%s", nil, v)
+		t.Errorf("Move did not work properly. E:%v, R:%v", nil, v)
 	}
 	client.Select(db)
 	v, _ = client.Get(key)
@@ -283,17 +542,17 @@ func TestPTtl(t *testing.T) {
 	client.Del(key)
 	v, _ := client.Pttl(key)
 	if v != -2 {
-		t.Errorf("Keys did not work properly. E:%s, R:%s", -2, v)
+		t.Errorf("Keys did not work properly. E:%d, R:%d", -2, v)
 	}
 	client.Set(key, value)
 	v, _ = client.Pttl(key)
 	if v != -1 {
-		t.Errorf("Keys did not work properly. E:%s, R:%s", -1, v)
+		t.Errorf("Keys did not work properly. E:%d, R:%d", -1, v)
 	}
 	client.Expire(key, 10000)
 	v, _ = client.Pttl(key)
 	if v <= 0 {
-		t.Errorf("Keys did not work properly. R:%s", v)
+		t.Errorf("Keys did not work properly. R:%d", v)
 	}
 }
 
@@ -334,12 +593,12 @@ func TestRenameNx(t *testing.T) {
 	client.Set(newkey, value)
 	s, _ := client.RenameNx(key, newkey)
 	if s == 1 {
-		t.Errorf("RenameNx did not work properly. R:%s", s)
+		t.Errorf("RenameNx did not work properly. R:%d", s)
 	}
 	client.Del(newkey)
 	s, _ = client.RenameNx(key, newkey)
 	if s == 0 {
-		t.Errorf("Rename did not work properly. R:%s", s)
+		t.Errorf("Rename did not work properly. R:%d", s)
 	}
 }
 
@@ -372,17 +631,17 @@ func TestTtl(t *testing.T) {
 	client.Del(key)
 	v, _ := client.Ttl(key)
 	if v != -2 {
-		t.Errorf("Ttl did not work properly. E:%s, R:%s", -2, v)
+		t.Errorf("Ttl did not work properly. E:%d, R:%d", -2, v)
 	}
 	client.Set(key, value)
 	v, _ = client.Ttl(key)
 	if v != -1 {
-		t.Errorf("Ttl did not work properly. E:%s, R:%s", -1, v)
+		t.Errorf("Ttl did not work properly. E:%d, R:%d", -1, v)
 	}
 	client.Expire(key, 10000)
 	v, _ = client.Ttl(key)
 	if v <= 0 {
-		t.Errorf("Ttl did not work properly. R:%s", v)
+		t.Errorf("Ttl did not work properly. R:%d", v)
 	}
 }
 
@@ -409,6 +668,282 @@ func TestScan(t *testing.T) {}
 
 // [END] RESP KEYS
 
+// [BEGIN] RESP SERVER
+
+// newScratchClient returns a client of a server of its own, for the commands which flush or stop the server.
+func newScratchClient(t *testing.T) (*redistest.Server, redis.Client) {
+	srv, err := redistest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %s", err.Error())
+	}
+	cli, err := redis.NewClient(srv.URL())
+	if err != nil {
+		srv.Close()
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	t.Cleanup(func() {
+		cli.Close()
+		srv.Close()
+	})
+	return srv, cli
+}
+
+func TestAcl(t *testing.T) {
+	srv, cli := newScratchClient(t)
+	rules := redis.AclRules{}.On().AddPassword("secret").KeyPattern("app:*").ReadKeyPattern("ro:*").
+		AllowCategory("read").AllowCommand("set", "client|id").ChannelPattern("news.*")
+	if rsp, err := cli.AclSetUser("app", rules); err != nil || rsp != "OK" {
+		t.Fatalf("AclSetUser did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if _, err := cli.AclSetUser("bad", redis.AclRules{}.Rule("+nosuchcommand")); err == nil {
+		t.Errorf("AclSetUser did not work properly. E:%s, R:%v", "an error", err)
+	}
+	u, err := cli.AclGetUser("app")
+	if err != nil || !u.Enabled() || len(u.Passwords) != 1 || u.Keys != "~app:* %R~ro:*" || u.Channels != "&news.*" ||
+		!strings.Contains(u.Commands, "+@read") {
+		t.Errorf("AclGetUser did not work properly. R:%+v %v", u, err)
+	}
+	if _, err := cli.AclGetUser("nobody"); err != redis.Nil {
+		t.Errorf("AclGetUser did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+	if users, err := cli.AclUsers(); err != nil || !reflect.DeepEqual(users, []string{"app", "default"}) {
+		t.Errorf("AclUsers did not work properly. E:%v, R:%v %v", []string{"app", "default"}, users, err)
+	}
+	if list, err := cli.AclList(); err != nil || len(list) != 2 || !strings.HasPrefix(list[0], "user app on") {
+		t.Errorf("AclList did not work properly. R:%v %v", list, err)
+	}
+	if cats, err := cli.AclCat(); err != nil || len(cats) == 0 {
+		t.Errorf("AclCat did not work properly. R:%v %v", cats, err)
+	}
+	if cmds, err := cli.AclCat("read"); err != nil || !strings.Contains(strings.Join(cmds, " "), "get") {
+		t.Errorf("AclCat did not work properly. R:%v %v", cmds, err)
+	}
+
+	if rsp, err := cli.AclDryRun("app", "get", "app:1"); err != nil || rsp != "OK" {
+		t.Errorf("AclDryRun did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if rsp, err := cli.AclDryRun("app", "set", "ro:1", "v"); err != nil || rsp == "OK" {
+		t.Errorf("AclDryRun did not work properly. E:%s, R:%s %v", "a denial", rsp, err)
+	}
+	if rsp, err := cli.AclDryRun("app", "del", "app:1"); err != nil || !strings.Contains(rsp, "'del' command") {
+		t.Errorf("AclDryRun did not work properly. E:%s, R:%s %v", "a denial", rsp, err)
+	}
+
+	app, err := redis.NewClient(srv.URL())
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer app.Close()
+	if _, err := app.AuthUser("app", "wrong"); err == nil {
+		t.Errorf("AuthUser did not work properly. E:%s, R:%v", "WRONGPASS", err)
+	}
+	if rsp, err := app.AuthUser("app", "secret"); err != nil || rsp != "OK" {
+		t.Errorf("AuthUser did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if name, err := app.AclWhoAmI(); err == nil {
+		t.Errorf("AclWhoAmI did not work properly. E:%s, R:%s %v", "NOPERM", name, err)
+	}
+	if _, err := app.Set("app:1", "v"); err != nil {
+		t.Errorf("Set did not work properly. R:%v", err)
+	}
+	if _, err := app.Set("other", "v"); err == nil {
+		t.Errorf("Set did not work properly. E:%s, R:%v", "NOPERM", err)
+	}
+	if name, err := cli.AclWhoAmI(); err != nil || name != "default" {
+		t.Errorf("AclWhoAmI did not work properly. E:%s, R:%s %v", "default", name, err)
+	}
+
+	log, err := cli.AclLog()
+	if err != nil || len(log) != 3 {
+		t.Fatalf("AclLog did not work properly. E:%d, R:%+v %v", 3, log, err)
+	}
+	if e := log[0]; e.Reason != "key" || e.Object != "other" || e.Username != "app" || e.Count != 1 ||
+		e.ClientInfo.User != "app" || e.Created.IsZero() || e.EntryID <= log[1].EntryID {
+		t.Errorf("AclLog did not work properly. R:%+v", e)
+	}
+	if e := log[2]; e.Reason != "auth" || e.Object != "AUTH" {
+		t.Errorf("AclLog did not work properly. R:%+v", e)
+	}
+	if rsp, err := cli.AclLogReset(); err != nil || rsp != "OK" {
+		t.Errorf("AclLogReset did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+
+	if n, err := cli.AclDelUser("app", "nobody"); err != nil || n != 1 {
+		t.Errorf("AclDelUser did not work properly. E:%d, R:%d %v", 1, n, err)
+	}
+	if _, err := cli.AclDelUser("default"); err == nil {
+		t.Errorf("AclDelUser did not work properly. E:%s, R:%v", "an error", err)
+	}
+}
+
+func TestBgSave(t *testing.T) {
+	rsp, err := client.BgSave()
+	if err != nil || rsp == "" {
+		t.Errorf("BgSave did not work properly. R:%s %v", rsp, err)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	m, err := client.ConfigGet("maxmemory")
+	if err != nil || len(m) != 1 {
+		t.Fatalf("ConfigGet did not work properly. R:%v %v", m, err)
+	}
+	if rsp, err := client.ConfigSet("maxmemory", m["maxmemory"]); err != nil || rsp != "OK" {
+		t.Errorf("ConfigSet did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if _, err := client.ConfigSet("no-such-parameter", 1); err == nil {
+		t.Errorf("ConfigSet did not work properly. E:%s, R:%v", "an error", err)
+	}
+	if rsp, err := client.ConfigResetStat(); err != nil || rsp != "OK" {
+		t.Errorf("ConfigResetStat did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+
+	_, cli := newScratchClient(t)
+	if rsp, err := cli.ConfigRewrite(); err != nil || rsp != "OK" {
+		t.Errorf("ConfigRewrite did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+}
+
+func TestDbSize(t *testing.T) {
+	const key = "TEST:DBSIZE"
+	client.Set(key, "v")
+	defer client.Del(key)
+	if n, err := client.DbSize(); n < 1 || err != nil {
+		t.Errorf("DbSize did not work properly. E:>=%d, R:%d %v", 1, n, err)
+	}
+}
+
+func TestDebugObject(t *testing.T) {
+	const key = "TEST:DEBUGOBJECT"
+	client.Set(key, 12345)
+	defer client.Del(key)
+	o, err := client.DebugObject(key)
+	if err != nil {
+		if strings.Contains(err.Error(), "DEBUG command not allowed") {
+			t.Skip(err.Error())
+		}
+		t.Fatalf("DebugObject: %s", err.Error())
+	}
+	if o.Encoding != "int" || o.RefCount < 1 {
+		t.Errorf("DebugObject did not work properly. E:%s, R:%+v", "int", o)
+	}
+	if _, err := client.DebugObject("TEST:NOSUCHKEY"); err == nil {
+		t.Errorf("DebugObject did not work properly. E:%s, R:%v", "ERR no such key", err)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	_, cli := newScratchClient(t)
+	cli.Set("a", 1)
+	cli.Select(1)
+	cli.Set("b", 1)
+	if rsp, err := cli.FlushDb(false); err != nil || rsp != "OK" {
+		t.Errorf("FlushDb did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if n, _ := cli.DbSize(); n != 0 {
+		t.Errorf("FlushDb did not work properly. E:%d, R:%d", 0, n)
+	}
+	cli.Select(0)
+	if n, _ := cli.DbSize(); n != 1 {
+		t.Errorf("FlushDb did not work properly. E:%d, R:%d", 1, n)
+	}
+	if rsp, err := cli.FlushAll(true); err != nil || rsp != "OK" {
+		t.Errorf("FlushAll did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if n, _ := cli.DbSize(); n != 0 {
+		t.Errorf("FlushAll did not work properly. E:%d, R:%d", 0, n)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	info, err := client.Info()
+	if err != nil {
+		t.Fatalf("Info: %s", err.Error())
+	}
+	if info.Server.RedisVersion == "" || info.Server.TCPPort == 0 || info.Clients.ConnectedClients < 1 ||
+		info.Replication.Role != "master" || info.Persistence.RDBLastSaveTime.IsZero() {
+		t.Errorf("Info did not work properly. R:%+v", info)
+	}
+	if info.Sections["server"]["redis_version"] != info.Server.RedisVersion {
+		t.Errorf("Info did not work properly. E:%s, R:%v", info.Server.RedisVersion, info.Sections["server"])
+	}
+
+	info, err = client.Info("clients")
+	if err != nil || len(info.Sections) != 1 || info.Clients.ConnectedClients < 1 {
+		t.Errorf("Info did not work properly. R:%+v %v", info, err)
+	}
+}
+
+func TestLastSave(t *testing.T) {
+	client.Save()
+	if ts, err := client.LastSave(); err != nil || time.Since(ts) > time.Minute {
+		t.Errorf("LastSave did not work properly. E:%v, R:%v %v", time.Now(), ts, err)
+	}
+}
+
+func TestRole(t *testing.T) {
+	role, err := client.Role()
+	if err != nil || role.Role != "master" {
+		t.Errorf("Role did not work properly. E:%s, R:%+v %v", "master", role, err)
+	}
+}
+
+func TestSave(t *testing.T) {
+	if rsp, err := client.Save(); err != nil || rsp != "OK" {
+		t.Errorf("Save did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	srv, cli := newScratchClient(t)
+	if err := cli.Shutdown("NOWAY"); err == nil {
+		t.Errorf("Shutdown did not work properly. E:%s, R:%v", "ERR syntax error", err)
+	}
+	if err := cli.Shutdown("NOSAVE"); err != nil {
+		t.Errorf("Shutdown did not work properly. E:%v, R:%v", nil, err)
+	}
+	if _, err := redis.NewClient(srv.URL()); err == nil {
+		t.Errorf("Shutdown did not work properly. E:%s, R:%v", "a closed server", err)
+	}
+}
+
+func TestSlowLog(t *testing.T) {
+	_, cli := newScratchClient(t)
+	cli.ClientSetName("TEST-SLOWLOG")
+	if _, err := cli.ConfigSet("slowlog-log-slower-than", 0); err != nil {
+		t.Fatalf("ConfigSet: %s", err.Error())
+	}
+	cli.SlowLogReset()
+	cli.Set("TEST:SLOWLOG", "v")
+	cli.Get("TEST:SLOWLOG")
+	if n, err := cli.SlowLogLen(); err != nil || n < 2 {
+		t.Errorf("SlowLogLen did not work properly. E:>=%d, R:%d %v", 2, n, err)
+	}
+	entries, err := cli.SlowLogGet(2)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("SlowLogGet did not work properly. R:%+v %v", entries, err)
+	}
+	e := entries[0]
+	if !reflect.DeepEqual(e.Args, []string{"GET", "TEST:SLOWLOG"}) || e.ID <= entries[1].ID || e.ClientName != "TEST-SLOWLOG" ||
+		e.ClientAddr == "" || time.Since(e.Time) > time.Minute || e.Duration < 0 {
+		t.Errorf("SlowLogGet did not work properly. R:%+v", e)
+	}
+	if rsp, err := cli.SlowLogReset(); err != nil || rsp != "OK" {
+		t.Errorf("SlowLogReset did not work properly. E:%s, R:%s %v", "OK", rsp, err)
+	}
+	if entries, err := cli.SlowLogGet(); err != nil || len(entries) != 0 {
+		t.Errorf("SlowLogGet did not work properly. E:%d, R:%d %v", 0, len(entries), err)
+	}
+}
+
+func TestTime(t *testing.T) {
+	if ts, err := client.Time(); err != nil || time.Since(ts).Abs() > time.Minute {
+		t.Errorf("Time did not work properly. E:%v, R:%v %v", time.Now(), ts, err)
+	}
+}
+
+// [END] RESP SERVER
+
 // [BEGIN] RESP STRINGS
 
 func TestAppend(t *testing.T) {
@@ -436,6 +971,55 @@ func TestBitCount(t *testing.T) {
 	if s != count {
 		t.Errorf("BitCount dit not work properly. R:%d", s)
 	}
+	s, _ = client.BitCount(key, 1, 1)
+	if s != count-8 {
+		t.Errorf("BitCount dit not work properly. E:%d, R:%d", count-8, s)
+	}
+}
+
+func TestBitCountRange(t *testing.T) {
+	const key = "TEST:BITCOUNTRANGE"
+	client.Set(key, "foobar")
+	if n, err := client.BitCountRange(key, 1, 1, ""); err != nil || n != 6 {
+		t.Errorf("BitCountRange did not work properly. E:%d, R:%d %v", 6, n, err)
+	}
+	if n, err := client.BitCountRange(key, 1, 1, "BYTE"); err != nil || n != 6 {
+		t.Errorf("BitCountRange did not work properly. E:%d, R:%d %v", 6, n, err)
+	}
+	if n, err := client.BitCountRange(key, 5, 30, "BIT"); err != nil || n != 17 {
+		t.Errorf("BitCountRange did not work properly. E:%d, R:%d %v", 17, n, err)
+	}
+}
+
+func TestBitField(t *testing.T) {
+	const key = "TEST:BITFIELD"
+	client.Del(key)
+	values, err := client.BitField(key, redis.BitFieldOps{}.IncrBy("i5", 100, 1).Get("u4", 0))
+	if err != nil || len(values) != 2 || values[0] == nil || *values[0] != 1 || values[1] == nil || *values[1] != 0 {
+		t.Errorf("BitField did not work properly. R:%v %v", values, err)
+	}
+	sat := redis.BitFieldOps{}.Overflow("SAT").IncrBy("u2", 102, 1)
+	for i := 1; i <= 4; i++ {
+		values, err = client.BitField(key, sat)
+		if e := int64(min(i, 3)); err != nil || len(values) != 1 || values[0] == nil || *values[0] != e {
+			t.Errorf("BitField did not work properly. E:%d, R:%v %v", e, values, err)
+		}
+	}
+	values, err = client.BitField(key, redis.BitFieldOps{}.Overflow("FAIL").IncrBy("u2", 102, 1).Overflow("WRAP").IncrBy("u2", 102, 1))
+	if err != nil || len(values) != 2 || values[0] != nil || values[1] == nil || *values[1] != 0 {
+		t.Errorf("BitField did not work properly. R:%v %v", values, err)
+	}
+	values, err = client.BitField(key, redis.BitFieldOps{}.Set("i8", "#1", -100))
+	if err != nil || len(values) != 1 || values[0] == nil {
+		t.Errorf("BitField did not work properly. R:%v %v", values, err)
+	}
+	ro, err := client.BitFieldRo(key, redis.BitFieldOps{}.Get("i8", "#1").Get("u8", 8))
+	if err != nil || !reflect.DeepEqual(ro, []int64{-100, 156}) {
+		t.Errorf("BitFieldRo did not work properly. E:%v, R:%v %v", []int64{-100, 156}, ro, err)
+	}
+	if _, err := client.BitFieldRo(key, redis.BitFieldOps{}.Set("i8", 0, 1)); err == nil {
+		t.Errorf("BitFieldRo did not work properly. E:error, R:nil")
+	}
 }
 
 func TestBitOp(t *testing.T) {
@@ -466,7 +1050,27 @@ func TestBitPOs(t *testing.T) {
 	client.SetBit(key, pos, bit)
 	p, _ := client.BitPOs(key, bit)
 	if p != pos {
-		t.Errorf("BitPOs did not work properly. E:%s, R:%s", pos, p)
+		t.Errorf("BitPOs did not work properly. E:%d, R:%d", pos, p)
+	}
+}
+
+func TestBitPos(t *testing.T) {
+	const key = "TEST:BITPOS:RANGE"
+	client.Set(key, "\x00\xff\xf0")
+	if p, err := client.BitPos(key, 1, 0); err != nil || p != 8 {
+		t.Errorf("BitPos did not work properly. E:%d, R:%d %v", 8, p, err)
+	}
+	if p, err := client.BitPos(key, 1, 2); err != nil || p != 16 {
+		t.Errorf("BitPos did not work properly. E:%d, R:%d %v", 16, p, err)
+	}
+	if p, err := client.BitPos(key, 0, 1, 1); err != nil || p != -1 {
+		t.Errorf("BitPos did not work properly. E:%d, R:%d %v", -1, p, err)
+	}
+	if p, err := client.BitPosRange(key, 1, 2, -1, "BYTE"); err != nil || p != 16 {
+		t.Errorf("BitPosRange did not work properly. E:%d, R:%d %v", 16, p, err)
+	}
+	if p, err := client.BitPosRange(key, 1, 7, 15, "BIT"); err != nil || p != 8 {
+		t.Errorf("BitPosRange did not work properly. E:%d, R:%d %v", 8, p, err)
 	}
 }
 
@@ -513,6 +1117,19 @@ func TestGet(t *testing.T) {
 	}
 }
 
+// TestGetBinary gets a value with line breaks, the bulk strings are read by length.
+func TestGetBinary(t *testing.T) {
+	const (
+		key   = "TEST:GETBINARY"
+		value = "foo\nbar\r\nbuzz\x00"
+	)
+	client.Set(key, []byte(value))
+	v, _ := client.Get(key)
+	if v != value {
+		t.Errorf("Get did not work properly. E:%q, R:%q", value, v)
+	}
+}
+
 func TestGetBit(t *testing.T) {
 	const (
 		key = "TEST:GETBIT"
@@ -522,7 +1139,7 @@ func TestGetBit(t *testing.T) {
 	client.SetBit(key, pos, bit)
 	b, _ := client.GetBit(key, pos)
 	if b != bit {
-		t.Errorf("GetBit did not work properly. E:%s, R:%s", bit, b)
+		t.Errorf("GetBit did not work properly. E:%d, R:%d", bit, b)
 	}
 }
 
@@ -0,0 +1,165 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+var errCmdPending = errors.New("redis: the command is not executed.")
+
+// ErrTxAborted is returned by the Exec of a transaction discarded by EXEC, e.g. when a watched key was modified.
+var ErrTxAborted = errors.New("redis: transaction aborted.")
+
+// Cmd is a command, of any name, and its reply, read by typed accessors:
+//
+//	n, err := client.Do(ctx, "HSTRLEN", "user:1", "name").Int64()
+//
+// A Cmd returned by Pipeline.Do has its reply once the pipeline is executed.
+// The accessors return the network error or the error reply of the command, and Nil for a null reply.
+type Cmd struct {
+	name  string
+	args  []interface{}
+	reply interface{}
+	err   error
+}
+
+// Do sends a command of any name and returns it with its reply, ctx is passed to the hooks.
+func (cli *Client) Do(ctx context.Context, cmd string, args ...interface{}) *Cmd {
+	c := cli.WithContext(ctx)
+	reply, err := c.Send(cmd, args...)
+	return &Cmd{name: cmd, args: args, reply: reply, err: replyErr(reply, err)}
+}
+
+// Do sends a command and scans its reply into a T, as by Cmd.Scan:
+//
+//	members, err := redis.Do[[]string](ctx, &client, "SMEMBERS", "set")
+func Do[T any](ctx context.Context, cli *Client, cmd string, args ...interface{}) (T, error) {
+	var v T
+	err := cli.Do(ctx, cmd, args...).Scan(&v)
+	return v, err
+}
+
+// Name returns the name of the command.
+func (c *Cmd) Name() string {
+	return c.name
+}
+
+// Args returns the arguments of the command.
+func (c *Cmd) Args() []interface{} {
+	return c.args
+}
+
+// Result returns the reply of the command as received, see Client.Send.
+func (c *Cmd) Result() (interface{}, error) {
+	return c.reply, c.err
+}
+
+// Err returns the network error or the error reply of the command.
+func (c *Cmd) Err() error {
+	return c.err
+}
+
+// Int64 returns the reply parsed by Int64.
+func (c *Cmd) Int64() (int64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	return Int64(c.reply)
+}
+
+// Float64 returns the reply parsed by Float64.
+func (c *Cmd) Float64() (float64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	return Float64(c.reply)
+}
+
+// Bool returns the reply parsed by Bool.
+func (c *Cmd) Bool() (bool, error) {
+	if c.err != nil {
+		return false, c.err
+	}
+	return Bool(c.reply)
+}
+
+// Bytes returns the reply parsed by Bytes.
+func (c *Cmd) Bytes() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return Bytes(c.reply)
+}
+
+// Text returns the reply as a string, Nil for a null reply.
+func (c *Cmd) Text() (string, error) {
+	b, err := c.Bytes()
+	return string(b), err
+}
+
+// Values returns the reply parsed by Values.
+func (c *Cmd) Values() ([]interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return Values(c.reply)
+}
+
+// StringSlice returns the reply parsed by StringSlice.
+func (c *Cmd) StringSlice() ([]string, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return StringSlice(c.reply)
+}
+
+// Int64Slice returns the reply parsed by Int64Slice.
+func (c *Cmd) Int64Slice() ([]int64, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return Int64Slice(c.reply)
+}
+
+// StringMap returns the reply parsed by StringMap.
+func (c *Cmd) StringMap() (map[string]string, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return StringMap(c.reply)
+}
+
+// Scan scans the reply into the value pointed by dest: a struct is scanned by ScanStruct, a slice other than
+// a []byte by ScanSlice, a map from an array of keys and values, e.g. the reply of HGETALL, an interface{}
+// is set to the reply, and the other values, the keys and the values of a map included, are scanned as
+// the fields of ScanStruct.
+func (c *Cmd) Scan(dest interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return errors.New("redis.Cmd.Scan: dest must be a non-nil pointer.")
+	}
+	t := d.Elem().Type()
+	switch {
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		d.Elem().Set(reflect.ValueOf(&c.reply).Elem())
+		return nil
+	case t.Kind() == reflect.Struct && !isScalar(t):
+		return ScanStruct(c.reply, dest)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+		return ScanSlice(c.reply, dest)
+	case c.reply == nil:
+		return Nil
+	case t.Kind() == reflect.Map:
+		return scanMap(d.Elem(), c.reply)
+	default:
+		return scanValue(d.Elem(), c.reply)
+	}
+}
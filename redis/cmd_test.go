@@ -0,0 +1,137 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"context"
+	"github.com/qqbuby/goredis/redis"
+	"reflect"
+	"testing"
+)
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	key := "TEST:DO"
+	defer client.Del(key)
+
+	if n, err := client.Do(ctx, "RPUSH", key, "a", "b", "1").Int64(); n != 3 || err != nil {
+		t.Errorf("Do did not work properly. E:%d, R:%d %v", 3, n, err)
+	}
+	if s, err := client.Do(ctx, "LRANGE", key, 0, -1).StringSlice(); !reflect.DeepEqual(s, []string{"a", "b", "1"}) || err != nil {
+		t.Errorf("Do did not work properly. E:%v, R:%v %v", []string{"a", "b", "1"}, s, err)
+	}
+	if s, err := client.Do(ctx, "LINDEX", key, 0).Text(); s != "a" || err != nil {
+		t.Errorf("Do did not work properly. E:%s, R:%s %v", "a", s, err)
+	}
+	if _, err := client.Do(ctx, "LINDEX", key, 9).Text(); err != redis.Nil {
+		t.Errorf("Do did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+	if _, err := client.Do(ctx, "INCR", key).Int64(); err == nil {
+		t.Errorf("Do did not work properly. E:%s, R:%v", "WRONGTYPE", err)
+	}
+
+	if n, err := redis.Do[int](ctx, &client, "LLEN", key); n != 3 || err != nil {
+		t.Errorf("Do[int] did not work properly. E:%d, R:%d %v", 3, n, err)
+	}
+	if s, err := redis.Do[[]string](ctx, &client, "LRANGE", key, 0, 1); !reflect.DeepEqual(s, []string{"a", "b"}) || err != nil {
+		t.Errorf("Do[[]string] did not work properly. E:%v, R:%v %v", []string{"a", "b"}, s, err)
+	}
+	if v, err := redis.Do[interface{}](ctx, &client, "LINDEX", key, 2); !reflect.DeepEqual(v, []byte("1")) || err != nil {
+		t.Errorf("Do[interface{}] did not work properly. E:%v, R:%v %v", []byte("1"), v, err)
+	}
+	if _, err := redis.Do[float64](ctx, &client, "LINDEX", key, 9); err != redis.Nil {
+		t.Errorf("Do[float64] did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+	if _, err := redis.Do[string](ctx, &client, "GET", key+":NIL"); err != redis.Nil {
+		t.Errorf("Do[string] did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+
+	hkey := key + ":HASH"
+	defer client.Del(hkey)
+	client.Send("HSET", hkey, "a", 1, "b", 2)
+	if m, err := redis.Do[map[string]string](ctx, &client, "HGETALL", hkey); !reflect.DeepEqual(m, map[string]string{"a": "1", "b": "2"}) || err != nil {
+		t.Errorf("Do[map[string]string] did not work properly. E:%v, R:%v %v", map[string]string{"a": "1", "b": "2"}, m, err)
+	}
+	if m, err := redis.Do[map[string]int](ctx, &client, "HGETALL", hkey); !reflect.DeepEqual(m, map[string]int{"a": 1, "b": 2}) || err != nil {
+		t.Errorf("Do[map[string]int] did not work properly. E:%v, R:%v %v", map[string]int{"a": 1, "b": 2}, m, err)
+	}
+	if m, err := redis.Do[map[string]string](ctx, &client, "HGETALL", hkey+":NIL"); len(m) != 0 || err != nil {
+		t.Errorf("Do[map[string]string] did not work properly. E:%v, R:%v %v", map[string]string{}, m, err)
+	}
+	if _, err := redis.Do[map[string]string](ctx, &client, "LRANGE", key, 0, -1); err == nil {
+		t.Error("Do[map[string]string] did not work properly: the array has an odd number of elements.")
+	}
+}
+
+func TestPipelineDo(t *testing.T) {
+	key := "TEST:PIPELINEDO"
+	defer client.Del(key)
+
+	p := client.Pipeline()
+	set := p.Do("SET", key, 1)
+	incr := p.Do("INCRBY", key, 2)
+	if _, err := incr.Int64(); err == nil {
+		t.Errorf("Pipeline.Do did not work properly. E:%s, R:%v", "an error before Exec", err)
+	}
+	if _, err := p.Exec(); err != nil {
+		t.Fatalf("Exec: %s", err.Error())
+	}
+	if s, err := set.Text(); s != "OK" || err != nil {
+		t.Errorf("Pipeline.Do did not work properly. E:%s, R:%s %v", "OK", s, err)
+	}
+	if n, err := incr.Int64(); n != 3 || err != nil {
+		t.Errorf("Pipeline.Do did not work properly. E:%d, R:%d %v", 3, n, err)
+	}
+
+	tx := client.TxPipeline()
+	incr = tx.Do("INCRBY", key, 2)
+	get := tx.Do("GET", key)
+	r, err := tx.Exec()
+	if err != nil || len(r) != 2 {
+		t.Fatalf("TxPipeline did not work properly. R:%v, E:%v", r, err)
+	}
+	if n, err := incr.Int64(); n != 5 || err != nil {
+		t.Errorf("TxPipeline did not work properly. E:%d, R:%d %v", 5, n, err)
+	}
+	if n, err := get.Int64(); n != 5 || err != nil {
+		t.Errorf("TxPipeline did not work properly. E:%d, R:%d %v", 5, n, err)
+	}
+
+	tx.Queue("SET", key, 1)
+	tx.Queue("NOSUCHCOMMAND")
+	if _, err := tx.Exec(); err == nil {
+		t.Errorf("TxPipeline did not work properly. E:%s, R:%v", "EXECABORT", err)
+	}
+	if v, _ := client.Get(key); v != "5" {
+		t.Errorf("TxPipeline did not work properly. E:%s, R:%v", "5", v)
+	}
+}
+
+func TestTxAborted(t *testing.T) {
+	cli, err := redis.NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer cli.Close()
+	key := "TEST:TXABORTED"
+	defer cli.Del(key)
+
+	cli.Send("WATCH", key)
+	other, err := redis.NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	other.Set(key, "other")
+	other.Close()
+
+	tx := cli.TxPipeline()
+	set := tx.Do("SET", key, "tx")
+	if _, err := tx.Exec(); err != redis.ErrTxAborted {
+		t.Errorf("TxPipeline did not work properly. E:%v, R:%v", redis.ErrTxAborted, err)
+	}
+	if err := set.Err(); err != redis.ErrTxAborted {
+		t.Errorf("TxPipeline did not work properly. E:%v, R:%v", redis.ErrTxAborted, err)
+	}
+}
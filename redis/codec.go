@@ -0,0 +1,172 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// Codec encodes the values stored by Typed, e.g. JSONCodec or GobCodec.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec encodes the values by encoding/json, it is the codec of a Client without SetCodec.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec encodes the values by encoding/gob, each value is encoded with its type.
+type GobCodec struct{}
+
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// The first byte of the values encoded by GzipCodec.
+const (
+	gzipRaw        = 0
+	gzipCompressed = 1
+)
+
+var errGzipFormat = errors.New("redis: the value is not encoded by GzipCodec.")
+
+// GzipCodec compresses by gzip the values encoded by Codec whose sizes are at least Threshold bytes.
+// The values are prefixed by a byte which tells whether they are compressed.
+type GzipCodec struct {
+	Codec Codec
+	// Threshold is the size of the smallest compressed value, zero compresses all the values.
+	Threshold int
+	// Level is the gzip compression level, gzip.DefaultCompression when zero.
+	Level int
+}
+
+func (c GzipCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.Codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < c.Threshold {
+		return append([]byte{gzipRaw}, data...), nil
+	}
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	var b bytes.Buffer
+	b.WriteByte(gzipCompressed)
+	zw, err := gzip.NewWriterLevel(&b, level)
+	if err != nil {
+		return nil, err
+	}
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (c GzipCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return errGzipFormat
+	}
+	switch data[0] {
+	case gzipRaw:
+		return c.Codec.Unmarshal(data[1:], v)
+	case gzipCompressed:
+		zr, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(zr)
+		if err != nil {
+			return err
+		}
+		return c.Codec.Unmarshal(b, v)
+	default:
+		return errGzipFormat
+	}
+}
+
+// SetCodec sets the codec of the values stored by the Typed of the client.
+func (cli *Client) SetCodec(c Codec) {
+	cli.codec = c
+}
+
+// Codec returns the codec of the client, JSONCodec by default.
+func (cli *Client) Codec() Codec {
+	if cli.codec == nil {
+		return JSONCodec{}
+	}
+	return cli.codec
+}
+
+// Typed stores values of type T encoded by Codec, or by the codec of Client when Codec is nil:
+//
+//	users := redis.Typed[User]{Client: &client}
+//	err := users.Set("user:1", User{Name: "roy"}, time.Hour)
+//	...
+//	u, err := users.Get("user:1")
+type Typed[T any] struct {
+	Client *Client
+	Codec  Codec
+}
+
+func (t Typed[T]) codec() Codec {
+	if t.Codec == nil {
+		return t.Client.Codec()
+	}
+	return t.Codec
+}
+
+// Get returns the value of a key, Nil for a key which does not exist.
+func (t Typed[T]) Get(key interface{}) (T, error) {
+	var v T
+	rsp, err := t.Client.Send("GET", key)
+	if err != nil {
+		return v, err
+	}
+	b, err := Bytes(rsp)
+	if err != nil {
+		return v, err
+	}
+	err = t.codec().Unmarshal(b, &v)
+	return v, err
+}
+
+// Set sets the value of a key which expires after ttl, a ttl of zero does not expire.
+func (t Typed[T]) Set(key interface{}, v T, ttl time.Duration) error {
+	b, err := t.codec().Marshal(v)
+	if err != nil {
+		return err
+	}
+	args := []interface{}{key, b}
+	if ttl > 0 {
+		args = append(args, "PX", max(ttl.Milliseconds(), 1))
+	}
+	rsp, err := t.Client.Send("SET", args...)
+	return replyErr(rsp, err)
+}
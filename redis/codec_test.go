@@ -0,0 +1,100 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"bytes"
+	"github.com/qqbuby/goredis/redis"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type codecValue struct {
+	Name string
+	Tags []string
+}
+
+func TestCodecs(t *testing.T) {
+	v := codecValue{Name: strings.Repeat("v", 1024), Tags: []string{"a", "b"}}
+	codecs := map[string]redis.Codec{
+		"json":      redis.JSONCodec{},
+		"gob":       redis.GobCodec{},
+		"gzip":      redis.GzipCodec{Codec: redis.JSONCodec{}, Threshold: 512},
+		"gzip-gob":  redis.GzipCodec{Codec: redis.GobCodec{}},
+		"gzip-json": redis.GzipCodec{Codec: redis.JSONCodec{}, Threshold: 1 << 20},
+	}
+	for name, c := range codecs {
+		b, err := c.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: Marshal: %s", name, err.Error())
+		}
+		var r codecValue
+		if err := c.Unmarshal(b, &r); err != nil || !reflect.DeepEqual(r, v) {
+			t.Errorf("%s did not work properly. E:%v, R:%v %v", name, v, r, err)
+		}
+	}
+
+	small, _ := redis.GzipCodec{Codec: redis.JSONCodec{}, Threshold: 1 << 20}.Marshal(v)
+	large, _ := redis.GzipCodec{Codec: redis.JSONCodec{}, Threshold: 512}.Marshal(v)
+	if len(large) >= len(small) || !bytes.Contains(small, []byte(`"Name"`)) {
+		t.Errorf("GzipCodec did not work properly. E:%s, R:%d %d", "a compressed value above the threshold", len(small), len(large))
+	}
+	if err := (redis.GzipCodec{Codec: redis.JSONCodec{}}).Unmarshal([]byte("{}"), &v); err == nil {
+		t.Errorf("GzipCodec did not work properly. E:%s, R:%v", "an error", err)
+	}
+}
+
+func TestTyped(t *testing.T) {
+	key := "TEST:TYPED"
+	defer client.Del(key)
+	v := codecValue{Name: "roy", Tags: []string{"a"}}
+
+	for _, c := range []redis.Codec{nil, redis.GobCodec{}, redis.GzipCodec{Codec: redis.GobCodec{}}} {
+		typed := redis.Typed[codecValue]{Client: &client, Codec: c}
+		if err := typed.Set(key, v, time.Minute); err != nil {
+			t.Fatalf("Set: %s", err.Error())
+		}
+		if r, err := typed.Get(key); err != nil || !reflect.DeepEqual(r, v) {
+			t.Errorf("Typed did not work properly. E:%v, R:%v %v", v, r, err)
+		}
+	}
+	if ttl, err := client.Ttl(key); ttl <= 0 || ttl > 60 || err != nil {
+		t.Errorf("Typed.Set did not work properly. E:%d, R:%d %v", 60, ttl, err)
+	}
+
+	cli, err := redis.NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer cli.Close()
+	cli.SetCodec(redis.GobCodec{})
+	typed := redis.Typed[codecValue]{Client: &cli}
+	if err := typed.Set(key, v, 0); err != nil {
+		t.Fatalf("Set: %s", err.Error())
+	}
+	if r, err := (redis.Typed[codecValue]{Client: &cli, Codec: redis.GobCodec{}}).Get(key); err != nil || !reflect.DeepEqual(r, v) {
+		t.Errorf("SetCodec did not work properly. E:%v, R:%v %v", v, r, err)
+	}
+	cli.Del(key)
+	if _, err := typed.Get(key); err != redis.Nil {
+		t.Errorf("Typed.Get did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+}
+
+func TestTypedSetError(t *testing.T) {
+	srv, _ := newScratchClient(t)
+	srv.SetPassword("secret")
+	cli, err := redis.NewClient(srv.URL())
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer cli.Close()
+	typed := redis.Typed[codecValue]{Client: &cli}
+	if err := typed.Set("TEST:TYPED:ERROR", codecValue{Name: "roy"}, 0); err == nil || !strings.HasPrefix(err.Error(), "NOAUTH") {
+		t.Errorf("Typed.Set did not work properly. E:%s, R:%v", "NOAUTH", err)
+	}
+}
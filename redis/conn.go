@@ -6,13 +6,12 @@ package redis
 
 import (
 	"bufio"
-	"bytes"
-	"errors"
 	"fmt"
-	"log"
+	"github.com/qqbuby/goredis/redis/resp"
+	"log/slog"
 	"net"
 	"net/url"
-	"strconv"
+	"strings"
 	"time"
 )
 
@@ -24,17 +23,26 @@ type Conn interface {
 	Close() error
 }
 
+// commandSender is implemented by the connections which write the commands built by resp.Command as they are.
+type commandSender interface {
+	sendCommand(cmd *resp.Command) (reply interface{}, err error)
+}
+
 type conn struct {
-	cn      net.Conn
-	bw      *bufio.Writer
-	br      *bufio.Reader
-	timeout time.Duration
+	cn    net.Conn
+	w     *resp.Writer
+	r     *resp.Reader
+	dr    *deadlineReader // the reader of r, which sets the read timeout
+	url   string
+	state [][]interface{} // the AUTH and SELECT commands replayed by redial
+	dial  dialFunc
+	log   *slog.Logger
 }
 
 func Dial(urlstring string) (Conn, error) {
 	u, e := url.Parse(urlstring)
 	if e != nil {
-		log.Fatal(e)
+		return nil, e
 	}
 	network := u.Scheme
 	address := u.Host
@@ -42,249 +50,184 @@ func Dial(urlstring string) (Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	w := bufio.NewWriter(cn)
-	r := bufio.NewReader(cn)
-	cli := &conn{cn: cn, bw: w, br: r, timeout: time.Second * 10}
+	timeout := time.Second * 10
+	w := resp.NewWriter(bufio.NewWriter(cn))
+	dr := &deadlineReader{cn, timeout}
+	cli := &conn{cn: cn, w: w, r: resp.NewReader(dr), dr: dr, url: urlstring}
 	return cli, nil
 }
 
-func (c *conn) Close() error {
-	return c.cn.Close()
+// deadlineReader sets the read deadline of the connection before each read of the socket,
+// so a long reply read in many reads times out only when the server stalls. A zero timeout clears the deadline.
+type deadlineReader struct {
+	cn      net.Conn
+	timeout time.Duration
 }
 
-func (c *conn) Send(cmd string, args ...interface{}) (reply interface{}, err error) {
-	c.execute(cmd, args...)
-	err = c.Flush()
-	if err != nil {
-		return nil, err
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	if d.timeout > 0 {
+		d.cn.SetReadDeadline(time.Now().Add(d.timeout))
+	} else {
+		d.cn.SetReadDeadline(time.Time{})
 	}
-	return c.Receive()
+	return d.cn.Read(p)
 }
 
-func (c *conn) Pipe(cmd string, args ...interface{}) error {
-	return c.execute(cmd, args...)
+// readTimeoutSetter is implemented by the connections whose read timeout can be changed,
+// e.g. for MONITOR, whose events may be minutes apart.
+type readTimeoutSetter interface {
+	setReadTimeout(d time.Duration)
 }
 
-func (c *conn) Flush() error {
-	return c.bw.Flush()
+// setReadTimeout sets the read timeout of c, a zero timeout waits for the replies forever.
+func (c *conn) setReadTimeout(d time.Duration) {
+	c.dr.timeout = d
 }
 
-// Receive returns the raw RESP reply without the symbols(+-:$*) and CR&LF.
-// []byte
-//    For Simple Strings the first byte of the reply is "+"
-//    For Integers the first byte of the reply is ":"
-//    For Bulk Strings the first byte of the reply is "$"
-// error
-//    For Errors the first byte of the reply is "-"
-// []interface{} (=[][]byte)
-//    For Arrays the first byte of the reply is "*"
-// nil
-//    For Null Bulk String
-//    For Null Array
-func (c *conn) Receive() (reply interface{}, err error) {
-	c.cn.SetReadDeadline(time.Now().Add(c.timeout))
-	b, e := c.br.ReadSlice('\n')
-	p, m := b[0], b[1:len(b)-2] //[+-:*],[message],[\r\n]
-	if e != nil {
-		return nil, e
-	}
-	switch p {
-	case '+', ':':
-		return m, nil
-	case '-':
-		return errors.New(string(m)), nil
-	case '$':
-		l, _ := strconv.Atoi(string(m)) // todo panic protocol error.
-		if l == -1 {
-			return nil, nil
-		} else {
-			m, e := c.br.ReadBytes('\n')
-			if e != nil {
-				return nil, e
-			}
-			return m[0 : len(m)-2], nil
-		}
-	case '*':
-		l, _ := strconv.Atoi(string(m)) // todo panic protocol error.
-		if l == -1 {
-			return nil, nil
-		} else {
-			a := make([]interface{}, l)
-			for i := 0; i < l; i++ {
-				m, e := c.Receive()
-				a[i] = m
-				if e != nil {
-					return nil, e
-				}
-			}
-			return a, nil
-		}
-	default:
-		panic(fmt.Sprintf("Protocol Error: %s. [+-:*]", string(p)))
-	}
-}
-
-func (c *conn) execute(cmd string, args ...interface{}) (err error) {
-	l := 1 + len(args)
-	c.wLen('*', l)
-	c.wBulkString(cmd)
-	for _, arg := range args {
-		switch v := arg.(type) {
-		case string:
-			err = c.wBulkString(v)
-		case int:
-			err = c.wBulkInt(v)
-		case int32:
-			err = c.wBulkInt32(v)
-		case int64:
-			err = c.wBulkInt64(v)
-		case float32:
-			err = c.wBulkFloat32(v)
-		case float64:
-			err = c.wBulkFloat64(v)
-		case bool:
-			err = c.wBulkBool(v)
-		case nil:
-			err = c.wBulkNil()
-		case uint:
-			err = c.wBulkUint(v)
-		case uint8:
-			err = c.wBulkUint8(v)
-		case uint16:
-			err = c.wBulkUint16(v)
-		case uint32:
-			err = c.wBulkUint32(v)
-		case uint64:
-			err = c.wBulkUint64(v)
-		default:
-			err = c.wBulkOther(v)
-		}
+// redial replaces the network connection by a new one and replays the AUTH and SELECT commands on it.
+func (c *conn) redial() error {
+	nc, err := c.dialState()
+	if err != nil {
+		return err
 	}
-	return
-}
-
-func (c *conn) wLen(p byte, i int) (err error) {
-	c.bw.WriteByte(p)
-	c.wInt(i)
-	return c.wCRLF()
-}
-
-func (c *conn) wBulkString(s string) (err error) {
-	l := len(s)
-	c.wLen('$', l)
-	c.bw.WriteString(s)
-	err = c.wCRLF()
-	return
-}
-
-func (c *conn) wBulkByte(b byte) (err error) {
-	c.wLen('$', 1)
-	c.bw.WriteByte(b)
-	err = c.wCRLF()
-	return
-}
-
-func (c *conn) wBulkBytes(b []byte) (err error) {
-	l := len(b)
-	c.wLen('$', l)
-	c.bw.Write(b)
-	err = c.wCRLF()
-	return
+	c.cn.Close()
+	nc.dr.timeout = c.dr.timeout
+	c.cn, c.w, c.r, c.dr = nc.cn, nc.w, nc.r, nc.dr
+	logAttr(c.log, slog.LevelInfo, "redis: reconnected", "url", c.url)
+	return nil
 }
 
-func (c *conn) wBulkInt(i int) (err error) {
-	return c.wBulkInt64(int64(i))
+// dialDedicated returns a new connection to the url of c, authenticated and selected as c.
+func (c *conn) dialDedicated() (Conn, error) {
+	return c.dialState()
 }
 
-func (c *conn) wBulkInt32(i int32) (err error) {
-	return c.wBulkInt64(int64(i))
-}
-
-func (c *conn) wBulkInt64(i int64) (err error) {
-	b := strconv.AppendInt([]byte{}, i, 10)
-	l := len(b)
-	c.wLen('$', l)
-	c.bw.Write(b)
-	err = c.wCRLF()
-	return
-}
-
-func (c *conn) wBulkUint(i uint) (err error) {
-	return c.wBulkUint64(uint64(i))
+// dialState dials the url of c and replays the AUTH and SELECT commands of c on the new connection.
+func (c *conn) dialState() (*conn, error) {
+	cn, err := dialWith(c.dial, c.url)
+	if err != nil {
+		return nil, err
+	}
+	nc := cn.(*conn)
+	nc.log = c.log
+	for _, s := range c.state {
+		rsp, err := nc.Send(s[0].(string), s[1:]...)
+		if err == nil {
+			err, _ = rsp.(error)
+		}
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return nc, nil
 }
 
-func (c *conn) wBulkUint8(i uint8) (err error) {
-	return c.wBulkUint64(uint64(i))
+func (c *conn) notifyDial(fn dialFunc) {
+	c.dial = fn
 }
 
-func (c *conn) wBulkUint16(i uint16) (err error) {
-	return c.wBulkUint64(uint64(i))
+func (c *conn) setLogger(l *slog.Logger) {
+	c.log = l
 }
 
-func (c *conn) wBulkUint32(i uint32) (err error) {
-	return c.wBulkUint64(uint64(i))
+// remember records the successful AUTH and SELECT commands, the last one of each kind is kept.
+func (c *conn) remember(cmd string, args []interface{}, reply interface{}) {
+	if _, e := reply.(error); e {
+		return
+	}
+	cmd = strings.ToUpper(cmd)
+	if cmd != "AUTH" && cmd != "SELECT" {
+		return
+	}
+	for i, s := range c.state {
+		if s[0] == cmd {
+			c.state = append(c.state[:i], c.state[i+1:]...)
+			break
+		}
+	}
+	c.state = append(c.state, append([]interface{}{cmd}, args...))
 }
 
-func (c *conn) wBulkUint64(i uint64) (err error) {
-	b := strconv.AppendUint([]byte{}, i, 10)
-	l := len(b)
-	c.wLen('$', l)
-	c.bw.Write(b)
-	err = c.wCRLF()
-	return
+func (c *conn) Close() error {
+	logAttr(c.log, slog.LevelDebug, "redis: connection closed", "url", c.url)
+	return c.cn.Close()
 }
 
-func (c *conn) wBulkFloat32(f float32) (err error) {
-	return c.wBulkFloat64(float64(f))
+func (c *conn) Send(cmd string, args ...interface{}) (reply interface{}, err error) {
+	c.execute(cmd, args...)
+	err = c.Flush()
+	if err != nil {
+		return nil, err
+	}
+	reply, err = c.Receive()
+	if err == nil {
+		c.remember(cmd, args, reply)
+	}
+	return reply, err
 }
 
-func (c *conn) wBulkFloat64(f float64) (err error) {
-	b := strconv.AppendFloat([]byte{}, f, 'g', -1, 64)
-	l := len(b)
-	c.wLen('$', l)
-	c.bw.Write(b)
-	err = c.wCRLF()
-	return
+func (c *conn) sendCommand(cmd *resp.Command) (reply interface{}, err error) {
+	c.w.Encode(cmd)
+	err = c.Flush()
+	if err != nil {
+		return nil, err
+	}
+	reply, err = c.Receive()
+	if name := cmd.Name(); err == nil && (strings.EqualFold(name, "AUTH") || strings.EqualFold(name, "SELECT")) {
+		c.remember(name, cmd.Args(), reply)
+	}
+	return reply, err
 }
 
-func (c *conn) wBulkBool(b bool) (err error) {
-	c.wLen('$', 1)
-	if b {
-		err = c.bw.WriteByte('1')
-	} else {
-		err = c.bw.WriteByte('0')
+func (c *conn) sendReader(cmd string, args []interface{}) (*resp.Reader, func(broken bool), error) {
+	c.execute(cmd, args...)
+	if err := c.Flush(); err != nil {
+		return nil, nil, err
 	}
-	return
+	return c.r, func(broken bool) {
+		if broken {
+			c.cn.Close()
+		}
+	}, nil
 }
 
-func (c *conn) wBulkNil() (err error) {
-	c.wLen('$', 0)
-	_, err = c.bw.WriteString("")
-	return
+func (c *conn) Pipe(cmd string, args ...interface{}) error {
+	return c.execute(cmd, args...)
 }
 
-func (c *conn) wBulkOther(o interface{}) (err error) {
-	var buf bytes.Buffer
-	fmt.Fprint(&buf, o)
-	b := buf.Bytes()
-	l := len(b)
-	c.wLen('$', l)
-	c.wBulkBytes(b)
-	return
+func (c *conn) Flush() error {
+	return c.w.Flush()
 }
 
-func (c *conn) wCRLF() (err error) {
-	const crlf = "\r\n"
-	_, err = c.bw.WriteString(crlf)
-	return
+// Receive returns the raw RESP reply without the symbols(+-:$*) and CR&LF.
+// []byte
+//    For Simple Strings the first byte of the reply is "+"
+//    For Integers the first byte of the reply is ":"
+//    For Bulk Strings the first byte of the reply is "$"
+// error (resp.Error)
+//    For Errors the first byte of the reply is "-"
+// []interface{} (=[][]byte)
+//    For Arrays the first byte of the reply is "*"
+// nil
+//    For Null Bulk String
+//    For Null Array
+// The RESP3 replies are returned as their RESP2 equivalents, see resp.Reader.
+func (c *conn) Receive() (reply interface{}, err error) {
+	reply, err = c.r.ReadValue()
+	if e, ok := err.(*resp.ProtocolError); ok {
+		return nil, c.protocolError(e.Line)
+	}
+	return reply, err
 }
 
-func (c *conn) wInt(i int) (err error) {
-	return c.wInt64(int64(i))
+// protocolError logs and returns the error of an unexpected reply line.
+func (c *conn) protocolError(line string) error {
+	err := fmt.Errorf("redis: protocol error: %q.", line)
+	logAttr(c.log, slog.LevelError, "redis: protocol error", "url", c.url, "line", line)
+	return err
 }
 
-func (c *conn) wInt64(i int64) (err error) {
-	m := strconv.AppendInt([]byte{}, i, 10)
-	_, err = c.bw.Write(m)
-	return
+func (c *conn) execute(cmd string, args ...interface{}) (err error) {
+	return c.w.WriteCommand(cmd, args...)
 }
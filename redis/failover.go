@@ -0,0 +1,356 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailoverOptions configures a client created by NewFailoverClient.
+type FailoverOptions struct {
+	LogOptions
+	// Password is sent by AUTH on every new master connection when it is not empty.
+	Password string
+	// RetryInterval is the delay before the next sentinel is tried by the failover watcher, 1 second by default.
+	RetryInterval time.Duration
+}
+
+// failoverConn sends the commands to the master resolved by the sentinels and follows its failovers.
+type failoverConn struct {
+	mu         sync.Mutex
+	masterName string
+	sentinels  []string
+	opt        FailoverOptions
+	addr       string
+	cn         Conn
+	ps         *PubSub
+	dial       dialFunc
+	log        *slog.Logger
+	done       chan struct{}
+}
+
+// NewFailoverClient returns a client of the master monitored by the sentinels under masterName.
+// The master is resolved by SENTINEL get-master-addr-by-name and verified by ROLE,
+// the client follows the +switch-master events of the sentinels and reconnects to the new master,
+// so a failover surfaces at most as one transient error to the callers.
+func NewFailoverClient(masterName string, sentinelAddrs []string, opt FailoverOptions) (Client, error) {
+	if len(sentinelAddrs) == 0 {
+		return Client{}, errors.New("redis: no sentinel address.")
+	}
+	if opt.RetryInterval <= 0 {
+		opt.RetryInterval = time.Second
+	}
+	fc := &failoverConn{
+		masterName: masterName,
+		sentinels:  append([]string{}, sentinelAddrs...),
+		opt:        opt,
+		log:        opt.Logger,
+		done:       make(chan struct{}),
+	}
+	if err := fc.connect(); err != nil {
+		return Client{}, err
+	}
+	go fc.watch()
+	cli := Client{cn: fc}
+	cli.SetLogger(opt.LogOptions)
+	return cli, nil
+}
+
+// masterAddr asks the sentinels for the address of the master, the first sentinel which answers is moved to the front.
+func (fc *failoverConn) masterAddr() (string, error) {
+	var err error
+	for i, s := range fc.sentinels {
+		var addr string
+		addr, err = getMasterAddrByName(s, fc.masterName)
+		if err != nil {
+			continue
+		}
+		fc.sentinels[0], fc.sentinels[i] = fc.sentinels[i], fc.sentinels[0]
+		return addr, nil
+	}
+	return "", fmt.Errorf("redis: could not resolve master %q: %v", fc.masterName, err)
+}
+
+// SENTINEL get-master-addr-by-name master-name
+// Return the ip and port number of the master with that name.
+func getMasterAddrByName(sentinel, masterName string) (string, error) {
+	u, err := url.Parse(sentinel)
+	if err != nil {
+		return "", err
+	}
+	cn, err := Dial(sentinel)
+	if err != nil {
+		return "", err
+	}
+	defer cn.Close()
+	rsp, err := cn.Send("SENTINEL", "get-master-addr-by-name", masterName)
+	if err != nil {
+		return "", err
+	}
+	if e, ok := rsp.(error); ok {
+		return "", e
+	}
+	a, ok := rsp.([]interface{})
+	if !ok || len(a) != 2 {
+		return "", fmt.Errorf("redis: unknown master %q.", masterName)
+	}
+	host, _ := String(a[0])
+	port, _ := String(a[1])
+	return u.Scheme + "://" + net.JoinHostPort(host, port), nil
+}
+
+// connect dials the current master and verifies its role, the caller must hold fc.mu or own fc exclusively.
+func (fc *failoverConn) connect() error {
+	addr, err := fc.masterAddr()
+	if err != nil {
+		return err
+	}
+	cn, err := fc.dialMaster(addr)
+	if err != nil {
+		return err
+	}
+	rsp, err := cn.Send("ROLE")
+	if err != nil {
+		cn.Close()
+		return err
+	}
+	role := ""
+	if a, ok := rsp.([]interface{}); ok && len(a) > 0 {
+		role, _ = String(a[0])
+	}
+	if role != "master" {
+		cn.Close()
+		return fmt.Errorf("redis: %s is not a master (role: %q).", addr, role)
+	}
+	fc.addr, fc.cn = addr, cn
+	logAttr(fc.log, slog.LevelInfo, "redis: connected to master", "master", fc.masterName, "url", addr)
+	return nil
+}
+
+// dialMaster dials addr and authenticates with FailoverOptions.Password.
+func (fc *failoverConn) dialMaster(addr string) (Conn, error) {
+	cn, err := dialWith(fc.dial, addr)
+	if err != nil {
+		return nil, err
+	}
+	if fc.opt.Password != "" {
+		rsp, err := cn.Send("AUTH", fc.opt.Password)
+		if err == nil {
+			err, _ = rsp.(error)
+		}
+		if err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// dialDedicated returns a new connection to the current master.
+func (fc *failoverConn) dialDedicated() (Conn, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if _, err := fc.conn(); err != nil {
+		return nil, err
+	}
+	return fc.dialMaster(fc.addr)
+}
+
+// reset drops the current master connection, the next command reconnects to the master.
+func (fc *failoverConn) reset() {
+	if fc.cn != nil {
+		fc.cn.Close()
+		fc.cn = nil
+	}
+}
+
+// conn returns the master connection, connecting if needed.
+func (fc *failoverConn) conn() (Conn, error) {
+	if fc.cn == nil {
+		if err := fc.connect(); err != nil {
+			return nil, err
+		}
+	}
+	return fc.cn, nil
+}
+
+// watch subscribes to +switch-master on the sentinels and switches to the new master on failover,
+// the master is resolved again after every subscription.
+//
+//	+switch-master <master name> <old ip> <old port> <new ip> <new port>
+func (fc *failoverConn) watch() {
+	for {
+		fc.mu.Lock()
+		sentinel := fc.sentinels[0]
+		fc.mu.Unlock()
+		fc.subscribe(sentinel)
+		// The subscription failed or was lost, the next sentinel is tried.
+		fc.mu.Lock()
+		if len(fc.sentinels) > 1 && fc.sentinels[0] == sentinel {
+			fc.sentinels = append(fc.sentinels[1:], sentinel)
+		}
+		fc.mu.Unlock()
+		select {
+		case <-fc.done:
+			return
+		case <-time.After(fc.opt.RetryInterval):
+		}
+	}
+}
+
+func (fc *failoverConn) subscribe(sentinel string) {
+	u, err := url.Parse(sentinel)
+	if err != nil {
+		return
+	}
+	ps, err := NewPubSub(sentinel)
+	if err != nil {
+		return
+	}
+	fc.mu.Lock()
+	select {
+	case <-fc.done:
+		fc.mu.Unlock()
+		ps.Close()
+		return
+	default:
+		fc.ps = &ps
+	}
+	fc.mu.Unlock()
+	defer func() {
+		fc.mu.Lock()
+		fc.ps = nil
+		fc.mu.Unlock()
+		ps.Close()
+	}()
+
+	if ps.Subscribe("+switch-master") != nil {
+		return
+	}
+	// The events published while the subscription was down are lost, the master is resolved again.
+	if addr, err := getMasterAddrByName(sentinel, fc.masterName); err == nil {
+		fc.switchMaster(addr)
+	}
+	defer logAttr(fc.log, slog.LevelWarn, "redis: sentinel subscription lost", "sentinel", sentinel)
+	for {
+		switch m := ps.Receive().(type) {
+		case Message:
+			f := strings.Fields(m.Text)
+			if len(f) != 5 || f[0] != fc.masterName {
+				continue
+			}
+			fc.switchMaster(u.Scheme + "://" + net.JoinHostPort(f[3], f[4]))
+		case net.Error:
+			if !m.Timeout() {
+				return
+			}
+		case error:
+			return
+		}
+	}
+}
+
+func (fc *failoverConn) notifyDial(fn dialFunc) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.dial = fn
+}
+
+func (fc *failoverConn) setLogger(l *slog.Logger) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.log = l
+}
+
+// switchMaster drops the connection to the old master, a pending pipeline is discarded.
+func (fc *failoverConn) switchMaster(addr string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if addr != fc.addr {
+		logAttr(fc.log, slog.LevelInfo, "redis: master switched", "master", fc.masterName, "from", fc.addr, "to", addr)
+		fc.reset()
+	}
+}
+
+// failed drops the master connection on network errors and on READONLY replies of a demoted master.
+func (fc *failoverConn) failed(reply interface{}, err error) {
+	if err != nil {
+		fc.reset()
+		return
+	}
+	if e, ok := reply.(error); ok && strings.HasPrefix(e.Error(), "READONLY") {
+		logAttr(fc.log, slog.LevelWarn, "redis: master was demoted", "master", fc.masterName, "url", fc.addr)
+		fc.reset()
+	}
+}
+
+func (fc *failoverConn) Send(cmd string, args ...interface{}) (reply interface{}, err error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	cn, err := fc.conn()
+	if err != nil {
+		return nil, err
+	}
+	reply, err = cn.Send(cmd, args...)
+	fc.failed(reply, err)
+	return reply, err
+}
+
+func (fc *failoverConn) Pipe(cmd string, args ...interface{}) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	cn, err := fc.conn()
+	if err != nil {
+		return err
+	}
+	err = cn.Pipe(cmd, args...)
+	fc.failed(nil, err)
+	return err
+}
+
+func (fc *failoverConn) Flush() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.cn == nil {
+		return errors.New("redis: master connection was reset.")
+	}
+	err := fc.cn.Flush()
+	fc.failed(nil, err)
+	return err
+}
+
+func (fc *failoverConn) Receive() (reply interface{}, err error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.cn == nil {
+		return nil, errors.New("redis: master connection was reset.")
+	}
+	reply, err = fc.cn.Receive()
+	fc.failed(reply, err)
+	return reply, err
+}
+
+func (fc *failoverConn) Close() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	select {
+	case <-fc.done:
+		return errors.New("redis: client is closed.")
+	default:
+		close(fc.done)
+	}
+	if fc.ps != nil {
+		fc.ps.Close()
+	}
+	fc.reset()
+	return nil
+}
@@ -0,0 +1,120 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"github.com/qqbuby/goredis/redis"
+	"github.com/qqbuby/goredis/redis/redistest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFailoverClient(t *testing.T) {
+	_, err := redis.NewFailoverClient("mymaster", nil, redis.FailoverOptions{})
+	if err == nil {
+		t.Error("NewFailoverClient did not work properly: no sentinel.")
+	}
+	// A plain Redis server is not a sentinel, the master can not be resolved.
+	_, err = redis.NewFailoverClient("mymaster", []string{url}, redis.FailoverOptions{})
+	if err == nil {
+		t.Error("NewFailoverClient did not work properly: not a sentinel.")
+	}
+}
+
+// switchMaster publishes +switch-master on the sentinel until the client receives it.
+func switchMaster(t *testing.T, sentinel redis.Client, from, to *redistest.Server) {
+	oldHost, oldPort, _ := strings.Cut(from.Addr(), ":")
+	newHost, newPort, _ := strings.Cut(to.Addr(), ":")
+	event := strings.Join([]string{"mymaster", oldHost, oldPort, newHost, newPort}, " ")
+	for deadline := time.Now().Add(time.Second * 5); time.Now().Before(deadline); time.Sleep(time.Millisecond * 10) {
+		if n, _ := sentinel.Publish("+switch-master", event); n > 0 {
+			return
+		}
+	}
+	t.Fatal("FailoverClient did not work properly: not subscribed to +switch-master.")
+}
+
+// waitMaster sets a key through the failover client until it is written to the master.
+func waitMaster(t *testing.T, cli redis.Client, master redis.Client) {
+	const key = "TEST:FAILOVER"
+	for deadline := time.Now().Add(time.Second * 5); time.Now().Before(deadline); time.Sleep(time.Millisecond * 10) {
+		master.Del(key)
+		cli.Set(key, "value")
+		if n, _ := master.Exists(key); n == 1 {
+			return
+		}
+	}
+	t.Fatal("FailoverClient did not work properly: the key was not written to the master.")
+}
+
+func TestFailoverClientSentinel(t *testing.T) {
+	sentinel, sentinelCli := newScratchClient(t)
+	master1, master1Cli := newScratchClient(t)
+	master2, master2Cli := newScratchClient(t)
+	replica, replicaCli := newScratchClient(t)
+	host, port, _ := strings.Cut(master1.Addr(), ":")
+	if _, err := replicaCli.Send("REPLICAOF", host, port); err != nil {
+		t.Fatalf("REPLICAOF: %s", err.Error())
+	}
+
+	// The master must reply master to ROLE.
+	sentinel.SetSentinelMaster("mymaster", replica.Addr())
+	if _, err := redis.NewFailoverClient("mymaster", []string{sentinel.URL()}, redis.FailoverOptions{}); err == nil {
+		t.Error("NewFailoverClient did not work properly: the master is a replica.")
+	}
+
+	sentinel.SetSentinelMaster("mymaster", master1.Addr())
+	cli, err := redis.NewFailoverClient("mymaster", []string{sentinel.URL()}, redis.FailoverOptions{RetryInterval: time.Millisecond * 50})
+	if err != nil {
+		t.Fatalf("NewFailoverClient: %s", err.Error())
+	}
+	defer cli.Close()
+	waitMaster(t, cli, master1Cli)
+
+	// +switch-master
+	sentinel.SetSentinelMaster("mymaster", master2.Addr())
+	switchMaster(t, sentinelCli, master1, master2)
+	waitMaster(t, cli, master2Cli)
+
+	// The switch is caught up when the subscription comes back.
+	sentinel.SetSentinelMaster("mymaster", master1.Addr())
+	if n, err := sentinelCli.ClientKill("TYPE", "pubsub"); n != 1 || err != nil {
+		t.Fatalf("ClientKill did not work properly. E:%d, R:%d %v", 1, n, err)
+	}
+	waitMaster(t, cli, master1Cli)
+}
+
+func TestFailoverClientNextSentinel(t *testing.T) {
+	sentinel0, err := redistest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %s", err.Error())
+	}
+	sentinel0Cli, err := redis.NewClient(sentinel0.URL())
+	if err != nil {
+		sentinel0.Close()
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	sentinel1, sentinel1Cli := newScratchClient(t)
+	master1, master1Cli := newScratchClient(t)
+	master2, master2Cli := newScratchClient(t)
+	sentinel0.SetSentinelMaster("mymaster", master1.Addr())
+	sentinel1.SetSentinelMaster("mymaster", master1.Addr())
+
+	cli, err := redis.NewFailoverClient("mymaster", []string{sentinel0.URL(), sentinel1.URL()}, redis.FailoverOptions{RetryInterval: time.Millisecond * 50})
+	if err != nil {
+		t.Fatalf("NewFailoverClient: %s", err.Error())
+	}
+	defer cli.Close()
+	waitMaster(t, cli, master1Cli)
+	switchMaster(t, sentinel0Cli, master1, master1)
+
+	// The master is healthy when the first sentinel stops, the watcher moves to the second one.
+	sentinel0Cli.Close()
+	sentinel0.Close()
+	sentinel1.SetSentinelMaster("mymaster", master2.Addr())
+	switchMaster(t, sentinel1Cli, master1, master2)
+	waitMaster(t, cli, master2Cli)
+}
@@ -0,0 +1,170 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"fmt"
+)
+
+// GeoLocation is a member of a geospatial index with its position,
+// Dist and GeoHash are only set by GEOSEARCH WITHDIST and WITHHASH.
+type GeoLocation struct {
+	Name      string
+	Longitude float64
+	Latitude  float64
+	// Dist is the distance to the center of the search, in the unit of the search.
+	Dist float64
+	// GeoHash is the 52 bits geohash, the score of the member in the sorted set.
+	GeoHash int64
+}
+
+// GeoSearchQuery is the query of GEOSEARCH and GEOSEARCHSTORE, built by chaining:
+//
+//	q := redis.GeoSearchQuery{}.FromLonLat(15, 37).ByRadius(200, "km").Asc().Count(10, false).WithDist()
+//	locations, err := client.GeoSearch("Sicily", q)
+//
+// A query needs exactly one of FromMember and FromLonLat, and one of ByRadius and ByBox.
+// GEOSEARCHSTORE ignores WithCoord, WithDist and WithHash.
+type GeoSearchQuery struct {
+	from, by, order, count        []interface{}
+	withCoord, withDist, withHash bool
+}
+
+// FromMember returns the query centered on the position of a member.
+func (q GeoSearchQuery) FromMember(member interface{}) GeoSearchQuery {
+	q.from = []interface{}{"FROMMEMBER", member}
+	return q
+}
+
+// FromLonLat returns the query centered on a position.
+func (q GeoSearchQuery) FromLonLat(longitude, latitude float64) GeoSearchQuery {
+	q.from = []interface{}{"FROMLONLAT", longitude, latitude}
+	return q
+}
+
+// ByRadius returns the query of the members within a radius, unit is m, km, ft or mi.
+func (q GeoSearchQuery) ByRadius(radius float64, unit string) GeoSearchQuery {
+	q.by = []interface{}{"BYRADIUS", radius, unit}
+	return q
+}
+
+// ByBox returns the query of the members within an axis-aligned rectangle, unit is m, km, ft or mi.
+func (q GeoSearchQuery) ByBox(width, height float64, unit string) GeoSearchQuery {
+	q.by = []interface{}{"BYBOX", width, height, unit}
+	return q
+}
+
+// Asc returns the query sorted from the nearest to the farthest member.
+func (q GeoSearchQuery) Asc() GeoSearchQuery {
+	q.order = []interface{}{"ASC"}
+	return q
+}
+
+// Desc returns the query sorted from the farthest to the nearest member.
+func (q GeoSearchQuery) Desc() GeoSearchQuery {
+	q.order = []interface{}{"DESC"}
+	return q
+}
+
+// Count returns the query limited to count members, firstFound (ANY) returns the first members found
+// instead of the nearest ones.
+func (q GeoSearchQuery) Count(count int, firstFound bool) GeoSearchQuery {
+	q.count = []interface{}{"COUNT", count}
+	if firstFound {
+		q.count = append(q.count, "ANY")
+	}
+	return q
+}
+
+// WithCoord returns the query which replies the positions of the members.
+func (q GeoSearchQuery) WithCoord() GeoSearchQuery {
+	q.withCoord = true
+	return q
+}
+
+// WithDist returns the query which replies the distances of the members to the center.
+func (q GeoSearchQuery) WithDist() GeoSearchQuery {
+	q.withDist = true
+	return q
+}
+
+// WithHash returns the query which replies the geohashes of the members.
+func (q GeoSearchQuery) WithHash() GeoSearchQuery {
+	q.withHash = true
+	return q
+}
+
+// args returns the arguments of the query, the reply options are added when with is true.
+func (q GeoSearchQuery) args(with bool) []interface{} {
+	args := []interface{}{}
+	for _, a := range [][]interface{}{q.from, q.by, q.order, q.count} {
+		args = append(args, a...)
+	}
+	if with {
+		if q.withCoord {
+			args = append(args, "WITHCOORD")
+		}
+		if q.withDist {
+			args = append(args, "WITHDIST")
+		}
+		if q.withHash {
+			args = append(args, "WITHHASH")
+		}
+	}
+	return args
+}
+
+// parseGeoLocations parses the reply of GEOSEARCH, the members are followed by the distance,
+// the geohash and the position requested by the query, in this order.
+func (q GeoSearchQuery) parseGeoLocations(reply interface{}) ([]GeoLocation, error) {
+	if !q.withCoord && !q.withDist && !q.withHash {
+		return Slice(reply, func(p interface{}) (GeoLocation, error) {
+			name, err := String(p)
+			return GeoLocation{Name: name}, err
+		})
+	}
+	return Slice(reply, func(p interface{}) (GeoLocation, error) {
+		loc := GeoLocation{}
+		a, err := Values(p)
+		if err != nil {
+			return loc, err
+		}
+		n := 1
+		for _, w := range []bool{q.withDist, q.withHash, q.withCoord} {
+			if w {
+				n++
+			}
+		}
+		if len(a) != n {
+			return loc, fmt.Errorf("redis.GeoSearch: a location has %d elements, not %d.", len(a), n)
+		}
+		if loc.Name, err = String(a[0]); err != nil {
+			return loc, err
+		}
+		i := 1
+		if q.withDist {
+			if loc.Dist, err = Float64(a[i]); err != nil {
+				return loc, err
+			}
+			i++
+		}
+		if q.withHash {
+			if loc.GeoHash, err = Int64(a[i]); err != nil {
+				return loc, err
+			}
+			i++
+		}
+		if q.withCoord {
+			pos, err := Positions([]interface{}{a[i]})
+			if err != nil {
+				return loc, err
+			}
+			if pos[0] != nil {
+				loc.Longitude, loc.Latitude = pos[0][0], pos[0][1]
+			}
+		}
+		return loc, nil
+	})
+}
@@ -0,0 +1,243 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CmdInfo describes a command to the hooks.
+type CmdInfo struct {
+	Name string
+	Args []interface{}
+	// Reply and Err are set before AfterProcess and AfterPipeline,
+	// Err is the network error or else the error reply of the command, or the error of a Before call which aborted it.
+	Reply    interface{}
+	Err      error
+	Duration time.Duration
+}
+
+// Hook is called around the execution of the commands of a Client or a PubSub.
+//
+// BeforeProcess and BeforePipeline may return a derived context which is passed to the matching After call,
+// a non-nil error aborts the command or the pipeline and is returned to the caller,
+// the hooks whose Before call already ran get the matching After call with this error.
+// A non-nil error of AfterProcess or AfterPipeline is returned to the caller instead of the error of the command.
+// OnDial is called for every connection dialed by the client after the hook was added.
+type Hook interface {
+	BeforeProcess(ctx context.Context, cmd *CmdInfo) (context.Context, error)
+	AfterProcess(ctx context.Context, cmd *CmdInfo) error
+	BeforePipeline(ctx context.Context, cmds []*CmdInfo) (context.Context, error)
+	AfterPipeline(ctx context.Context, cmds []*CmdInfo) error
+	OnDial(ctx context.Context, network, addr string, d time.Duration, err error)
+}
+
+// BaseHook is a Hook which does nothing, it is embedded by the hooks implementing only a few calls.
+type BaseHook struct{}
+
+func (BaseHook) BeforeProcess(ctx context.Context, cmd *CmdInfo) (context.Context, error) {
+	return ctx, nil
+}
+
+func (BaseHook) AfterProcess(ctx context.Context, cmd *CmdInfo) error {
+	return nil
+}
+
+func (BaseHook) BeforePipeline(ctx context.Context, cmds []*CmdInfo) (context.Context, error) {
+	return ctx, nil
+}
+
+func (BaseHook) AfterPipeline(ctx context.Context, cmds []*CmdInfo) error {
+	return nil
+}
+
+func (BaseHook) OnDial(ctx context.Context, network, addr string, d time.Duration, err error) {}
+
+// hooks is the hook chain shared by the copies of a client. The chain is copied on write,
+// so the commands running while a hook is added see either the old or the new chain.
+type hooks struct {
+	mu   sync.Mutex   // serializes add
+	list atomic.Value // []Hook
+}
+
+func (h *hooks) add(hook Hook) *hooks {
+	if h == nil {
+		h = &hooks{}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	list := h.get()
+	h.list.Store(append(list[:len(list):len(list)], hook))
+	return h
+}
+
+// set replaces the first hook matched by same with hook, or adds hook when none is matched.
+func (h *hooks) set(hook Hook, same func(Hook) bool) *hooks {
+	if h == nil {
+		h = &hooks{}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	list := h.get()
+	for i, hk := range list {
+		if same(hk) {
+			l := append([]Hook{}, list...)
+			l[i] = hook
+			h.list.Store(l)
+			return h
+		}
+	}
+	h.list.Store(append(list[:len(list):len(list)], hook))
+	return h
+}
+
+// get returns the current chain, it must not be modified.
+func (h *hooks) get() []Hook {
+	if h == nil {
+		return nil
+	}
+	list, _ := h.list.Load().([]Hook)
+	return list
+}
+
+// process runs the command through the hooks, the first hook added is the outermost.
+// When a BeforeProcess fails, the command is not sent and the hooks which already ran get AfterProcess
+// with the error, so they can release what BeforeProcess acquired, e.g. a span.
+func (h *hooks) process(ctx context.Context, cmd string, args []interface{}, send func(string, ...interface{}) (interface{}, error)) (interface{}, error) {
+	list := h.get()
+	if len(list) == 0 {
+		return send(cmd, args...)
+	}
+	info := &CmdInfo{Name: cmd, Args: args}
+	ctxs := make([]context.Context, len(list))
+	var reply interface{}
+	var err error
+	n := 0
+	for ; n < len(list); n++ {
+		c, e := list[n].BeforeProcess(ctx, info)
+		if e != nil {
+			err = e
+			break
+		}
+		ctx, ctxs[n] = c, c
+	}
+	if err == nil {
+		start := time.Now()
+		reply, err = send(cmd, args...)
+		info.Reply, info.Err, info.Duration = reply, replyErr(reply, err), time.Since(start)
+	} else {
+		info.Err = err
+	}
+	for i := n - 1; i >= 0; i-- {
+		if e := list[i].AfterProcess(ctxs[i], info); e != nil {
+			err = e
+		}
+	}
+	return reply, err
+}
+
+// pipeline runs the queued commands through the hooks.
+func (h *hooks) pipeline(ctx context.Context, cmds []*CmdInfo, exec func([]*CmdInfo) error) error {
+	list := h.get()
+	if len(list) == 0 {
+		return exec(cmds)
+	}
+	ctxs := make([]context.Context, len(list))
+	var err error
+	n := 0
+	for ; n < len(list); n++ {
+		c, e := list[n].BeforePipeline(ctx, cmds)
+		if e != nil {
+			err = e
+			break
+		}
+		ctx, ctxs[n] = c, c
+	}
+	if err == nil {
+		err = exec(cmds)
+	} else {
+		for _, cmd := range cmds {
+			cmd.Err = err
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if e := list[i].AfterPipeline(ctxs[i], cmds); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (h *hooks) onDial(network, addr string, d time.Duration, err error) {
+	for _, hk := range h.get() {
+		hk.OnDial(context.Background(), network, addr, d, err)
+	}
+}
+
+// replyErr returns the network error or else the error reply.
+func replyErr(reply interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	e, _ := reply.(error)
+	return e
+}
+
+type dialFunc func(network, addr string, d time.Duration, err error)
+
+// dialNotifier is implemented by the connections which dial new network connections after their creation.
+type dialNotifier interface {
+	notifyDial(fn dialFunc)
+}
+
+// dialWith dials the url and reports the dial to fn when it is not nil.
+func dialWith(fn dialFunc, urlstring string) (Conn, error) {
+	start := time.Now()
+	cn, err := Dial(urlstring)
+	if fn == nil {
+		return cn, err
+	}
+	network, addr := "", urlstring
+	if u, e := url.Parse(urlstring); e == nil {
+		network, addr = u.Scheme, u.Host
+	}
+	fn(network, addr, time.Since(start), err)
+	if d, ok := cn.(dialNotifier); ok {
+		d.notifyDial(fn)
+	}
+	return cn, err
+}
+
+// AddHook adds a hook to the client, the hooks are shared by the copies of the client made afterwards.
+func (cli *Client) AddHook(hook Hook) {
+	cli.hooks = cli.hooks.add(hook)
+	if d, ok := cli.cn.(dialNotifier); ok {
+		d.notifyDial(cli.hooks.onDial)
+	}
+}
+
+// WithContext returns a copy of the client whose commands pass ctx to the hooks.
+func (cli *Client) WithContext(ctx context.Context) Client {
+	c := *cli
+	c.ctx = ctx
+	return c
+}
+
+// Context returns the context of the client, context.Background() by default.
+func (cli *Client) Context() context.Context {
+	if cli.ctx == nil {
+		return context.Background()
+	}
+	return cli.ctx
+}
+
+// AddHook adds a hook to the PubSub, the subscription commands are reported without reply.
+func (p *PubSub) AddHook(hook Hook) {
+	p.hooks = p.hooks.add(hook)
+}
@@ -0,0 +1,127 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"github.com/qqbuby/goredis/redis"
+	"sync"
+	"testing"
+)
+
+type ctxKey struct{}
+
+type recordHook struct {
+	redis.BaseHook
+	before, after []string
+	pipelines     []int
+	ctxValue      interface{}
+}
+
+func (h *recordHook) BeforeProcess(ctx context.Context, cmd *redis.CmdInfo) (context.Context, error) {
+	h.before = append(h.before, cmd.Name)
+	return context.WithValue(ctx, ctxKey{}, cmd.Name), nil
+}
+
+func (h *recordHook) AfterProcess(ctx context.Context, cmd *redis.CmdInfo) error {
+	h.after = append(h.after, cmd.Name)
+	h.ctxValue = ctx.Value(ctxKey{})
+	return nil
+}
+
+func (h *recordHook) AfterPipeline(ctx context.Context, cmds []*redis.CmdInfo) error {
+	h.pipelines = append(h.pipelines, len(cmds))
+	return nil
+}
+
+func TestAddHook(t *testing.T) {
+	const (
+		key   = "TEST:HOOK"
+		value = key
+	)
+	client, e := redis.NewClient(url)
+	if e != nil {
+		t.Fatalf("AddHook: %s", e.Error())
+	}
+	defer client.Close()
+
+	h := &recordHook{}
+	client.AddHook(h)
+	client.Set(key, value)
+	client.Get(key)
+	if len(h.before) != 2 || h.before[0] != "SET" || h.after[1] != "GET" {
+		t.Errorf("AddHook did not work properly. B:%v, A:%v", h.before, h.after)
+	}
+	if h.ctxValue != "GET" {
+		t.Errorf("AddHook did not work properly. Context:%v", h.ctxValue)
+	}
+
+	p := client.Pipeline()
+	p.Queue("SET", key, value)
+	p.Queue("GET", key)
+	r, err := p.Exec()
+	if err != nil || len(r) != 2 || string(r[1].([]byte)) != value {
+		t.Errorf("Pipeline did not work properly. R:%v, E:%v", r, err)
+	}
+	if len(h.pipelines) != 1 || h.pipelines[0] != 2 {
+		t.Errorf("AddHook did not work properly. Pipelines:%v", h.pipelines)
+	}
+}
+
+type failHook struct {
+	redis.BaseHook
+	err error
+}
+
+func (h failHook) BeforeProcess(ctx context.Context, cmd *redis.CmdInfo) (context.Context, error) {
+	return ctx, h.err
+}
+
+func (h failHook) BeforePipeline(ctx context.Context, cmds []*redis.CmdInfo) (context.Context, error) {
+	return ctx, h.err
+}
+
+func TestAddHookBeforeError(t *testing.T) {
+	_, client := newScratchClient(t)
+	h := &recordHook{}
+	failed := errors.New("failed")
+	client.AddHook(h)
+	client.AddHook(failHook{err: failed})
+
+	if _, err := client.Send("PING"); err != failed {
+		t.Errorf("AddHook did not work properly. E:%v, R:%v", failed, err)
+	}
+	if len(h.before) != 1 || len(h.after) != 1 || h.ctxValue != "PING" {
+		t.Errorf("AddHook did not work properly. B:%v, A:%v", h.before, h.after)
+	}
+	p := client.Pipeline()
+	p.Queue("PING")
+	if _, err := p.Exec(); err != failed || len(h.pipelines) != 1 {
+		t.Errorf("AddHook did not work properly. E:%v, R:%v, Pipelines:%v", failed, err, h.pipelines)
+	}
+}
+
+func TestAddHookConcurrent(t *testing.T) {
+	srv, _ := newScratchClient(t)
+	client, pool := redis.NewPoolClient(srv.URL(), redis.PoolOptions{})
+	defer pool.Close()
+	client.AddHook(redis.BaseHook{})
+	// The copies share the hooks added to client afterwards.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(cli redis.Client) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				cli.Send("PING")
+			}
+		}(client)
+	}
+	for i := 0; i < 10; i++ {
+		client.AddHook(redis.BaseHook{})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,334 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Info is the reply of INFO. Sections holds every field of every section, e.g. Sections["memory"]["used_memory"],
+// the fields of the main sections are also parsed into typed fields.
+type Info struct {
+	Sections    map[string]map[string]string
+	Server      ServerInfo
+	Clients     ClientsInfo
+	Memory      MemoryInfo
+	Persistence PersistenceInfo
+	Replication ReplicationInfo
+	// Keyspace is the keyspace of each database which has keys, by database index.
+	Keyspace map[int]KeyspaceInfo
+}
+
+// ServerInfo is the server section of INFO.
+type ServerInfo struct {
+	RedisVersion    string `redis:"redis_version"`
+	RedisMode       string `redis:"redis_mode"`
+	OS              string `redis:"os"`
+	ProcessID       int64  `redis:"process_id"`
+	TCPPort         int    `redis:"tcp_port"`
+	UptimeInSeconds int64  `redis:"uptime_in_seconds"`
+}
+
+// ClientsInfo is the clients section of INFO.
+type ClientsInfo struct {
+	ConnectedClients int64 `redis:"connected_clients"`
+	BlockedClients   int64 `redis:"blocked_clients"`
+	MaxClients       int64 `redis:"maxclients"`
+}
+
+// MemoryInfo is the memory section of INFO, the sizes are in bytes.
+type MemoryInfo struct {
+	UsedMemory            int64   `redis:"used_memory"`
+	UsedMemoryRSS         int64   `redis:"used_memory_rss"`
+	UsedMemoryPeak        int64   `redis:"used_memory_peak"`
+	MaxMemory             int64   `redis:"maxmemory"`
+	MaxMemoryPolicy       string  `redis:"maxmemory_policy"`
+	MemFragmentationRatio float64 `redis:"mem_fragmentation_ratio"`
+}
+
+// PersistenceInfo is the persistence section of INFO.
+type PersistenceInfo struct {
+	Loading                 bool      `redis:"loading"`
+	RDBChangesSinceLastSave int64     `redis:"rdb_changes_since_last_save"`
+	RDBBgsaveInProgress     bool      `redis:"rdb_bgsave_in_progress"`
+	RDBLastSaveTime         time.Time `redis:"rdb_last_save_time"`
+	RDBLastBgsaveStatus     string    `redis:"rdb_last_bgsave_status"`
+	AOFEnabled              bool      `redis:"aof_enabled"`
+	AOFRewriteInProgress    bool      `redis:"aof_rewrite_in_progress"`
+}
+
+// ReplicationInfo is the replication section of INFO, the master fields are set on the replicas.
+type ReplicationInfo struct {
+	Role             string `redis:"role"`
+	ConnectedSlaves  int    `redis:"connected_slaves"`
+	MasterHost       string `redis:"master_host"`
+	MasterPort       int    `redis:"master_port"`
+	MasterLinkStatus string `redis:"master_link_status"`
+	MasterReplOffset int64  `redis:"master_repl_offset"`
+}
+
+// KeyspaceInfo is the keyspace of a database, e.g. db0:keys=1,expires=0,avg_ttl=0.
+type KeyspaceInfo struct {
+	Keys    int64 `redis:"keys"`
+	Expires int64 `redis:"expires"`
+	AvgTTL  int64 `redis:"avg_ttl"`
+}
+
+var errInfoFormat = errors.New("redis: the INFO reply is malformed.")
+
+// ParseInfo parses the reply of INFO. The typed fields which cannot be parsed, e.g. of another version
+// of Redis, are left zero, they are still in Sections.
+func ParseInfo(text string) (*Info, error) {
+	info := &Info{Sections: make(map[string]map[string]string), Keyspace: make(map[int]KeyspaceInfo)}
+	var section map[string]string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "# "); ok {
+			section = make(map[string]string)
+			info.Sections[strings.ToLower(name)] = section
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok || section == nil {
+			return nil, errInfoFormat
+		}
+		section[k] = v
+	}
+
+	scanFields(&info.Server, info.Sections["server"])
+	scanFields(&info.Clients, info.Sections["clients"])
+	scanFields(&info.Memory, info.Sections["memory"])
+	scanFields(&info.Persistence, info.Sections["persistence"])
+	scanFields(&info.Replication, info.Sections["replication"])
+	for k, v := range info.Sections["keyspace"] {
+		db, err := strconv.Atoi(strings.TrimPrefix(k, "db"))
+		if err != nil {
+			continue
+		}
+		var ks KeyspaceInfo
+		scanFields(&ks, parseInfoValue(v))
+		info.Keyspace[db] = ks
+	}
+	return info, nil
+}
+
+// parseInfoValue parses an INFO value made of fields, e.g. the keyspace of a database keys=1,expires=0,avg_ttl=0
+// or a replica ip=127.0.0.1,port=6380,state=online,offset=1,lag=0.
+func parseInfoValue(v string) map[string]string {
+	fields := make(map[string]string)
+	for _, f := range strings.Split(v, ",") {
+		if name, value, ok := strings.Cut(f, "="); ok {
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+// scanFields scans the fields of a section into the struct pointed by dest as ScanStruct, the fields
+// which cannot be parsed are skipped.
+func scanFields(dest interface{}, fields map[string]string) {
+	d := reflect.ValueOf(dest).Elem()
+	for _, fs := range structSpecOf(d.Type()).fields {
+		if v, ok := fields[fs.name]; ok {
+			scanValue(d.FieldByIndex(fs.index), []byte(v))
+		}
+	}
+}
+
+// RoleInfo is the reply of ROLE.
+type RoleInfo struct {
+	// Role is master, slave or sentinel.
+	Role string
+	// ReplicationOffset is the replication offset of a master or a replica.
+	ReplicationOffset int64
+	// Replicas are the replicas of a master.
+	Replicas []RoleReplica
+	// MasterHost, MasterPort and State, e.g. connected, are the master of a replica and its link state.
+	MasterHost string
+	MasterPort int
+	State      string
+	// MasterNames are the masters monitored by a sentinel.
+	MasterNames []string
+}
+
+// RoleReplica is a replica of a master in the reply of ROLE.
+type RoleReplica struct {
+	Host   string
+	Port   int
+	Offset int64
+}
+
+var errRoleFormat = errors.New("redis: the ROLE reply is malformed.")
+
+func parseRole(reply interface{}) (*RoleInfo, error) {
+	a, err := Values(reply)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) == 0 {
+		return nil, errRoleFormat
+	}
+	r := &RoleInfo{}
+	if r.Role, err = String(a[0]); err != nil {
+		return nil, err
+	}
+	switch {
+	case r.Role == "master" && len(a) == 3:
+		if r.ReplicationOffset, err = Int64(a[1]); err != nil {
+			return nil, err
+		}
+		replicas, err := Values(a[2])
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range replicas {
+			s, err := StringSlice(v)
+			if err != nil || len(s) != 3 {
+				return nil, errRoleFormat
+			}
+			port, _ := strconv.Atoi(s[1])
+			offset, _ := strconv.ParseInt(s[2], 10, 64)
+			r.Replicas = append(r.Replicas, RoleReplica{Host: s[0], Port: port, Offset: offset})
+		}
+	case r.Role == "slave" && len(a) == 5:
+		r.MasterHost, _ = String(a[1])
+		port, _ := Int64(a[2])
+		r.MasterPort = int(port)
+		r.State, _ = String(a[3])
+		if r.ReplicationOffset, err = Int64(a[4]); err != nil {
+			return nil, err
+		}
+	case r.Role == "sentinel" && len(a) == 2:
+		if r.MasterNames, err = StringSlice(a[1]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errRoleFormat
+	}
+	return r, nil
+}
+
+// ObjectInfo is the reply of DEBUG OBJECT, e.g.
+// Value at:0x7f7e3c0 refcount:1 encoding:embstr serializedlength:6 lru:1164 lru_seconds_idle:3.
+type ObjectInfo struct {
+	RefCount         int64  `redis:"refcount"`
+	Encoding         string `redis:"encoding"`
+	SerializedLength int64  `redis:"serializedlength"`
+	LRU              int64  `redis:"lru"`
+	LRUSecondsIdle   int64  `redis:"lru_seconds_idle"`
+}
+
+func parseObjectInfo(text string) *ObjectInfo {
+	fields := make(map[string]string)
+	for _, f := range strings.Fields(text) {
+		if k, v, ok := strings.Cut(f, ":"); ok {
+			fields[k] = v
+		}
+	}
+	o := &ObjectInfo{}
+	scanFields(o, fields)
+	return o
+}
+
+// ClientInfo is a client in the reply of CLIENT LIST or CLIENT INFO. Fields holds every field of the client,
+// e.g. Fields["qbuf"], the main fields are also parsed into typed fields.
+type ClientInfo struct {
+	ID     int64             `redis:"id"`
+	Addr   string            `redis:"addr"`
+	LAddr  string            `redis:"laddr"`
+	FD     int64             `redis:"fd"`
+	Name   string            `redis:"name"`
+	Age    time.Duration     `redis:"age"`
+	Idle   time.Duration     `redis:"idle"`
+	Flags  string            `redis:"flags"`
+	DB     int               `redis:"db"`
+	Sub    int               `redis:"sub"`
+	PSub   int               `redis:"psub"`
+	Multi  int               `redis:"multi"`
+	OMem   int64             `redis:"omem"`
+	TotMem int64             `redis:"tot-mem"`
+	Events string            `redis:"events"`
+	Cmd    string            `redis:"cmd"`
+	User   string            `redis:"user"`
+	Fields map[string]string `redis:"-"`
+}
+
+// ParseClientList parses the reply of CLIENT LIST, a client by line.
+func ParseClientList(text string) []ClientInfo {
+	var r []ClientInfo
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			r = append(r, parseClientInfo(line))
+		}
+	}
+	return r
+}
+
+func parseClientInfo(line string) ClientInfo {
+	c := ClientInfo{Fields: make(map[string]string)}
+	for _, f := range strings.Fields(line) {
+		if k, v, ok := strings.Cut(f, "="); ok {
+			c.Fields[k] = v
+		}
+	}
+	scanFields(&c, c.Fields)
+	// age and idle are in seconds.
+	c.Age *= time.Second
+	c.Idle *= time.Second
+	return c
+}
+
+// SlowLogEntry is an entry of the reply of SLOWLOG GET.
+type SlowLogEntry struct {
+	ID       int64
+	Time     time.Time
+	Duration time.Duration
+	Args     []string
+	// ClientAddr and ClientName are the client which sent the command, since Redis 4.0.
+	ClientAddr string
+	ClientName string
+}
+
+var errSlowLogFormat = errors.New("redis: the SLOWLOG GET reply is malformed.")
+
+func parseSlowLog(reply interface{}) ([]SlowLogEntry, error) {
+	return Slice(reply, func(p interface{}) (SlowLogEntry, error) {
+		var e SlowLogEntry
+		a, err := Values(p)
+		if err != nil {
+			return e, err
+		}
+		if len(a) < 4 {
+			return e, errSlowLogFormat
+		}
+		if e.ID, err = Int64(a[0]); err != nil {
+			return e, err
+		}
+		ts, err := Int64(a[1])
+		if err != nil {
+			return e, err
+		}
+		us, err := Int64(a[2])
+		if err != nil {
+			return e, err
+		}
+		if e.Args, err = StringSlice(a[3]); err != nil {
+			return e, err
+		}
+		e.Time, e.Duration = time.Unix(ts, 0), time.Duration(us)*time.Microsecond
+		if len(a) >= 6 {
+			e.ClientAddr, _ = String(a[4])
+			e.ClientName, _ = String(a[5])
+		}
+		return e, nil
+	})
+}
@@ -0,0 +1,64 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"github.com/qqbuby/goredis/redis"
+	"testing"
+	"time"
+)
+
+const infoText = "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\ntcp_port:6379\r\nuptime_in_seconds:42\r\n\r\n" +
+	"# Memory\r\nused_memory:1048576\r\nmaxmemory_policy:allkeys-lru\r\nmem_fragmentation_ratio:1.25\r\n\r\n" +
+	"# Persistence\r\nloading:0\r\nrdb_last_save_time:1500000000\r\naof_enabled:1\r\n\r\n" +
+	"# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6380\r\nmaster_link_status:up\r\n\r\n" +
+	"# Keyspace\r\ndb0:keys=3,expires=1,avg_ttl=500\r\ndb5:keys=1,expires=0,avg_ttl=0\r\n"
+
+func TestParseInfo(t *testing.T) {
+	info, err := redis.ParseInfo(infoText)
+	if err != nil {
+		t.Fatalf("ParseInfo: %s", err.Error())
+	}
+	if info.Server.RedisVersion != "7.2.4" || info.Server.TCPPort != 6379 || info.Server.UptimeInSeconds != 42 {
+		t.Errorf("ParseInfo did not work properly. R:%+v", info.Server)
+	}
+	if info.Memory.UsedMemory != 1048576 || info.Memory.MaxMemoryPolicy != "allkeys-lru" || info.Memory.MemFragmentationRatio != 1.25 {
+		t.Errorf("ParseInfo did not work properly. R:%+v", info.Memory)
+	}
+	if info.Persistence.Loading || !info.Persistence.AOFEnabled || !info.Persistence.RDBLastSaveTime.Equal(time.Unix(1500000000, 0)) {
+		t.Errorf("ParseInfo did not work properly. R:%+v", info.Persistence)
+	}
+	if info.Replication.Role != "slave" || info.Replication.MasterHost != "10.0.0.1" || info.Replication.MasterPort != 6380 {
+		t.Errorf("ParseInfo did not work properly. R:%+v", info.Replication)
+	}
+	if len(info.Keyspace) != 2 || info.Keyspace[0] != (redis.KeyspaceInfo{Keys: 3, Expires: 1, AvgTTL: 500}) || info.Keyspace[5].Keys != 1 {
+		t.Errorf("ParseInfo did not work properly. R:%+v", info.Keyspace)
+	}
+	if info.Sections["memory"]["used_memory"] != "1048576" || len(info.Sections) != 5 {
+		t.Errorf("ParseInfo did not work properly. R:%v", info.Sections)
+	}
+
+	if _, err := redis.ParseInfo("no section\r\n"); err == nil {
+		t.Errorf("ParseInfo did not work properly. E:%s, R:%v", "an error", err)
+	}
+}
+
+func TestParseClientList(t *testing.T) {
+	text := "id=3 addr=127.0.0.1:50000 laddr=127.0.0.1:6379 fd=8 name=worker age=120 idle=5 flags=N db=2 sub=0 psub=0 multi=-1 " +
+		"qbuf=26 qbuf-free=20448 argv-mem=10 obl=0 oll=0 omem=0 tot-mem=22298 events=r cmd=client|list user=default\n" +
+		"id=4 addr=127.0.0.1:50001 laddr=127.0.0.1:6379 fd=9 name= age=1 idle=1 flags=P db=0 sub=1 psub=0 multi=-1 cmd=subscribe user=default\n"
+	clients := redis.ParseClientList(text)
+	if len(clients) != 2 {
+		t.Fatalf("ParseClientList did not work properly. E:%d, R:%d", 2, len(clients))
+	}
+	c := clients[0]
+	if c.ID != 3 || c.Addr != "127.0.0.1:50000" || c.Name != "worker" || c.Age != time.Minute*2 || c.Idle != time.Second*5 ||
+		c.DB != 2 || c.Multi != -1 || c.TotMem != 22298 || c.Cmd != "client|list" || c.Fields["qbuf"] != "26" {
+		t.Errorf("ParseClientList did not work properly. R:%+v", c)
+	}
+	if c := clients[1]; c.Name != "" || c.Sub != 1 || c.Flags != "P" {
+		t.Errorf("ParseClientList did not work properly. R:%+v", c)
+	}
+}
@@ -4,7 +4,6 @@
 
 package redis_test
 
-const (
-	url = "tcp://127.0.0.1:6379"
-	// url = "tcp://192.168.128.134:6379"
-)
+// url is the url of the in-memory server started by TestMain,
+// or of the server of the environment variable REDIS_URL, e.g. "tcp://127.0.0.1:6379".
+var url string
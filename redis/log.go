@@ -0,0 +1,110 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LogOptions configures the logging of a client.
+type LogOptions struct {
+	// Logger receives the log records, nil disables the logging.
+	Logger *slog.Logger
+	// SlowThreshold logs the commands slower than SlowThreshold at the warning level. Zero disables it.
+	SlowThreshold time.Duration
+	// ShowArgs logs the argument values of the commands, only their number is logged by default.
+	ShowArgs bool
+}
+
+// loggerSetter is implemented by the connections which log their lifecycle events.
+type loggerSetter interface {
+	setLogger(l *slog.Logger)
+}
+
+// logAttr logs a record when the logger is not nil.
+func logAttr(l *slog.Logger, level slog.Level, msg string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.Log(context.Background(), level, msg, args...)
+}
+
+// SetLogger logs the failed and the slow commands of the client and the lifecycle events of its connections:
+// dials, reconnects, failovers, health changes and protocol errors. The logging never exits the process.
+// A later call replaces the options of the previous one.
+func (cli *Client) SetLogger(opt LogOptions) {
+	if opt.Logger == nil {
+		return
+	}
+	cli.hooks = cli.hooks.set(&logHook{opt: opt}, func(h Hook) bool {
+		_, ok := h.(*logHook)
+		return ok
+	})
+	if d, ok := cli.cn.(dialNotifier); ok {
+		d.notifyDial(cli.hooks.onDial)
+	}
+	if s, ok := cli.cn.(loggerSetter); ok {
+		s.setLogger(opt.Logger)
+	}
+}
+
+// logHook logs the network errors at the error level, the slow commands at the warning level,
+// the error replies and the dials at the debug level.
+type logHook struct {
+	BaseHook
+	opt LogOptions
+}
+
+func (h *logHook) args(cmd *CmdInfo) slog.Attr {
+	if h.opt.ShowArgs {
+		return slog.Any("args", cmd.Args)
+	}
+	return slog.Int("nargs", len(cmd.Args))
+}
+
+func (h *logHook) AfterProcess(ctx context.Context, cmd *CmdInfo) error {
+	l := h.opt.Logger
+	if cmd.Err != nil {
+		if _, ok := cmd.Reply.(error); ok {
+			l.DebugContext(ctx, "redis: error reply", "cmd", cmd.Name, h.args(cmd), "error", cmd.Err)
+		} else {
+			l.ErrorContext(ctx, "redis: command failed", "cmd", cmd.Name, h.args(cmd), "error", cmd.Err)
+		}
+	}
+	if h.opt.SlowThreshold > 0 && cmd.Duration > h.opt.SlowThreshold {
+		l.WarnContext(ctx, "redis: slow command", "cmd", cmd.Name, h.args(cmd), "duration", cmd.Duration)
+	}
+	return nil
+}
+
+func (h *logHook) AfterPipeline(ctx context.Context, cmds []*CmdInfo) error {
+	l := h.opt.Logger
+	var d time.Duration
+	for _, c := range cmds {
+		if c.Duration > d {
+			d = c.Duration
+		}
+		if c.Err != nil {
+			if _, ok := c.Reply.(error); !ok {
+				l.ErrorContext(ctx, "redis: pipeline failed", "size", len(cmds), "error", c.Err)
+				break
+			}
+		}
+	}
+	if h.opt.SlowThreshold > 0 && d > h.opt.SlowThreshold {
+		l.WarnContext(ctx, "redis: slow pipeline", "size", len(cmds), "duration", d)
+	}
+	return nil
+}
+
+func (h *logHook) OnDial(ctx context.Context, network, addr string, d time.Duration, err error) {
+	if err != nil {
+		h.opt.Logger.WarnContext(ctx, "redis: dial failed", "network", network, "addr", addr, "error", err)
+		return
+	}
+	h.opt.Logger.DebugContext(ctx, "redis: dialed", "network", network, "addr", addr, "duration", d)
+}
@@ -0,0 +1,72 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"bytes"
+	"github.com/qqbuby/goredis/redis"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetLogger(t *testing.T) {
+	const (
+		key    = "TEST:LOGGER"
+		secret = "TEST:LOGGER:SECRET"
+	)
+	client, e := redis.NewClient(url)
+	if e != nil {
+		t.Fatalf("SetLogger: %s", e.Error())
+	}
+	defer client.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client.SetLogger(redis.LogOptions{Logger: logger, SlowThreshold: time.Nanosecond})
+	client.Set(key, secret)
+	client.Incr(key)
+
+	s := buf.String()
+	if !strings.Contains(s, `msg="redis: slow command" cmd=SET nargs=2`) {
+		t.Errorf("SetLogger did not work properly: no slow command. %s", s)
+	}
+	if !strings.Contains(s, `msg="redis: error reply" cmd=INCRBY`) {
+		t.Errorf("SetLogger did not work properly: no error reply. %s", s)
+	}
+	if strings.Contains(s, secret) {
+		t.Errorf("SetLogger did not work properly: the arguments are not redacted. %s", s)
+	}
+}
+
+func TestSetLoggerTwice(t *testing.T) {
+	const (
+		key = "TEST:LOGGER:TWICE"
+	)
+	client, e := redis.NewClient(url)
+	if e != nil {
+		t.Fatalf("SetLogger: %s", e.Error())
+	}
+	defer client.Close()
+
+	var buf1, buf2 bytes.Buffer
+	client.SetLogger(redis.LogOptions{Logger: slog.New(slog.NewTextHandler(&buf1, nil)), SlowThreshold: time.Nanosecond})
+	client.SetLogger(redis.LogOptions{Logger: slog.New(slog.NewTextHandler(&buf2, nil)), SlowThreshold: time.Nanosecond})
+	client.Set(key, key)
+
+	if buf1.Len() != 0 {
+		t.Errorf("SetLogger did not work properly: the first logger is used. %s", buf1.String())
+	}
+	if n := strings.Count(buf2.String(), "redis: slow command"); n != 1 {
+		t.Errorf("SetLogger did not work properly. E:%d, R:%d %s", 1, n, buf2.String())
+	}
+}
+
+func TestDialError(t *testing.T) {
+	if _, err := redis.Dial("tcp://127.0.0.1:6379/%zz"); err == nil {
+		t.Error("Dial did not work properly: invalid url.")
+	}
+}
@@ -0,0 +1,141 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds in seconds of the command latency histograms.
+var LatencyBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+
+// PipelineBuckets are the upper bounds of the pipeline size histogram.
+var PipelineBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+// Histogram is a snapshot of a histogram, Counts[i] is the cumulative count of the observations <= Buckets[i].
+type Histogram struct {
+	Count   uint64
+	Sum     float64
+	Buckets []float64
+	Counts  []uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{Buckets: buckets, Counts: make([]uint64, len(buckets))}
+}
+
+func (h *Histogram) observe(v float64) {
+	h.Count++
+	h.Sum += v
+	for i := sort.SearchFloat64s(h.Buckets, v); i < len(h.Buckets); i++ {
+		h.Counts[i]++
+	}
+}
+
+func (h *Histogram) snapshot() Histogram {
+	s := *h
+	s.Counts = append([]uint64{}, h.Counts...)
+	return s
+}
+
+// MetricsStats is a snapshot of the metrics of the commands.
+type MetricsStats struct {
+	// Commands are the latency histograms in seconds by command name, the pipelined commands excluded.
+	Commands map[string]Histogram
+	// Errors are the error counts by error prefix (ERR, WRONGTYPE, ...), NETWORK for the network errors.
+	Errors map[string]uint64
+	// Pipelines is the histogram of the pipeline sizes.
+	Pipelines Histogram
+	// PipelineLatency is the latency histogram of the pipelines in seconds.
+	PipelineLatency Histogram
+}
+
+// Metrics is a Hook which measures the commands of the clients it is added to.
+type Metrics struct {
+	BaseHook
+	mu        sync.Mutex
+	commands  map[string]*Histogram
+	errors    map[string]uint64
+	pipelines *Histogram
+	latency   *Histogram
+}
+
+// NewMetrics returns a metrics hook to add to the clients by AddHook.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		commands:  make(map[string]*Histogram),
+		errors:    make(map[string]uint64),
+		pipelines: newHistogram(PipelineBuckets),
+		latency:   newHistogram(LatencyBuckets),
+	}
+}
+
+// errorPrefix returns the first word of an error reply or NETWORK.
+func errorPrefix(cmd *CmdInfo) string {
+	if _, ok := cmd.Reply.(error); !ok {
+		return "NETWORK"
+	}
+	s := cmd.Err.Error()
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func (m *Metrics) AfterProcess(ctx context.Context, cmd *CmdInfo) error {
+	name := strings.ToUpper(cmd.Name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.commands[name]
+	if h == nil {
+		h = newHistogram(LatencyBuckets)
+		m.commands[name] = h
+	}
+	h.observe(cmd.Duration.Seconds())
+	if cmd.Err != nil {
+		m.errors[errorPrefix(cmd)]++
+	}
+	return nil
+}
+
+func (m *Metrics) AfterPipeline(ctx context.Context, cmds []*CmdInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pipelines.observe(float64(len(cmds)))
+	var d time.Duration
+	for _, c := range cmds {
+		if c.Duration > d {
+			d = c.Duration
+		}
+		if c.Err != nil {
+			m.errors[errorPrefix(c)]++
+		}
+	}
+	m.latency.observe(d.Seconds())
+	return nil
+}
+
+// Stats returns a snapshot of the metrics.
+func (m *Metrics) Stats() MetricsStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := MetricsStats{
+		Commands:        make(map[string]Histogram, len(m.commands)),
+		Errors:          make(map[string]uint64, len(m.errors)),
+		Pipelines:       m.pipelines.snapshot(),
+		PipelineLatency: m.latency.snapshot(),
+	}
+	for k, h := range m.commands {
+		s.Commands[k] = h.snapshot()
+	}
+	for k, v := range m.errors {
+		s.Errors[k] = v
+	}
+	return s
+}
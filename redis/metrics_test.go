@@ -0,0 +1,42 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"github.com/qqbuby/goredis/redis"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	const (
+		key = "TEST:METRICS"
+	)
+	client, e := redis.NewClient(url)
+	if e != nil {
+		t.Fatalf("Metrics: %s", e.Error())
+	}
+	defer client.Close()
+
+	m := redis.NewMetrics()
+	client.AddHook(m)
+	client.Set(key, "value")
+	client.Get(key)
+	client.Incr(key)
+	p := client.Pipeline()
+	p.Queue("GET", key)
+	p.Queue("GET", key)
+	p.Exec()
+
+	s := m.Stats()
+	if h := s.Commands["GET"]; h.Count != 1 || h.Counts[len(h.Counts)-1] != 1 {
+		t.Errorf("Metrics did not work properly. GET:%+v", h)
+	}
+	if n := s.Errors["ERR"]; n != 1 {
+		t.Errorf("Metrics did not work properly. E:%d, R:%d", 1, n)
+	}
+	if s.Pipelines.Count != 1 || s.Pipelines.Sum != 2 {
+		t.Errorf("Metrics did not work properly. Pipelines:%+v", s.Pipelines)
+	}
+}
@@ -0,0 +1,148 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dedicatedDialer is implemented by the connections which can dial a new connection to their server,
+// e.g. for MONITOR, which takes over the connection it is issued on.
+type dedicatedDialer interface {
+	dialDedicated() (Conn, error)
+}
+
+var errDedicated = errors.New("redis: the connection cannot dial a dedicated connection.")
+
+// MonitorEvent is a command executed by the server, reported by MONITOR:
+//
+//	1339518083.107412 [0 127.0.0.1:60866] "keys" "*"
+type MonitorEvent struct {
+	Time time.Time
+	DB   int
+	// ClientAddr is the address of the client, "lua" for a script or "unix:/path" for a unix socket.
+	ClientAddr string
+	Command    string
+	Args       []string
+}
+
+// Monitor opens a dedicated connection, issues MONITOR on it and sends the commands executed by the server
+// on the returned channel. The connection and the channel are closed when ctx is cancelled
+// or when the connection is lost. The lines which cannot be parsed are skipped.
+func (cli *Client) Monitor(ctx context.Context) (<-chan MonitorEvent, error) {
+	d, ok := cli.cn.(dedicatedDialer)
+	if !ok {
+		return nil, errDedicated
+	}
+	cn, err := d.dialDedicated()
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := cn.Send("MONITOR")
+	if err == nil {
+		err, _ = rsp.(error)
+	}
+	if err != nil {
+		cn.Close()
+		return nil, err
+	}
+	// The events may be minutes apart, the connection waits for them until ctx is cancelled.
+	if s, ok := cn.(readTimeoutSetter); ok {
+		s.setReadTimeout(0)
+	}
+	events := make(chan MonitorEvent)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		cn.Close()
+	}()
+	go func() {
+		defer close(events)
+		defer close(done)
+		for {
+			rsp, err := cn.Receive()
+			if err != nil {
+				return
+			}
+			line, err := String(rsp)
+			if err != nil {
+				continue
+			}
+			ev, err := ParseMonitorEvent(line)
+			if err != nil {
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// ParseMonitorEvent parses a line of MONITOR, the arguments are unquoted.
+func ParseMonitorEvent(line string) (MonitorEvent, error) {
+	ev := MonitorEvent{}
+	i := strings.Index(line, " [")
+	j := strings.Index(line, "] ")
+	if i < 0 || j < i {
+		return ev, fmt.Errorf("redis: malformed MONITOR line %q.", line)
+	}
+	sec, frac, _ := strings.Cut(line[:i], ".")
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return ev, fmt.Errorf("redis: malformed MONITOR line %q.", line)
+	}
+	us, _ := strconv.ParseInt(frac, 10, 64)
+	ev.Time = time.Unix(s, us*int64(time.Microsecond))
+	db, addr, _ := strings.Cut(line[i+2:j], " ")
+	if ev.DB, err = strconv.Atoi(db); err != nil {
+		return ev, fmt.Errorf("redis: malformed MONITOR line %q.", line)
+	}
+	ev.ClientAddr = addr
+	args, err := unquoteArgs(line[j+2:])
+	if err != nil || len(args) == 0 {
+		return ev, fmt.Errorf("redis: malformed MONITOR line %q.", line)
+	}
+	ev.Command, ev.Args = args[0], args[1:]
+	return ev, nil
+}
+
+// unquoteArgs splits the quoted arguments of a MONITOR line, quoted as by sdscatrepr:
+// \\, \", \n, \r, \t, \a, \b and \xHH are escaped.
+func unquoteArgs(s string) ([]string, error) {
+	args := []string{}
+	for s = strings.TrimLeft(s, " "); s != ""; s = strings.TrimLeft(s, " ") {
+		if s[0] != '"' {
+			return nil, errors.New("redis: missing quote.")
+		}
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return nil, errors.New("redis: missing quote.")
+		}
+		a, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+		s = s[end+1:]
+	}
+	return args, nil
+}
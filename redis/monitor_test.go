@@ -0,0 +1,108 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"context"
+	"github.com/qqbuby/goredis/redis"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMonitorEvent(t *testing.T) {
+	ev, err := redis.ParseMonitorEvent(`1339518083.107412 [3 127.0.0.1:60866] "set" "k" "a \"b\"\n\x00"`)
+	if err != nil {
+		t.Fatalf("ParseMonitorEvent: %s", err.Error())
+	}
+	if !ev.Time.Equal(time.Unix(1339518083, 107412000)) || ev.DB != 3 || ev.ClientAddr != "127.0.0.1:60866" ||
+		ev.Command != "set" || !reflect.DeepEqual(ev.Args, []string{"k", "a \"b\"\n\x00"}) {
+		t.Errorf("ParseMonitorEvent did not work properly. R:%+v", ev)
+	}
+	ev, err = redis.ParseMonitorEvent(`1339518083.000001 [0 lua] "get" "k"`)
+	if err != nil || ev.ClientAddr != "lua" || ev.Command != "get" {
+		t.Errorf("ParseMonitorEvent did not work properly. R:%+v %v", ev, err)
+	}
+	for _, line := range []string{"OK", `1339518083.1 [x 127.0.0.1:1] "get"`, `1339518083.1 [0 127.0.0.1:1] "get`, `1339518083.1 [0 127.0.0.1:1] `} {
+		if _, err := redis.ParseMonitorEvent(line); err == nil {
+			t.Errorf("ParseMonitorEvent did not work properly. E:error, R:nil for %q", line)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	_, cli := newScratchClient(t)
+	cli.Select(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := cli.Monitor(ctx)
+	if err != nil {
+		t.Fatalf("Monitor: %s", err.Error())
+	}
+	cli.Set("TEST:MONITOR", "a\tb")
+	select {
+	case ev := <-events:
+		if ev.DB != 2 || ev.Command != "SET" || !reflect.DeepEqual(ev.Args, []string{"TEST:MONITOR", "a\tb"}) ||
+			ev.ClientAddr == "" || time.Since(ev.Time).Abs() > time.Minute {
+			t.Errorf("Monitor did not work properly. R:%+v", ev)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Monitor did not work properly. E:an event, R:timeout")
+	}
+	list, err := cli.ClientList()
+	monitors := 0
+	for _, c := range list {
+		if strings.Contains(c.Flags, "O") {
+			monitors++
+		}
+	}
+	if err != nil || monitors != 1 {
+		t.Errorf("Monitor did not work properly. E:%d monitor, R:%d %v", 1, monitors, err)
+	}
+	cancel()
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Monitor did not work properly. E:closed, R:timeout")
+		}
+	}
+}
+
+func TestMonitorPoolClient(t *testing.T) {
+	const password = "secret"
+	srv, _ := newScratchClient(t)
+	srv.SetPassword(password)
+	client, _ := redis.NewPoolClient(srv.URL(), redis.PoolOptions{Password: password, DB: 2})
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Monitor(ctx)
+	if err != nil {
+		t.Fatalf("Monitor: %s", err.Error())
+	}
+	client.Set("TEST:MONITOR:POOL", "v")
+	// The connection of the pool dialed for SET selects the database first.
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Command != "SET" {
+				continue
+			}
+			if ev.DB != 2 {
+				t.Errorf("Monitor did not work properly. R:%+v", ev)
+			}
+			return
+		case <-timeout:
+			t.Fatalf("Monitor did not work properly. E:an event, R:timeout")
+		}
+	}
+}
@@ -10,31 +10,81 @@ import (
 	"strconv"
 )
 
+// Nil is returned by the converters for a null reply, e.g. the reply of GET for a key which does not exist.
+var Nil = errors.New("redis: nil reply.")
+
 // Int parses a RESP Integer to int.
 func Int(p interface{}) (int, error) {
-	b, e := p.([]byte)
-	if e {
-		return strconv.Atoi(string(b))
+	switch v := p.(type) {
+	case []byte:
+		return strconv.Atoi(string(v))
+	default:
+		return 0, convertError("Int", p)
+	}
+}
+
+// Int64 parses a RESP Integer or Bulk String to int64.
+func Int64(p interface{}) (int64, error) {
+	switch v := p.(type) {
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	default:
+		return 0, convertError("Int64", p)
+	}
+}
+
+// Uint64 parses a RESP Integer or Bulk String to uint64.
+func Uint64(p interface{}) (uint64, error) {
+	switch v := p.(type) {
+	case []byte:
+		return strconv.ParseUint(string(v), 10, 64)
+	default:
+		return 0, convertError("Uint64", p)
 	}
-	return 0, strconv.ErrRange
 }
 
 // Float64 parses a RESP Bulk String to a float64 number.
 func Float64(p interface{}) (float64, error) {
-	b, e := p.([]byte)
-	if e {
-		return strconv.ParseFloat(string(b), 64)
+	switch v := p.(type) {
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	default:
+		return 0, convertError("Float64", p)
+	}
+}
+
+// Bool parses a RESP Integer, e.g. 1 or 0, or a Bulk String accepted by strconv.ParseBool to a bool.
+func Bool(p interface{}) (bool, error) {
+	switch v := p.(type) {
+	case []byte:
+		return strconv.ParseBool(string(v))
+	default:
+		return false, convertError("Bool", p)
+	}
+}
+
+// Bytes returns a RESP Bulk String or Simple String as a []byte.
+func Bytes(p interface{}) ([]byte, error) {
+	switch v := p.(type) {
+	case []byte:
+		return v, nil
+	default:
+		return nil, convertError("Bytes", p)
 	}
-	return 0, strconv.ErrRange
 }
 
 // Stringx parses a RESP Bulk String or a Simple String to a string or a nil, otherwise a nil when a error occured.
+// The error of an error reply is returned as it is.
+// Unlike the other converters, a null reply is a nil without error and not Nil: the nullable replies of the
+// Client, e.g. of Get, are the values of Stringx.
 func Stringx(p interface{}) (interface{}, error) {
 	switch v := p.(type) {
 	case []byte:
 		return string(v), nil
 	case nil:
 		return nil, nil
+	case error:
+		return nil, v
 	default:
 		return nil, errors.New("redis.Stringx(interface{}): Protocol error.")
 	}
@@ -42,6 +92,8 @@ func Stringx(p interface{}) (interface{}, error) {
 
 // String parses RESP Bulk String or Simple String to a string, otherwise a empty string.
 // usually, the p is a string or a nil (i.e. a zero value).
+// Unlike the other converters, a null reply is an empty string without error and not Nil,
+// e.g. the reply of CLIENT GETNAME for a connection without name.
 func String(p interface{}) (string, error) {
 	v, e := Stringx(p)
 	s, _ := v.(string)
@@ -55,8 +107,137 @@ func Strings(p interface{}) ([]interface{}, error) {
 		return nil, errors.New(fmt.Sprintf("redis.Strings(interface{}): interface conversion, interface is %T, not []interface{}.", p))
 	}
 	for i, v := range rsp {
-		p, _ := Stringx(v) // Error check should be not required for performance.
-		rsp[i] = p
+		s, err := Stringx(v)
+		if err != nil {
+			return nil, elementError("Strings", i, convertError("String", v))
+		}
+		rsp[i] = s
 	}
 	return rsp, nil
 }
+
+// Values returns a RESP Array as a []interface{}.
+func Values(p interface{}) ([]interface{}, error) {
+	switch v := p.(type) {
+	case []interface{}:
+		return v, nil
+	default:
+		return nil, convertError("Values", p)
+	}
+}
+
+// Slice converts the elements of a RESP Array by conv, e.g. Slice(reply, redis.Int64).
+func Slice[T any](p interface{}, conv func(interface{}) (T, error)) ([]T, error) {
+	a, err := Values(p)
+	if err != nil {
+		return nil, err
+	}
+	s := make([]T, len(a))
+	for i, v := range a {
+		if s[i], err = conv(v); err != nil {
+			return nil, elementError("Slice", i, err)
+		}
+	}
+	return s, nil
+}
+
+// Map converts a RESP Array of keys and values, e.g. the reply of HGETALL, by convk and convv.
+func Map[K comparable, V any](p interface{}, convk func(interface{}) (K, error), convv func(interface{}) (V, error)) (map[K]V, error) {
+	a, err := Values(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(a)%2 != 0 {
+		return nil, fmt.Errorf("redis.Map: the array has an odd number of elements, %d.", len(a))
+	}
+	m := make(map[K]V, len(a)/2)
+	for i := 0; i < len(a); i += 2 {
+		k, err := convk(a[i])
+		if err != nil {
+			return nil, elementError("Map", i, err)
+		}
+		v, err := convv(a[i+1])
+		if err != nil {
+			return nil, elementError("Map", i+1, err)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// StringSlice parses a RESP Array to a []string, a null element is an empty string.
+func StringSlice(p interface{}) ([]string, error) {
+	return Slice(p, String)
+}
+
+// Int64Slice parses a RESP Array to a []int64.
+func Int64Slice(p interface{}) ([]int64, error) {
+	return Slice(p, Int64)
+}
+
+// Float64Slice parses a RESP Array to a []float64.
+func Float64Slice(p interface{}) ([]float64, error) {
+	return Slice(p, Float64)
+}
+
+// StringMap parses a RESP Array of keys and values to a map[string]string, e.g. the reply of HGETALL.
+func StringMap(p interface{}) (map[string]string, error) {
+	return Map(p, String, String)
+}
+
+// IntMap parses a RESP Array of keys and integer values to a map[string]int.
+func IntMap(p interface{}) (map[string]int, error) {
+	return Map(p, String, Int)
+}
+
+// Positions parses the reply of GEOPOS to the longitudes and latitudes of the members,
+// the position of a member which does not exist is nil.
+func Positions(p interface{}) ([]*[2]float64, error) {
+	return Slice(p, func(v interface{}) (*[2]float64, error) {
+		if v == nil {
+			return nil, nil
+		}
+		a, err := Float64Slice(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(a) != 2 {
+			return nil, fmt.Errorf("redis.Positions: a position has %d elements, not 2.", len(a))
+		}
+		return &[2]float64{a[0], a[1]}, nil
+	})
+}
+
+// convertError returns the error of the conversion of a reply by the converter conv:
+// Nil for a null reply, the error of an error reply, otherwise an error naming the type of the reply.
+func convertError(conv string, p interface{}) error {
+	switch v := p.(type) {
+	case nil:
+		return Nil
+	case error:
+		return v
+	default:
+		return fmt.Errorf("redis.%s: cannot convert %s reply.", conv, replyType(p))
+	}
+}
+
+// elementError returns the error of the conversion of the element i of an array.
+func elementError(conv string, i int, err error) error {
+	return fmt.Errorf("redis.%s: element %d: %w", conv, i, err)
+}
+
+// replyType returns the name of the RESP type of a reply.
+func replyType(p interface{}) string {
+	switch p.(type) {
+	case []byte:
+		return "a string"
+	case []interface{}:
+		return "an array"
+	case nil:
+		return "a null"
+	case error:
+		return "an error"
+	default:
+		return fmt.Sprintf("a %T", p)
+	}
+}
@@ -0,0 +1,101 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"errors"
+	"github.com/qqbuby/goredis/redis"
+	"github.com/qqbuby/goredis/redis/resp"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParserScalars(t *testing.T) {
+	if v, err := redis.Int64([]byte("-9223372036854775808")); v != -9223372036854775808 || err != nil {
+		t.Errorf("Int64 did not work properly. E:%d, R:%d %v", int64(-9223372036854775808), v, err)
+	}
+	if v, err := redis.Uint64([]byte("18446744073709551615")); v != 18446744073709551615 || err != nil {
+		t.Errorf("Uint64 did not work properly. E:%d, R:%d %v", uint64(18446744073709551615), v, err)
+	}
+	if v, err := redis.Bool([]byte("1")); !v || err != nil {
+		t.Errorf("Bool did not work properly. E:%v, R:%v %v", true, v, err)
+	}
+	if v, err := redis.Bytes([]byte("v")); string(v) != "v" || err != nil {
+		t.Errorf("Bytes did not work properly. E:%s, R:%s %v", "v", v, err)
+	}
+	if v, err := redis.Float64([]byte("inf")); v < 1e308 || err != nil {
+		t.Errorf("Float64 did not work properly. E:%s, R:%v %v", "+Inf", v, err)
+	}
+
+	if _, err := redis.Int(nil); err != redis.Nil {
+		t.Errorf("Int did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+	if _, err := redis.Int64(nil); err != redis.Nil {
+		t.Errorf("Int64 did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+	if _, err := redis.Bytes(nil); err != redis.Nil {
+		t.Errorf("Bytes did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+	// String and Stringx are the exceptions, their null reply is a zero value.
+	if v, err := redis.String(nil); v != "" || err != nil {
+		t.Errorf("String did not work properly. E:%q, R:%q %v", "", v, err)
+	}
+	if v, err := redis.Stringx(nil); v != nil || err != nil {
+		t.Errorf("Stringx did not work properly. E:%v, R:%v %v", nil, v, err)
+	}
+	if _, err := redis.Float64(resp.Error("ERR value is not a valid float")); err != resp.Error("ERR value is not a valid float") {
+		t.Errorf("Float64 did not work properly. E:%s, R:%v", "ERR value is not a valid float", err)
+	}
+	if _, err := redis.Uint64([]interface{}{}); err == nil || !strings.Contains(err.Error(), "array") {
+		t.Errorf("Uint64 did not work properly. E:%s, R:%v", "an error naming an array", err)
+	}
+}
+
+func TestParserSlices(t *testing.T) {
+	reply := []interface{}{[]byte("a"), nil, []byte("c")}
+	if v, err := redis.StringSlice(reply); !reflect.DeepEqual(v, []string{"a", "", "c"}) || err != nil {
+		t.Errorf("StringSlice did not work properly. E:%q, R:%q %v", []string{"a", "", "c"}, v, err)
+	}
+	if v, err := redis.Int64Slice([]interface{}{[]byte("1"), []byte("-2")}); !reflect.DeepEqual(v, []int64{1, -2}) || err != nil {
+		t.Errorf("Int64Slice did not work properly. E:%v, R:%v %v", []int64{1, -2}, v, err)
+	}
+	if _, err := redis.Int64Slice(reply); err == nil || !strings.Contains(err.Error(), "element 0") {
+		t.Errorf("Int64Slice did not work properly. E:%s, R:%v", "element 0", err)
+	}
+	if _, err := redis.Float64Slice([]interface{}{[]byte("1.5"), nil}); !errors.Is(err, redis.Nil) {
+		t.Errorf("Float64Slice did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+	if _, err := redis.Values(nil); err != redis.Nil {
+		t.Errorf("Values did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+	if v, err := redis.Slice(reply, redis.Stringx); !reflect.DeepEqual(v, []interface{}{"a", nil, "c"}) || err != nil {
+		t.Errorf("Slice did not work properly. E:%v, R:%v %v", []interface{}{"a", nil, "c"}, v, err)
+	}
+	if _, err := redis.Strings([]interface{}{[]interface{}{}}); err == nil {
+		t.Errorf("Strings did not work properly. E:%s, R:%v", "an error", err)
+	}
+
+	pos, err := redis.Positions([]interface{}{[]interface{}{[]byte("13.36"), []byte("38.11")}, nil})
+	if len(pos) != 2 || *pos[0] != [2]float64{13.36, 38.11} || pos[1] != nil || err != nil {
+		t.Errorf("Positions did not work properly. E:%v, R:%v %v", "[[13.36 38.11] nil]", pos, err)
+	}
+}
+
+func TestParserMaps(t *testing.T) {
+	reply := []interface{}{[]byte("a"), []byte("1"), []byte("b"), []byte("2")}
+	if v, err := redis.StringMap(reply); !reflect.DeepEqual(v, map[string]string{"a": "1", "b": "2"}) || err != nil {
+		t.Errorf("StringMap did not work properly. E:%v, R:%v %v", map[string]string{"a": "1", "b": "2"}, v, err)
+	}
+	if v, err := redis.IntMap(reply); !reflect.DeepEqual(v, map[string]int{"a": 1, "b": 2}) || err != nil {
+		t.Errorf("IntMap did not work properly. E:%v, R:%v %v", map[string]int{"a": 1, "b": 2}, v, err)
+	}
+	if v, err := redis.Map(reply, redis.String, redis.Uint64); len(v) != 2 || err != nil {
+		t.Errorf("Map did not work properly. E:%d, R:%v %v", 2, v, err)
+	}
+	if _, err := redis.StringMap(reply[:3]); err == nil {
+		t.Errorf("StringMap did not work properly. E:%s, R:%v", "an error", err)
+	}
+}
@@ -0,0 +1,133 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"time"
+)
+
+// Pipeline queues commands and sends them to the server in one round trip.
+type Pipeline struct {
+	cli     *Client
+	cmds    []*CmdInfo
+	results []*Cmd // the Cmd of each command queued by Do
+	tx      bool
+}
+
+// Pipeline returns a new pipeline of the client.
+func (cli *Client) Pipeline() *Pipeline {
+	return &Pipeline{cli: cli}
+}
+
+// TxPipeline returns a new pipeline of the client whose commands are sent in a transaction, MULTI and EXEC
+// included, and whose replies are those of EXEC.
+func (cli *Client) TxPipeline() *Pipeline {
+	return &Pipeline{cli: cli, tx: true}
+}
+
+// Queue queues a command, it is sent by Exec.
+func (p *Pipeline) Queue(cmd string, args ...interface{}) {
+	p.cmds = append(p.cmds, &CmdInfo{Name: cmd, Args: args})
+	p.results = append(p.results, nil)
+}
+
+// Do queues a command and returns it, its reply is set by Exec.
+func (p *Pipeline) Do(cmd string, args ...interface{}) *Cmd {
+	c := &Cmd{name: cmd, args: args, err: errCmdPending}
+	p.Queue(cmd, args...)
+	p.results[len(p.results)-1] = c
+	return c
+}
+
+// Len returns the number of queued commands.
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Exec sends the queued commands and returns their replies in order, the error replies included.
+// The error is the first network error. The pipeline is empty after Exec.
+//
+// The error of a transaction is also the error reply of EXEC, e.g. EXECABORT, or ErrTxAborted
+// when EXEC discards it, the error of each of its commands is then set to this error.
+func (p *Pipeline) Exec() ([]interface{}, error) {
+	cmds, results := p.cmds, p.results
+	p.cmds, p.results = nil, nil
+	if len(cmds) == 0 {
+		return []interface{}{}, nil
+	}
+	sent := cmds
+	if p.tx {
+		sent = make([]*CmdInfo, 0, len(cmds)+2)
+		sent = append(append(append(sent, &CmdInfo{Name: "MULTI"}), cmds...), &CmdInfo{Name: "EXEC"})
+	}
+	err := p.cli.hooks.pipeline(p.cli.Context(), sent, p.cli.execPipeline)
+	if p.tx && err == nil {
+		err = execTx(cmds, sent[len(sent)-1])
+	}
+	replies := make([]interface{}, len(cmds))
+	for i, c := range cmds {
+		replies[i] = c.Reply
+		if r := results[i]; r != nil {
+			r.reply, r.err = c.Reply, c.Err
+		}
+	}
+	return replies, err
+}
+
+// execTx sets the replies of the commands of a transaction to the elements of the reply of its EXEC.
+func execTx(cmds []*CmdInfo, exec *CmdInfo) error {
+	err := exec.Err
+	if r, ok := exec.Reply.([]interface{}); ok && len(r) == len(cmds) {
+		for i, c := range cmds {
+			c.Reply, c.Err = r[i], replyErr(r[i], nil)
+		}
+		return nil
+	}
+	if err == nil {
+		err = ErrTxAborted
+	}
+	for _, c := range cmds {
+		if c.Err == nil {
+			c.Reply, c.Err = nil, err
+		}
+	}
+	return err
+}
+
+// pipelineChecker is implemented by the connections which reject some pipelines as a whole,
+// before any of their commands is queued.
+type pipelineChecker interface {
+	checkPipeline(cmds []*CmdInfo) error
+}
+
+func (cli *Client) execPipeline(cmds []*CmdInfo) error {
+	start := time.Now()
+	var err error
+	if pc, ok := cli.cn.(pipelineChecker); ok {
+		err = pc.checkPipeline(cmds)
+	}
+	for _, c := range cmds {
+		if err != nil {
+			break
+		}
+		err = cli.cn.Pipe(c.Name, c.Args...)
+	}
+	if err == nil {
+		err = cli.cn.Flush()
+	}
+	for _, c := range cmds {
+		if err != nil {
+			c.Err = err
+			continue
+		}
+		c.Reply, err = cli.cn.Receive()
+		c.Err = replyErr(c.Reply, err)
+	}
+	d := time.Since(start)
+	for _, c := range cmds {
+		c.Duration = d
+	}
+	return err
+}
@@ -0,0 +1,469 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"github.com/qqbuby/goredis/redis/resp"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPoolTimeout is returned by Pool.Get when no connection is released before PoolOptions.WaitTimeout.
+var ErrPoolTimeout = errors.New("redis: connection pool timeout.")
+
+// ErrPoolClosed is returned by Pool.Get after Pool.Close.
+var ErrPoolClosed = errors.New("redis: connection pool is closed.")
+
+// errPoolState is returned by a pool client for the commands which would only change the state of one of its connections.
+var errPoolState = errors.New("redis: AUTH, SELECT and CLIENT SETNAME are not supported by a pool client, use PoolOptions.")
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	LogOptions
+	// MaxIdle is the maximum number of idle connections, 8 by default.
+	MaxIdle int
+	// MaxActive is the maximum number of connections, idle or in use. Zero means no limit.
+	MaxActive int
+	// IdleTimeout closes the connections which stay idle longer than IdleTimeout. Zero means no timeout.
+	IdleTimeout time.Duration
+	// WaitTimeout is how long Get waits for a connection when MaxActive is reached, 3 seconds by default.
+	WaitTimeout time.Duration
+	// Password is sent by AUTH on every new connection when it is not empty.
+	Password string
+	// DB is selected by SELECT on every new connection when it is not 0.
+	DB int
+}
+
+// PoolStats are the gauges and the counters of a Pool.
+type PoolStats struct {
+	Total        int           // connections, idle or in use
+	Idle         int           // idle connections
+	InUse        int           // connections in use
+	Waits        uint64        // number of Get calls which waited for a connection
+	WaitDuration time.Duration // total time waited by Get
+	Timeouts     uint64        // number of Get calls which timed out
+	StaleClosed  uint64        // number of idle connections closed by IdleTimeout
+}
+
+type idleConn struct {
+	cn *conn
+	t  time.Time
+}
+
+// Pool is a pool of connections to a server, it is safe for concurrent use.
+type Pool struct {
+	url   string
+	opt   PoolOptions
+	mu    sync.Mutex
+	idle  []idleConn
+	total int
+	slots chan struct{}
+	stats PoolStats
+	dial  dialFunc
+	log   *slog.Logger
+	done  bool
+}
+
+// NewPool returns a pool of connections to the url, the connections are dialed on demand.
+func NewPool(url string, opt PoolOptions) *Pool {
+	if opt.MaxIdle <= 0 {
+		opt.MaxIdle = 8
+	}
+	if opt.WaitTimeout <= 0 {
+		opt.WaitTimeout = time.Second * 3
+	}
+	p := &Pool{url: url, opt: opt, log: opt.Logger}
+	if opt.MaxActive > 0 {
+		p.slots = make(chan struct{}, opt.MaxActive)
+	}
+	return p
+}
+
+// Get returns a connection of the pool, its Close returns it to the pool.
+// A connection which failed on a network error or has pending replies is closed instead.
+func (p *Pool) Get() (Conn, error) {
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	if p.done {
+		p.mu.Unlock()
+		p.release()
+		return nil, ErrPoolClosed
+	}
+	for len(p.idle) > 0 {
+		ic := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.opt.IdleTimeout > 0 && time.Since(ic.t) > p.opt.IdleTimeout {
+			ic.cn.Close()
+			p.total--
+			p.stats.StaleClosed++
+			logAttr(p.log, slog.LevelDebug, "redis: stale connection closed", "url", p.url, "idle", time.Since(ic.t))
+			continue
+		}
+		p.mu.Unlock()
+		return &pooledConn{p: p, cn: ic.cn}, nil
+	}
+	p.total++
+	p.mu.Unlock()
+
+	c, err := p.dialConn()
+	if err != nil {
+		p.mu.Lock()
+		p.total--
+		p.mu.Unlock()
+		p.release()
+		return nil, err
+	}
+	return &pooledConn{p: p, cn: c}, nil
+}
+
+// dialConn dials a new connection, authenticated by PoolOptions.Password and selecting PoolOptions.DB.
+func (p *Pool) dialConn() (*conn, error) {
+	p.mu.Lock()
+	dial, l := p.dial, p.log
+	p.mu.Unlock()
+	cn, err := dialWith(dial, p.url)
+	if err != nil {
+		return nil, err
+	}
+	c := cn.(*conn)
+	c.setLogger(l)
+	var cmds [][]interface{}
+	if p.opt.Password != "" {
+		cmds = append(cmds, []interface{}{"AUTH", p.opt.Password})
+	}
+	if p.opt.DB != 0 {
+		cmds = append(cmds, []interface{}{"SELECT", p.opt.DB})
+	}
+	for _, cmd := range cmds {
+		rsp, err := c.Send(cmd[0].(string), cmd[1:]...)
+		if err == nil {
+			err, _ = rsp.(error)
+		}
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// acquire takes a slot of MaxActive, waiting up to WaitTimeout.
+func (p *Pool) acquire() error {
+	if p.slots == nil {
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	start := time.Now()
+	t := time.NewTimer(p.opt.WaitTimeout)
+	defer t.Stop()
+	var err error
+	select {
+	case p.slots <- struct{}{}:
+	case <-t.C:
+		err = ErrPoolTimeout
+	}
+	p.mu.Lock()
+	p.stats.Waits++
+	p.stats.WaitDuration += time.Since(start)
+	if err != nil {
+		p.stats.Timeouts++
+		logAttr(p.log, slog.LevelWarn, "redis: connection pool timeout", "url", p.url, "wait", p.opt.WaitTimeout)
+	}
+	p.mu.Unlock()
+	return err
+}
+
+func (p *Pool) release() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+func (p *Pool) put(cn *conn, broken bool) {
+	p.mu.Lock()
+	if broken || p.done || len(p.idle) >= p.opt.MaxIdle {
+		p.total--
+		p.mu.Unlock()
+		cn.Close()
+	} else {
+		p.idle = append(p.idle, idleConn{cn: cn, t: time.Now()})
+		p.mu.Unlock()
+	}
+	p.release()
+}
+
+// Stats returns the gauges and the counters of the pool.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	s.Total = p.total
+	s.Idle = len(p.idle)
+	s.InUse = p.total - len(p.idle)
+	return s
+}
+
+// Close closes the idle connections, the connections in use are closed when they are returned.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return ErrPoolClosed
+	}
+	p.done = true
+	for _, ic := range p.idle {
+		ic.cn.Close()
+	}
+	p.total -= len(p.idle)
+	p.idle = nil
+	return nil
+}
+
+func (p *Pool) notifyDial(fn dialFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dial = fn
+}
+
+// pooledConn is a connection of a pool, Close returns it to the pool.
+type pooledConn struct {
+	p       *Pool
+	cn      *conn
+	pending int
+	broken  bool
+}
+
+func (pc *pooledConn) failed(err error) error {
+	if err != nil {
+		pc.broken = true
+	}
+	return err
+}
+
+func (pc *pooledConn) Send(cmd string, args ...interface{}) (reply interface{}, err error) {
+	if pc.cn == nil {
+		return nil, errors.New("redis: connection is closed.")
+	}
+	reply, err = pc.cn.Send(cmd, args...)
+	return reply, pc.failed(err)
+}
+
+func (pc *pooledConn) sendCommand(cmd *resp.Command) (reply interface{}, err error) {
+	if pc.cn == nil {
+		return nil, errors.New("redis: connection is closed.")
+	}
+	reply, err = pc.cn.sendCommand(cmd)
+	return reply, pc.failed(err)
+}
+
+func (pc *pooledConn) Pipe(cmd string, args ...interface{}) error {
+	if pc.cn == nil {
+		return errors.New("redis: connection is closed.")
+	}
+	pc.pending++
+	return pc.failed(pc.cn.Pipe(cmd, args...))
+}
+
+func (pc *pooledConn) Flush() error {
+	if pc.cn == nil {
+		return errors.New("redis: connection is closed.")
+	}
+	return pc.failed(pc.cn.Flush())
+}
+
+func (pc *pooledConn) Receive() (reply interface{}, err error) {
+	if pc.cn == nil {
+		return nil, errors.New("redis: connection is closed.")
+	}
+	reply, err = pc.cn.Receive()
+	pc.pending--
+	return reply, pc.failed(err)
+}
+
+func (pc *pooledConn) Close() error {
+	if pc.cn == nil {
+		return errors.New("redis: connection is closed.")
+	}
+	pc.p.put(pc.cn, pc.broken || pc.pending != 0)
+	pc.cn = nil
+	return nil
+}
+
+// poolClientConn sends every command on a connection of the pool,
+// a pipeline holds its connection until all its replies are received.
+type poolClientConn struct {
+	p       *Pool
+	mu      sync.Mutex
+	pinned  Conn
+	pending int
+}
+
+// NewPoolClient returns a client whose commands are sent on the connections of a new pool,
+// the commands may be sent concurrently, the pipelines may not.
+// AUTH, SELECT and CLIENT SETNAME return an error, as they would only change one connection of the pool,
+// the password and the database are set by opt.Password and opt.DB instead.
+func NewPoolClient(url string, opt PoolOptions) (Client, *Pool) {
+	p := NewPool(url, opt)
+	cli := Client{cn: &poolClientConn{p: p}}
+	cli.SetLogger(opt.LogOptions)
+	return cli, p
+}
+
+// checkState rejects the commands which change the state of a connection.
+func checkState(cmd string, args []interface{}) error {
+	switch strings.ToUpper(cmd) {
+	case "AUTH", "SELECT":
+		return errPoolState
+	case "CLIENT":
+		if len(args) == 0 {
+			break
+		}
+		sub := fmt.Sprint(args[0])
+		if b, ok := args[0].([]byte); ok {
+			sub = string(b)
+		}
+		if strings.EqualFold(sub, "SETNAME") {
+			return errPoolState
+		}
+	}
+	return nil
+}
+
+func (pcc *poolClientConn) Send(cmd string, args ...interface{}) (reply interface{}, err error) {
+	if err := checkState(cmd, args); err != nil {
+		return nil, err
+	}
+	cn, err := pcc.p.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer cn.Close()
+	return cn.Send(cmd, args...)
+}
+
+func (pcc *poolClientConn) sendCommand(cmd *resp.Command) (reply interface{}, err error) {
+	if err := checkState(cmd.Name(), cmd.Args()); err != nil {
+		return nil, err
+	}
+	cn, err := pcc.p.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer cn.Close()
+	return cn.(commandSender).sendCommand(cmd)
+}
+
+func (pcc *poolClientConn) sendReader(cmd string, args []interface{}) (*resp.Reader, func(broken bool), error) {
+	cn, err := pcc.p.Get()
+	if err != nil {
+		return nil, nil, err
+	}
+	pc := cn.(*pooledConn)
+	r, _, err := pc.cn.sendReader(cmd, args)
+	if err != nil {
+		pc.broken = true
+		pc.Close()
+		return nil, nil, err
+	}
+	return r, func(broken bool) {
+		pc.broken = pc.broken || broken
+		pc.Close()
+	}, nil
+}
+
+func (pcc *poolClientConn) Pipe(cmd string, args ...interface{}) error {
+	pcc.mu.Lock()
+	defer pcc.mu.Unlock()
+	if err := checkState(cmd, args); err != nil {
+		pcc.release()
+		return err
+	}
+	if pcc.pinned == nil {
+		cn, err := pcc.p.Get()
+		if err != nil {
+			return err
+		}
+		pcc.pinned = cn
+	}
+	if err := pcc.pinned.Pipe(cmd, args...); err != nil {
+		pcc.release()
+		return err
+	}
+	pcc.pending++
+	return nil
+}
+
+func (pcc *poolClientConn) Flush() error {
+	pcc.mu.Lock()
+	defer pcc.mu.Unlock()
+	if pcc.pinned == nil {
+		return nil
+	}
+	err := pcc.pinned.Flush()
+	if err != nil {
+		pcc.release()
+	}
+	return err
+}
+
+// release returns the pinned connection to the pool after a failed pipeline, its replies are not received,
+// so it is closed if commands were queued on it.
+func (pcc *poolClientConn) release() {
+	if pcc.pinned != nil {
+		pcc.pinned.Close()
+		pcc.pinned, pcc.pending = nil, 0
+	}
+}
+
+func (pcc *poolClientConn) Receive() (reply interface{}, err error) {
+	pcc.mu.Lock()
+	defer pcc.mu.Unlock()
+	if pcc.pinned == nil {
+		return nil, errors.New("redis: no pending reply.")
+	}
+	reply, err = pcc.pinned.Receive()
+	pcc.pending--
+	if pcc.pending == 0 || err != nil {
+		pcc.pinned.Close()
+		pcc.pinned, pcc.pending = nil, 0
+	}
+	return reply, err
+}
+
+func (pcc *poolClientConn) Close() error {
+	pcc.mu.Lock()
+	defer pcc.mu.Unlock()
+	if pcc.pinned != nil {
+		pcc.pinned.Close()
+		pcc.pinned = nil
+	}
+	return pcc.p.Close()
+}
+
+func (pcc *poolClientConn) notifyDial(fn dialFunc) {
+	pcc.p.notifyDial(fn)
+}
+
+func (pcc *poolClientConn) setLogger(l *slog.Logger) {
+	pcc.p.mu.Lock()
+	defer pcc.p.mu.Unlock()
+	pcc.p.log = l
+}
+
+// dialDedicated returns a new connection to the url of the pool, which is not counted by the pool,
+// authenticated and selecting the database as the connections of the pool.
+func (pcc *poolClientConn) dialDedicated() (Conn, error) {
+	return pcc.p.dialConn()
+}
@@ -0,0 +1,129 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"github.com/qqbuby/goredis/redis"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	pool := redis.NewPool(url, redis.PoolOptions{MaxIdle: 1, MaxActive: 2, WaitTimeout: time.Millisecond * 10})
+	defer pool.Close()
+
+	c1, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Pool.Get: %s", err.Error())
+	}
+	c2, _ := pool.Get()
+	if _, err := pool.Get(); err != redis.ErrPoolTimeout {
+		t.Errorf("Pool did not work properly. E:%v, R:%v", redis.ErrPoolTimeout, err)
+	}
+	if s := pool.Stats(); s.Total != 2 || s.InUse != 2 || s.Waits != 1 || s.Timeouts != 1 {
+		t.Errorf("Pool did not work properly. Stats:%+v", s)
+	}
+	c1.Close()
+	c2.Close()
+	if s := pool.Stats(); s.Total != 1 || s.Idle != 1 {
+		t.Errorf("Pool did not work properly. Stats:%+v", s)
+	}
+}
+
+func TestPoolClient(t *testing.T) {
+	const (
+		key = "TEST:POOL"
+	)
+	client, pool := redis.NewPoolClient(url, redis.PoolOptions{MaxActive: 4})
+	defer client.Close()
+
+	client.Del(key)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Incr(key)
+		}()
+	}
+	wg.Wait()
+	v, _ := client.Get(key)
+	if v != "10" {
+		t.Errorf("PoolClient did not work properly. E:%s, R:%v", "10", v)
+	}
+	if s := pool.Stats(); s.InUse != 0 || s.Total > 4 {
+		t.Errorf("PoolClient did not work properly. Stats:%+v", s)
+	}
+}
+
+func TestPoolClientState(t *testing.T) {
+	const (
+		key      = "TEST:POOL:STATE"
+		password = "secret"
+	)
+	srv, cli := newScratchClient(t)
+	srv.SetPassword(password)
+	client, _ := redis.NewPoolClient(srv.URL(), redis.PoolOptions{Password: password, DB: 3})
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Incr(key)
+		}()
+	}
+	wg.Wait()
+	cli.Auth(password)
+	cli.Select(3)
+	if v, _ := cli.Get(key); v != "4" {
+		t.Errorf("PoolClient did not work properly. E:%s, R:%v", "4", v)
+	}
+
+	if _, err := client.Select(1); err == nil {
+		t.Error("PoolClient did not work properly: SELECT is not supported.")
+	}
+	if _, err := client.Auth(password); err == nil {
+		t.Error("PoolClient did not work properly: AUTH is not supported.")
+	}
+	if _, err := client.ClientSetName("pool"); err == nil {
+		t.Error("PoolClient did not work properly: CLIENT SETNAME is not supported.")
+	}
+}
+
+func TestPoolClientPipelineBroken(t *testing.T) {
+	const (
+		key = "TEST:POOL:PIPELINE"
+	)
+	srv, cli := newScratchClient(t)
+	client, pool := redis.NewPoolClient(srv.URL(), redis.PoolOptions{MaxIdle: 1})
+	defer client.Close()
+
+	id, err := client.ClientID()
+	if err != nil {
+		t.Fatalf("ClientID: %s", err.Error())
+	}
+	if n, _ := cli.ClientKill("ID", id); n != 1 {
+		t.Fatalf("PoolClient did not work properly. E:%d, R:%d", 1, n)
+	}
+	p := client.Pipeline()
+	p.Queue("SET", key, strings.Repeat("x", 4<<20))
+	p.Queue("GET", key)
+	if _, err := p.Exec(); err == nil {
+		t.Fatal("PoolClient did not work properly: the connection was killed.")
+	}
+	p.Queue("SET", key, "1")
+	p.Queue("GET", key)
+	replies, err := p.Exec()
+	if err != nil || len(replies) != 2 || string(replies[1].([]byte)) != "1" {
+		t.Errorf("PoolClient did not work properly. E:%s, R:%v %v", "1", replies, err)
+	}
+	if s := pool.Stats(); s.InUse != 0 {
+		t.Errorf("PoolClient did not work properly. Stats:%+v", s)
+	}
+}
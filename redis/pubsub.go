@@ -5,6 +5,7 @@
 package redis
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -14,6 +15,7 @@ type PubSub struct {
 	cn       Conn
 	channels []string
 	numSub   int
+	hooks    *hooks
 }
 
 func NewPubSub(url string) (PubSub, error) {
@@ -64,15 +66,14 @@ func (p *PubSub) NumSub() int {
 // PSUBSCRIBE pattern [pattern ...]
 // Listen for messages published to channels matching the given patterns
 func (p *PubSub) PSubscribe(pattern string, patterns ...interface{}) error {
-	p.cn.Pipe("SUBSCRIBE", MakeSlice(patterns, pattern)...)
-	return p.cn.Flush()
+	return p.pipe("PSUBSCRIBE", MakeSlice(patterns, pattern)...)
 }
 
 // PUBLISH channel message
 // Post a message to a channel
 // Integer reply: the number of clients that received the message.
 func (p *PubSub) Publish(channel, message string) (int, error) {
-	r, e := p.cn.Send("PUBLISH", channel, message)
+	r, e := p.send("PUBLISH", channel, message)
 	v, _ := Int(r)
 	return v, e
 }
@@ -80,22 +81,34 @@ func (p *PubSub) Publish(channel, message string) (int, error) {
 // PUNSUBSCRIBE [pattern [pattern ...]]
 // Stop listening for messages posted to channels matching the given patterns
 func (p *PubSub) PUnsubscribe(pattern string, patterns ...interface{}) error {
-	p.cn.Pipe("SUBSCRIBE", MakeSlice(patterns, pattern)...)
-	return p.cn.Flush()
+	return p.pipe("PUNSUBSCRIBE", MakeSlice(patterns, pattern)...)
 }
 
 // SUBSCRIBE channel [channel ...]
 // Listen for messages published to the given channels
 func (p *PubSub) Subscribe(channel string, channels ...interface{}) error {
-	p.cn.Pipe("SUBSCRIBE", MakeSlice(channels, channel)...)
-	return p.cn.Flush()
+	return p.pipe("SUBSCRIBE", MakeSlice(channels, channel)...)
 }
 
 // UNSUBSCRIBE [channel [channel ...]]
 // Stop listening for messages posted to the given channels
 func (p *PubSub) Unsubscribe(channel string, channels ...interface{}) error {
-	p.cn.Pipe("SUBSCRIBE", MakeSlice(channels, channel)...)
-	return p.cn.Flush()
+	return p.pipe("UNSUBSCRIBE", MakeSlice(channels, channel)...)
+}
+
+func (p *PubSub) send(cmd string, args ...interface{}) (interface{}, error) {
+	return p.hooks.process(context.Background(), cmd, args, p.cn.Send)
+}
+
+// pipe sends a subscription command whose replies are read by Receive.
+func (p *PubSub) pipe(cmd string, args ...interface{}) error {
+	_, err := p.hooks.process(context.Background(), cmd, args, func(cmd string, args ...interface{}) (interface{}, error) {
+		if err := p.cn.Pipe(cmd, args...); err != nil {
+			return nil, err
+		}
+		return nil, p.cn.Flush()
+	})
+	return err
 }
 
 func (p *PubSub) Receive() interface{} {
@@ -127,7 +140,7 @@ func (p *PubSub) Receive() interface{} {
 }
 
 func (p *PubSub) Ping() (string, error) {
-	v, err := p.cn.Send("PING")
+	v, err := p.send("PING")
 	s, _ := v.(string)
 	return s, err
 }
@@ -0,0 +1,198 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"bytes"
+	"errors"
+	"github.com/qqbuby/goredis/redis/resp"
+	"io"
+)
+
+var errReplyRead = errors.New("redis: the reply is read.")
+
+// replyReaderSender is implemented by the connections whose replies can be read as they are received.
+// release is called once the reply is read, broken reports that the connection cannot be used anymore.
+type replyReaderSender interface {
+	sendReader(cmd string, args []interface{}) (r *resp.Reader, release func(broken bool), err error)
+}
+
+// ReplyReader reads the reply of a command element by element into the buffers of the caller,
+// so the replies of millions of elements are read without materialising them:
+//
+//	rr, err := client.SendReader("LRANGE", "list", 0, -1)
+//	if err != nil {
+//		return err
+//	}
+//	defer rr.Close()
+//	n, err := rr.ReadArrayLen()
+//	var b []byte
+//	for i := 0; i < n && err == nil; i++ {
+//		_, err = rr.ReadInto(&b) // b is valid until the next read.
+//		...
+//	}
+//
+// The connection of the reply is held until Close.
+type ReplyReader struct {
+	r       *resp.Reader
+	left    int       // the number of values left to read
+	stream  io.Reader // the stream returned by ReadStream, it is skipped by the next read
+	err     error
+	release func(broken bool)
+}
+
+// SendReader sends a command and returns the reader of its reply, the command is not retried.
+// When the client has hooks or its connection cannot read the replies as they are received,
+// the reply is received by Send and read from memory.
+func (cli *Client) SendReader(cmd string, args ...interface{}) (*ReplyReader, error) {
+	rs, ok := cli.cn.(replyReaderSender)
+	if !ok || len(cli.hooks.get()) > 0 {
+		reply, err := cli.Send(cmd, args...)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		resp.NewWriter(&b).WriteValue(reply)
+		return &ReplyReader{r: resp.NewReader(&b), left: 1, release: func(bool) {}}, nil
+	}
+	r, release, err := rs.sendReader(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplyReader{r: r, left: 1, release: release}, nil
+}
+
+// Remaining returns the number of values left to read, the elements of the arrays whose length is read included.
+func (rr *ReplyReader) Remaining() int {
+	return rr.left
+}
+
+// ReadArrayLen reads the length of an array, see resp.Reader.ReadArrayLen.
+func (rr *ReplyReader) ReadArrayLen() (int, error) {
+	if err := rr.check(); err != nil {
+		return 0, err
+	}
+	n, err := rr.r.ReadArrayLen()
+	return n, rr.read(n, err)
+}
+
+// ReadInto reads a value which is not an array into dst, see resp.Reader.ReadInto.
+func (rr *ReplyReader) ReadInto(dst *[]byte) (resp.Type, error) {
+	if err := rr.check(); err != nil {
+		return 0, err
+	}
+	t, err := rr.r.ReadInto(dst)
+	return t, rr.read(0, err)
+}
+
+// ReadStream reads a bulk string as a stream, see resp.Reader.ReadStream.
+// What is left of the stream is skipped by the next read or by Close.
+func (rr *ReplyReader) ReadStream() (io.Reader, int64, error) {
+	if err := rr.check(); err != nil {
+		return nil, 0, err
+	}
+	r, n, err := rr.r.ReadStream()
+	rr.stream = r
+	return r, n, rr.read(0, err)
+}
+
+// Skip reads a value and discards it.
+func (rr *ReplyReader) Skip() error {
+	if err := rr.check(); err != nil {
+		return err
+	}
+	return rr.read(0, rr.r.Skip())
+}
+
+// Close reads what is left of the reply and releases its connection.
+func (rr *ReplyReader) Close() error {
+	if rr.release == nil {
+		return nil
+	}
+	rr.skipStream()
+	for rr.err == nil && rr.left > 0 {
+		rr.Skip()
+	}
+	rr.release(rr.err != nil)
+	rr.release = nil
+	return rr.err
+}
+
+func (rr *ReplyReader) check() error {
+	rr.skipStream()
+	if rr.err != nil {
+		return rr.err
+	}
+	if rr.left == 0 || rr.release == nil {
+		return errReplyRead
+	}
+	return nil
+}
+
+func (rr *ReplyReader) skipStream() {
+	if rr.stream == nil || rr.err != nil {
+		return
+	}
+	if _, err := io.Copy(io.Discard, rr.stream); err != nil {
+		rr.err = err
+	}
+	rr.stream = nil
+}
+
+// read counts a read value, an array of n elements adds its elements to the values left to read.
+func (rr *ReplyReader) read(n int, err error) error {
+	switch err.(type) {
+	case nil:
+		rr.left += max(n, 0) - 1
+	case resp.Error:
+		rr.left--
+	case *resp.TypeError:
+		// The value is not read.
+	default:
+		rr.err = err
+	}
+	return err
+}
+
+// GetReader returns the reader of the value of a key, read as it is received, and the size of the value.
+// The reader must be closed, the connection is held until then. For a key which does not exist,
+// the reader is nil and the size is -1.
+func (cli *Client) GetReader(key interface{}) (io.ReadCloser, int64, error) {
+	rr, err := cli.SendReader("GET", key)
+	if err != nil {
+		return nil, 0, err
+	}
+	r, size, err := rr.ReadStream()
+	if r == nil {
+		if e := rr.Close(); err == nil {
+			err = e
+		}
+		return nil, size, err
+	}
+	return &valueReader{Reader: r, rr: rr}, size, nil
+}
+
+// valueReader is the reader of GetReader, Close releases its connection.
+type valueReader struct {
+	io.Reader
+	rr *ReplyReader
+}
+
+func (v *valueReader) Close() error {
+	return v.rr.Close()
+}
+
+// SetFromReader sets the value of a key to size bytes copied from r as they are sent, p are the options of Set.
+// When r has less than size bytes, io.ErrUnexpectedEOF is returned and the connection, which is left
+// with a partial value, is re-established.
+func (cli *Client) SetFromReader(key interface{}, r io.Reader, size int64, p ...interface{}) (string, error) {
+	s, err := cli.Set(key, resp.Stream{R: r, Size: size}, p...)
+	if err != nil {
+		if rd, ok := cli.cn.(redialer); ok {
+			rd.redial()
+		}
+	}
+	return s, err
+}
@@ -0,0 +1,129 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"bytes"
+	"github.com/qqbuby/goredis/redis"
+	"github.com/qqbuby/goredis/redis/resp"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// scanList reads an LRANGE element by element into a reused buffer.
+func scanList(t *testing.T, client *redis.Client, key string, n int) {
+	rr, err := client.SendReader("LRANGE", key, 0, -1)
+	if err != nil {
+		t.Fatalf("SendReader: %s", err.Error())
+	}
+	defer rr.Close()
+	l, err := rr.ReadArrayLen()
+	if l != n || err != nil {
+		t.Fatalf("ReadArrayLen did not work properly. E:%d, R:%d, %v", n, l, err)
+	}
+	var b []byte
+	for i := 0; i < l; i++ {
+		typ, err := rr.ReadInto(&b)
+		if typ != resp.BulkString || string(b) != strconv.Itoa(i) || err != nil {
+			t.Fatalf("ReadInto did not work properly. E:%d, R:%q %q %v", i, typ, b, err)
+		}
+	}
+	if rr.Remaining() != 0 {
+		t.Errorf("ReplyReader did not work properly. Remaining:%d", rr.Remaining())
+	}
+}
+
+func TestSendReader(t *testing.T) {
+	const (
+		key = "TEST:SENDREADER"
+		n   = 1000
+	)
+	// A client of its own, in the database 0 as the pool client and the hooked client below.
+	client, err := redis.NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer client.Close()
+	defer client.Del(key)
+	args := make([]interface{}, n)
+	for i := range args {
+		args[i] = i
+	}
+	client.Send("RPUSH", append([]interface{}{key}, args...)...)
+	scanList(t, &client, key, n)
+
+	// Close reads what is left of the reply, the connection is ready for the next command.
+	rr, _ := client.SendReader("LRANGE", key, 0, -1)
+	rr.ReadArrayLen()
+	if err := rr.Close(); err != nil {
+		t.Errorf("Close did not work properly: %s", err.Error())
+	}
+	if s, _ := client.Ping(); s != "PONG" {
+		t.Errorf("Close did not work properly. E:%s, R:%s", "PONG", s)
+	}
+
+	rr, _ = client.SendReader("GET", key)
+	if n, err := rr.ReadArrayLen(); n != -1 || err == nil {
+		t.Errorf("ReadArrayLen did not work properly: WRONGTYPE. R:%d %v", n, err)
+	}
+	rr.Close()
+
+	pc, _ := redis.NewPoolClient(url, redis.PoolOptions{MaxIdle: 1})
+	defer pc.Close()
+	scanList(t, &pc, key, n)
+
+	hooked, _ := redis.NewClient(url)
+	defer hooked.Close()
+	hooked.AddHook(&recordHook{})
+	scanList(t, &hooked, key, n)
+}
+
+func TestGetReader(t *testing.T) {
+	const key = "TEST:GETREADER"
+	value := bytes.Repeat([]byte("0123456789abcdef"), 1<<16) // 1MB
+	if s, err := client.SetFromReader(key, bytes.NewReader(value), int64(len(value))); s != "OK" {
+		t.Fatalf("SetFromReader did not work properly. E:%s, R:%s %v", "OK", s, err)
+	}
+	r, size, err := client.GetReader(key)
+	if err != nil {
+		t.Fatalf("GetReader: %s", err.Error())
+	}
+	b, err := io.ReadAll(r)
+	r.Close()
+	if size != int64(len(value)) || !bytes.Equal(b, value) || err != nil {
+		t.Errorf("GetReader did not work properly. E:%d, R:%d %d %v", len(value), size, len(b), err)
+	}
+
+	// Close skips what is left of the value.
+	r, _, _ = client.GetReader(key)
+	r.Read(make([]byte, 10))
+	r.Close()
+	if s, _ := client.Ping(); s != "PONG" {
+		t.Errorf("GetReader did not work properly. E:%s, R:%s", "PONG", s)
+	}
+
+	client.Del(key)
+	if r, size, err := client.GetReader(key); r != nil || size != -1 || err != nil {
+		t.Errorf("GetReader did not work properly. E:%d, R:%d %v", -1, size, err)
+	}
+
+	short, _ := redis.NewClient(url)
+	defer short.Close()
+	if _, err := short.SetFromReader(key, bytes.NewReader(value[:10]), 20); err != io.ErrUnexpectedEOF {
+		t.Errorf("SetFromReader did not work properly. E:%v, R:%v", io.ErrUnexpectedEOF, err)
+	}
+	if s, err := short.Set(key, "value"); s != "OK" {
+		t.Errorf("SetFromReader did not work properly. E:%s, R:%s %v", "OK", s, err)
+	}
+	pc, _ := redis.NewPoolClient(url, redis.PoolOptions{MaxIdle: 1})
+	defer pc.Close()
+	if _, err := pc.SetFromReader(key, bytes.NewReader(value[:10]), 20); err != io.ErrUnexpectedEOF {
+		t.Errorf("SetFromReader did not work properly. E:%v, R:%v", io.ErrUnexpectedEOF, err)
+	}
+	if s, err := pc.Set(key, "value"); s != "OK" {
+		t.Errorf("SetFromReader did not work properly. E:%s, R:%s %v", "OK", s, err)
+	}
+}
@@ -0,0 +1,195 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+// Package redisotel traces the commands of a redis.Client with OpenTelemetry.
+//
+//	client.AddHook(redisotel.NewTracingHook(redisotel.WithAddr(url)))
+//	client.WithContext(ctx).Get(key) // a child span of the span of ctx
+package redisotel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qqbuby/goredis/redis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/qqbuby/goredis/redis/redisotel"
+
+// Redaction builds the db.statement of a command from its name and arguments.
+type Redaction func(cmd string, args []interface{}) string
+
+// RedactValues keeps the command name and its first argument (usually the key),
+// the other arguments are replaced by "?". It is the default redaction.
+func RedactValues(cmd string, args []interface{}) string {
+	if len(args) == 0 {
+		return cmd
+	}
+	s := []string{cmd, arg(args[0])}
+	for range args[1:] {
+		s = append(s, "?")
+	}
+	return strings.Join(s, " ")
+}
+
+// RedactAll keeps only the command name.
+func RedactAll(cmd string, args []interface{}) string {
+	return cmd
+}
+
+// RedactNone keeps the whole command.
+func RedactNone(cmd string, args []interface{}) string {
+	s := []string{cmd}
+	for _, a := range args {
+		s = append(s, arg(a))
+	}
+	return strings.Join(s, " ")
+}
+
+// secretCommands never show their arguments whatever the redaction, the subcommands, e.g. ACL SETUSER,
+// only show their name.
+var secretCommands = map[string]bool{
+	"AUTH": true, "HELLO": true, "MIGRATE": true,
+	"ACL SETUSER": true, "CONFIG SET": true,
+}
+
+func arg(a interface{}) string {
+	if b, ok := a.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(a)
+}
+
+// Option configures a tracing hook.
+type Option func(*TracingHook)
+
+// WithTracerProvider sets the tracer provider, the global provider by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(h *TracingHook) {
+		h.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// WithAddr sets the server address of the spans from the url of the client, e.g. "tcp://127.0.0.1:6379".
+func WithAddr(urlstring string) Option {
+	return func(h *TracingHook) {
+		u, err := url.Parse(urlstring)
+		if err != nil {
+			return
+		}
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			return
+		}
+		h.attrs = append(h.attrs, attribute.String("server.address", host))
+		if p, err := strconv.Atoi(port); err == nil {
+			h.attrs = append(h.attrs, attribute.Int("server.port", p))
+		}
+	}
+}
+
+// WithRedaction sets the redaction of the db.statement attribute, RedactValues by default.
+func WithRedaction(r Redaction) Option {
+	return func(h *TracingHook) {
+		h.redact = r
+	}
+}
+
+// TracingHook is a redis.Hook which emits one span per command or pipeline.
+type TracingHook struct {
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+	redact Redaction
+}
+
+var _ redis.Hook = (*TracingHook)(nil)
+
+// NewTracingHook returns a tracing hook to add to a client by AddHook.
+func NewTracingHook(opts ...Option) *TracingHook {
+	h := &TracingHook{
+		tracer: otel.GetTracerProvider().Tracer(instrumentationName),
+		attrs:  []attribute.KeyValue{attribute.String("db.system", "redis")},
+		redact: RedactValues,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *TracingHook) statement(cmd *redis.CmdInfo) string {
+	name := strings.ToUpper(cmd.Name)
+	if secretCommands[name] {
+		return RedactAll(cmd.Name, cmd.Args)
+	}
+	if len(cmd.Args) > 0 && secretCommands[name+" "+strings.ToUpper(arg(cmd.Args[0]))] {
+		return RedactAll(cmd.Name+" "+arg(cmd.Args[0]), cmd.Args[1:])
+	}
+	return h.redact(cmd.Name, cmd.Args)
+}
+
+func (h *TracingHook) BeforeProcess(ctx context.Context, cmd *redis.CmdInfo) (context.Context, error) {
+	ctx, span := h.tracer.Start(ctx, strings.ToUpper(cmd.Name), trace.WithSpanKind(trace.SpanKindClient))
+	span.SetAttributes(h.attrs...)
+	span.SetAttributes(attribute.String("db.statement", h.statement(cmd)))
+	return ctx, nil
+}
+
+func (h *TracingHook) AfterProcess(ctx context.Context, cmd *redis.CmdInfo) error {
+	span := trace.SpanFromContext(ctx)
+	end(span, cmd.Err)
+	return nil
+}
+
+func (h *TracingHook) BeforePipeline(ctx context.Context, cmds []*redis.CmdInfo) (context.Context, error) {
+	ctx, span := h.tracer.Start(ctx, "pipeline", trace.WithSpanKind(trace.SpanKindClient))
+	s := make([]string, len(cmds))
+	for i, c := range cmds {
+		s[i] = h.statement(c)
+	}
+	span.SetAttributes(h.attrs...)
+	span.SetAttributes(
+		attribute.String("db.statement", strings.Join(s, "\n")),
+		attribute.Int("db.redis.num_cmd", len(cmds)),
+	)
+	return ctx, nil
+}
+
+func (h *TracingHook) AfterPipeline(ctx context.Context, cmds []*redis.CmdInfo) error {
+	span := trace.SpanFromContext(ctx)
+	var err error
+	for _, c := range cmds {
+		if c.Err != nil {
+			err = c.Err
+			break
+		}
+	}
+	end(span, err)
+	return nil
+}
+
+func (h *TracingHook) OnDial(ctx context.Context, network, addr string, d time.Duration, err error) {
+	_, span := h.tracer.Start(ctx, "dial", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(time.Now().Add(-d)))
+	span.SetAttributes(h.attrs...)
+	span.SetAttributes(attribute.String("network.transport", network), attribute.String("network.peer.address", addr))
+	end(span, err)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,113 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redisotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qqbuby/goredis/redis"
+	"github.com/qqbuby/goredis/redis/redisotel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newHook(opts ...redisotel.Option) (*redisotel.TracingHook, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	opts = append(opts, redisotel.WithTracerProvider(tp), redisotel.WithAddr("tcp://127.0.0.1:6379"))
+	return redisotel.NewTracingHook(opts...), exporter
+}
+
+func attr(attrs []attribute.KeyValue, key string) attribute.Value {
+	for _, a := range attrs {
+		if string(a.Key) == key {
+			return a.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestProcess(t *testing.T) {
+	h, exporter := newHook()
+	parent, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "parent")
+	defer span.End()
+
+	cmd := &redis.CmdInfo{Name: "SET", Args: []interface{}{"key", "secret"}}
+	ctx, _ := h.BeforeProcess(parent, cmd)
+	cmd.Err = errors.New("ERR wrong number of arguments")
+	h.AfterProcess(ctx, cmd)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("TracingHook did not work properly. Spans:%d", len(spans))
+	}
+	s := spans[0]
+	if s.Name != "SET" || s.Parent.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("TracingHook did not work properly. Name:%s, Parent:%v", s.Name, s.Parent.SpanID())
+	}
+	if v := attr(s.Attributes, "db.system").AsString(); v != "redis" {
+		t.Errorf("TracingHook did not work properly. E:%s, R:%s", "redis", v)
+	}
+	if v := attr(s.Attributes, "db.statement").AsString(); v != "SET key ?" {
+		t.Errorf("TracingHook did not work properly. E:%s, R:%s", "SET key ?", v)
+	}
+	if v := attr(s.Attributes, "server.address").AsString(); v != "127.0.0.1" {
+		t.Errorf("TracingHook did not work properly. E:%s, R:%s", "127.0.0.1", v)
+	}
+	if s.Status.Code != codes.Error {
+		t.Errorf("TracingHook did not work properly. Status:%v", s.Status)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	h, exporter := newHook(redisotel.WithRedaction(redisotel.RedactNone))
+	for _, cmd := range []*redis.CmdInfo{
+		{Name: "SET", Args: []interface{}{"key", []byte("value")}},
+		{Name: "AUTH", Args: []interface{}{"password"}},
+		{Name: "ACL", Args: []interface{}{"setuser", "app", "on", ">secret"}},
+		{Name: "CONFIG", Args: []interface{}{[]byte("SET"), "requirepass", "secret"}},
+		{Name: "ACL", Args: []interface{}{"GETUSER", "app"}},
+	} {
+		ctx, _ := h.BeforeProcess(context.Background(), cmd)
+		h.AfterProcess(ctx, cmd)
+	}
+	spans := exporter.GetSpans()
+	if v := attr(spans[0].Attributes, "db.statement").AsString(); v != "SET key value" {
+		t.Errorf("TracingHook did not work properly. E:%s, R:%s", "SET key value", v)
+	}
+	if v := attr(spans[1].Attributes, "db.statement").AsString(); v != "AUTH" {
+		t.Errorf("TracingHook did not work properly. E:%s, R:%s", "AUTH", v)
+	}
+	for i, e := range []string{"ACL setuser", "CONFIG SET", "ACL GETUSER app"} {
+		if v := attr(spans[i+2].Attributes, "db.statement").AsString(); v != e {
+			t.Errorf("TracingHook did not work properly. E:%s, R:%s", e, v)
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	h, exporter := newHook()
+	cmds := []*redis.CmdInfo{
+		{Name: "SET", Args: []interface{}{"key", "value"}},
+		{Name: "GET", Args: []interface{}{"key"}},
+	}
+	ctx, _ := h.BeforePipeline(context.Background(), cmds)
+	h.AfterPipeline(ctx, cmds)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "pipeline" {
+		t.Fatalf("TracingHook did not work properly. Spans:%v", spans)
+	}
+	if v := attr(spans[0].Attributes, "db.redis.num_cmd").AsInt64(); v != 2 {
+		t.Errorf("TracingHook did not work properly. E:%d, R:%d", 2, v)
+	}
+	if v := attr(spans[0].Attributes, "db.statement").AsString(); v != "SET key ?\nGET key" {
+		t.Errorf("TracingHook did not work properly. R:%q", v)
+	}
+}
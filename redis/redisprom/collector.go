@@ -0,0 +1,104 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+// Package redisprom exports the metrics of the redis package to Prometheus.
+//
+//	m := redis.NewMetrics()
+//	client.AddHook(m)
+//	redisprom.NewCollector("cache", m, pool).Register(prometheus.DefaultRegisterer)
+package redisprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qqbuby/goredis/redis"
+)
+
+const namespace = "redis"
+
+var (
+	commandDesc = prometheus.NewDesc(namespace+"_command_duration_seconds",
+		"Latency of the commands.", []string{"client", "command"}, nil)
+	errorDesc = prometheus.NewDesc(namespace+"_errors_total",
+		"Errors of the commands by Redis error prefix.", []string{"client", "prefix"}, nil)
+	pipelineSizeDesc = prometheus.NewDesc(namespace+"_pipeline_size",
+		"Number of commands of the pipelines.", []string{"client"}, nil)
+	pipelineDesc = prometheus.NewDesc(namespace+"_pipeline_duration_seconds",
+		"Latency of the pipelines.", []string{"client"}, nil)
+
+	poolTotalDesc = prometheus.NewDesc(namespace+"_pool_connections",
+		"Connections of the pool, idle or in use.", []string{"client"}, nil)
+	poolIdleDesc = prometheus.NewDesc(namespace+"_pool_idle_connections",
+		"Idle connections of the pool.", []string{"client"}, nil)
+	poolInUseDesc = prometheus.NewDesc(namespace+"_pool_in_use_connections",
+		"Connections of the pool in use.", []string{"client"}, nil)
+	poolWaitsDesc = prometheus.NewDesc(namespace+"_pool_waits_total",
+		"Number of times a connection was waited for.", []string{"client"}, nil)
+	poolWaitDurationDesc = prometheus.NewDesc(namespace+"_pool_wait_duration_seconds_total",
+		"Total time waited for a connection.", []string{"client"}, nil)
+	poolTimeoutsDesc = prometheus.NewDesc(namespace+"_pool_timeouts_total",
+		"Number of times no connection was available in time.", []string{"client"}, nil)
+	poolStaleDesc = prometheus.NewDesc(namespace+"_pool_stale_connections_closed_total",
+		"Number of idle connections closed by the idle timeout.", []string{"client"}, nil)
+)
+
+// Collector is a prometheus.Collector of the metrics of a client and of its pool.
+type Collector struct {
+	name    string
+	metrics *redis.Metrics
+	pool    *redis.Pool
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a collector labelled client=name, metrics or pool may be nil.
+func NewCollector(name string, metrics *redis.Metrics, pool *redis.Pool) *Collector {
+	return &Collector{name: name, metrics: metrics, pool: pool}
+}
+
+// Register registers the collector with the registerer.
+func (c *Collector) Register(reg prometheus.Registerer) error {
+	return reg.Register(c)
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		commandDesc, errorDesc, pipelineSizeDesc, pipelineDesc,
+		poolTotalDesc, poolIdleDesc, poolInUseDesc, poolWaitsDesc,
+		poolWaitDurationDesc, poolTimeoutsDesc, poolStaleDesc,
+	} {
+		ch <- d
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	if c.metrics != nil {
+		s := c.metrics.Stats()
+		for cmd, h := range s.Commands {
+			ch <- histogram(commandDesc, h, c.name, cmd)
+		}
+		for prefix, n := range s.Errors {
+			ch <- prometheus.MustNewConstMetric(errorDesc, prometheus.CounterValue, float64(n), c.name, prefix)
+		}
+		ch <- histogram(pipelineSizeDesc, s.Pipelines, c.name)
+		ch <- histogram(pipelineDesc, s.PipelineLatency, c.name)
+	}
+	if c.pool != nil {
+		s := c.pool.Stats()
+		ch <- prometheus.MustNewConstMetric(poolTotalDesc, prometheus.GaugeValue, float64(s.Total), c.name)
+		ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(s.Idle), c.name)
+		ch <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(s.InUse), c.name)
+		ch <- prometheus.MustNewConstMetric(poolWaitsDesc, prometheus.CounterValue, float64(s.Waits), c.name)
+		ch <- prometheus.MustNewConstMetric(poolWaitDurationDesc, prometheus.CounterValue, s.WaitDuration.Seconds(), c.name)
+		ch <- prometheus.MustNewConstMetric(poolTimeoutsDesc, prometheus.CounterValue, float64(s.Timeouts), c.name)
+		ch <- prometheus.MustNewConstMetric(poolStaleDesc, prometheus.CounterValue, float64(s.StaleClosed), c.name)
+	}
+}
+
+func histogram(desc *prometheus.Desc, h redis.Histogram, labels ...string) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(h.Buckets))
+	for i, b := range h.Buckets {
+		buckets[b] = h.Counts[i]
+	}
+	return prometheus.MustNewConstHistogram(desc, h.Count, h.Sum, buckets, labels...)
+}
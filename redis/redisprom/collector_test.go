@@ -0,0 +1,59 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redisprom_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qqbuby/goredis/redis"
+	"github.com/qqbuby/goredis/redis/redisprom"
+)
+
+func TestCollector(t *testing.T) {
+	m := redis.NewMetrics()
+	get := &redis.CmdInfo{Name: "get", Reply: []byte("value"), Duration: time.Millisecond}
+	m.AfterProcess(context.Background(), get)
+	e := errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	m.AfterProcess(context.Background(), &redis.CmdInfo{Name: "GET", Reply: e, Err: e, Duration: time.Millisecond})
+	m.AfterPipeline(context.Background(), []*redis.CmdInfo{get, get, get})
+
+	reg := prometheus.NewRegistry()
+	pool := redis.NewPool("tcp://127.0.0.1:6379", redis.PoolOptions{})
+	defer pool.Close()
+	if err := redisprom.NewCollector("test", m, pool).Register(reg); err != nil {
+		t.Fatalf("Register: %s", err.Error())
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %s", err.Error())
+	}
+	found := map[string]bool{}
+	for _, f := range families {
+		found[f.GetName()] = true
+		switch f.GetName() {
+		case "redis_command_duration_seconds":
+			if n := f.GetMetric()[0].GetHistogram().GetSampleCount(); n != 2 {
+				t.Errorf("Collector did not work properly. E:%d, R:%d", 2, n)
+			}
+		case "redis_errors_total":
+			if l := f.GetMetric()[0].GetLabel()[1].GetValue(); l != "WRONGTYPE" {
+				t.Errorf("Collector did not work properly. E:%s, R:%s", "WRONGTYPE", l)
+			}
+		case "redis_pipeline_size":
+			if s := f.GetMetric()[0].GetHistogram().GetSampleSum(); s != 3 {
+				t.Errorf("Collector did not work properly. E:%d, R:%v", 3, s)
+			}
+		}
+	}
+	for _, name := range []string{"redis_command_duration_seconds", "redis_errors_total", "redis_pipeline_size", "redis_pool_idle_connections"} {
+		if !found[name] {
+			t.Errorf("Collector did not work properly: %s is missing.", name)
+		}
+	}
+}
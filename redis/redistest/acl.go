@@ -0,0 +1,462 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// aclUser is an ACL user. The commands it may run are computed from its command rules, applied in order.
+type aclUser struct {
+	name      string
+	enabled   bool
+	nopass    bool
+	passwords map[string]bool // the SHA-256 of the passwords
+	rules     []string        // the command rules, e.g. +@all -flushall
+	allowed   map[string]bool // the commands, and the subcommands as CLIENT|ID, allowed by the rules
+	keys      []string        // the key patterns, e.g. ~app:* or %R~cache:*
+	channels  []string        // the channel patterns, e.g. &news.*
+}
+
+func newACLUser(name string) *aclUser {
+	u := &aclUser{name: name, passwords: make(map[string]bool)}
+	u.setRule("-@all")
+	return u
+}
+
+// aclCategories are the categories of the commands, the commands of read and write are those of their flags.
+var aclCategories = map[string][]string{
+	"admin":       {"ACL", "BGSAVE", "CLIENT", "CONFIG", "DEBUG", "FLUSHALL", "FLUSHDB", "INFO", "LASTSAVE", "ROLE", "SAVE", "SHUTDOWN", "SLOWLOG"},
+	"connection":  {"AUTH", "CLIENT", "ECHO", "PING", "QUIT", "SELECT"},
+	"dangerous":   {"ACL", "CLIENT", "CONFIG", "DEBUG", "FLUSHALL", "FLUSHDB", "INFO", "KEYS", "ROLE", "SAVE", "SHUTDOWN", "SLOWLOG"},
+	"pubsub":      {"PSUBSCRIBE", "PUBLISH", "PUBSUB", "PUNSUBSCRIBE", "SUBSCRIBE", "UNSUBSCRIBE"},
+	"transaction": {"DISCARD", "EXEC", "MULTI", "UNWATCH", "WATCH"},
+	"read":        nil,
+	"write":       nil,
+	"all":         nil,
+}
+
+// categoryCommands returns the commands of a category, nil if it does not exist.
+func categoryCommands(cat string) []string {
+	names, ok := aclCategories[cat]
+	if !ok {
+		return nil
+	}
+	if names != nil {
+		return names
+	}
+	names = []string{}
+	for name, cmd := range commands {
+		if cat == "all" || (cat == "read" && cmd.flags&readOnly != 0) || (cat == "write" && cmd.flags&write != 0) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hashPassword(p string) string {
+	h := sha256.Sum256([]byte(p))
+	return hex.EncodeToString(h[:])
+}
+
+// setRule applies a rule of ACL SETUSER to the user.
+func (u *aclUser) setRule(rule string) error {
+	lower := strings.ToLower(rule)
+	switch {
+	case lower == "on":
+		u.enabled = true
+	case lower == "off":
+		u.enabled = false
+	case lower == "nopass":
+		u.nopass, u.passwords = true, make(map[string]bool)
+	case lower == "resetpass":
+		u.nopass, u.passwords = false, make(map[string]bool)
+	case strings.HasPrefix(rule, ">"):
+		u.nopass = false
+		u.passwords[hashPassword(rule[1:])] = true
+	case strings.HasPrefix(rule, "<"):
+		delete(u.passwords, hashPassword(rule[1:]))
+	case strings.HasPrefix(rule, "#"):
+		if len(rule) != 65 {
+			return errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+		}
+		u.nopass = false
+		u.passwords[strings.ToLower(rule[1:])] = true
+	case strings.HasPrefix(rule, "!"):
+		delete(u.passwords, strings.ToLower(rule[1:]))
+	case lower == "allkeys":
+		u.keys = []string{"~*"}
+	case lower == "resetkeys":
+		u.keys = nil
+	case strings.HasPrefix(rule, "~"), strings.HasPrefix(lower, "%r~"), strings.HasPrefix(lower, "%w~"), strings.HasPrefix(lower, "%rw~"):
+		u.keys = append(u.keys, rule)
+	case lower == "allchannels":
+		u.channels = []string{"&*"}
+	case lower == "resetchannels":
+		u.channels = nil
+	case strings.HasPrefix(rule, "&"):
+		u.channels = append(u.channels, rule)
+	case lower == "allcommands", lower == "+@all":
+		u.rules, u.allowed = []string{"+@all"}, make(map[string]bool)
+		for name := range commands {
+			u.allowed[name] = true
+		}
+	case lower == "nocommands", lower == "-@all":
+		u.rules, u.allowed = []string{"-@all"}, make(map[string]bool)
+	case strings.HasPrefix(rule, "+@"), strings.HasPrefix(rule, "-@"):
+		names := categoryCommands(lower[2:])
+		if names == nil {
+			return fmt.Errorf("Unknown command or category name in ACL")
+		}
+		for _, name := range names {
+			u.allowed[name] = rule[0] == '+'
+		}
+		u.rules = append(u.rules, lower)
+	case strings.HasPrefix(rule, "+"), strings.HasPrefix(rule, "-"):
+		name, sub, _ := strings.Cut(strings.ToUpper(rule[1:]), "|")
+		if _, ok := commands[name]; !ok {
+			return fmt.Errorf("Unknown command or category name in ACL")
+		}
+		if sub != "" {
+			name += "|" + sub
+		}
+		u.allowed[name] = rule[0] == '+'
+		u.rules = append(u.rules, lower)
+	case lower == "reset":
+		*u = *newACLUser(u.name)
+		u.setRule("resetpass")
+	default:
+		return fmt.Errorf("Syntax error")
+	}
+	return nil
+}
+
+// checkPassword reports whether the user is enabled and the password is one of its passwords.
+func (u *aclUser) checkPassword(password string) bool {
+	return u.enabled && (u.nopass || u.passwords[hashPassword(password)])
+}
+
+// canRun returns the reason and the object of the denial of a command, e.g. "key" and the key, or empty strings.
+func (u *aclUser) canRun(name string, args []string) (reason, object string) {
+	cmd := commands[name]
+	allowed := u.allowed[name]
+	if len(args) > 1 {
+		if a, ok := u.allowed[name+"|"+strings.ToUpper(args[1])]; ok {
+			allowed = a
+		}
+	}
+	if !allowed {
+		return "command", strings.ToLower(name)
+	}
+	for _, k := range keysOf(cmd, args) {
+		if !u.canAccessKey(k, cmd.flags) {
+			return "key", k
+		}
+	}
+	switch name {
+	case "PUBLISH", "SUBSCRIBE", "PSUBSCRIBE":
+		channels := args[1:]
+		if name == "PUBLISH" {
+			channels = args[1:2]
+		}
+		for _, ch := range channels {
+			if !u.canAccessChannel(ch, name == "PSUBSCRIBE") {
+				return "channel", ch
+			}
+		}
+	}
+	return "", ""
+}
+
+func (u *aclUser) canAccessKey(key string, flags int) bool {
+	for _, p := range u.keys {
+		perm, pattern, _ := strings.Cut(strings.TrimPrefix(p, "%"), "~")
+		perm = strings.ToUpper(perm)
+		if perm == "" {
+			perm = "RW"
+		}
+		if (flags&readOnly != 0 && !strings.Contains(perm, "R")) || (flags&write != 0 && !strings.Contains(perm, "W")) {
+			continue
+		}
+		if flags&(readOnly|write) == 0 && perm != "RW" {
+			continue
+		}
+		if match(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccessChannel checks a channel, or the pattern of PSUBSCRIBE which must be one of the channel patterns.
+func (u *aclUser) canAccessChannel(ch string, pattern bool) bool {
+	for _, p := range u.channels {
+		if p == "&*" || (pattern && p[1:] == ch) || (!pattern && match(p[1:], ch)) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe is the rules of the user as listed by ACL LIST.
+func (u *aclUser) describe() string {
+	parts := []string{"user", u.name, "off"}
+	if u.enabled {
+		parts[2] = "on"
+	}
+	if u.nopass {
+		parts = append(parts, "nopass")
+	}
+	for _, h := range u.sortedPasswords() {
+		parts = append(parts, "#"+h)
+	}
+	parts = append(parts, u.keys...)
+	if len(u.channels) == 0 {
+		parts = append(parts, "resetchannels")
+	}
+	parts = append(parts, u.channels...)
+	return strings.Join(append(parts, u.rules...), " ")
+}
+
+func (u *aclUser) sortedPasswords() []string {
+	r := make([]string, 0, len(u.passwords))
+	for h := range u.passwords {
+		r = append(r, h)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// aclLogEntry is an entry of ACL LOG, the denials of the same reason, context, object and user are counted together.
+type aclLogEntry struct {
+	id       int64
+	count    int
+	reason   string
+	context  string
+	object   string
+	username string
+	client   string
+	created  time.Time
+	updated  time.Time
+}
+
+// logACL logs a denied command or a failed authentication.
+func (s *Server) logACL(c *client, reason, object, username string) {
+	context := "toplevel"
+	if c.multi != nil {
+		context = "multi"
+	}
+	now := time.Now()
+	for _, e := range s.aclLog {
+		if e.reason == reason && e.context == context && e.object == object && e.username == username {
+			e.count++
+			e.updated, e.client = now, c.info()
+			return
+		}
+	}
+	s.aclLogID++
+	e := &aclLogEntry{id: s.aclLogID, count: 1, reason: reason, context: context, object: object, username: username,
+		client: c.info(), created: now, updated: now}
+	s.aclLog = append([]*aclLogEntry{e}, s.aclLog...)
+	if len(s.aclLog) > 128 {
+		s.aclLog = s.aclLog[:128]
+	}
+}
+
+// noPerm checks the permissions of the user of a client to run a command, the denial is logged.
+func (s *Server) noPerm(c *client, name string, args []string) error {
+	reason, object := c.user.canRun(name, args)
+	switch reason {
+	case "":
+		return nil
+	case "command":
+		s.logACL(c, reason, object, c.user.name)
+		return fmt.Errorf("NOPERM User %s has no permissions to run the '%s' command", c.user.name, object)
+	case "key":
+		s.logACL(c, reason, object, c.user.name)
+		return errors.New("NOPERM No permissions to access a key")
+	default:
+		s.logACL(c, reason, object, c.user.name)
+		return errors.New("NOPERM No permissions to access a channel")
+	}
+}
+
+// aclCmd implements ACL SETUSER, GETUSER, DELUSER, LIST, USERS, WHOAMI, CAT, LOG and DRYRUN.
+func aclCmd(c *client, args []string) interface{} {
+	s := c.srv
+	switch sub := strings.ToUpper(args[0]); sub {
+	case "SETUSER":
+		if len(args) < 2 {
+			return fmt.Errorf("ERR wrong number of arguments for 'acl|setuser' command")
+		}
+		u, ok := s.users[args[1]]
+		if !ok {
+			u = newACLUser(args[1])
+		} else {
+			cp := *u
+			cp.passwords, cp.allowed = make(map[string]bool), make(map[string]bool)
+			for k, v := range u.passwords {
+				cp.passwords[k] = v
+			}
+			for k, v := range u.allowed {
+				cp.allowed[k] = v
+			}
+			cp.rules, cp.keys, cp.channels = append([]string{}, u.rules...), append([]string{}, u.keys...), append([]string{}, u.channels...)
+			u = &cp
+		}
+		for _, rule := range args[2:] {
+			if err := u.setRule(rule); err != nil {
+				return fmt.Errorf("ERR Error in ACL SETUSER modifier '%s': %s", rule, err.Error())
+			}
+		}
+		if old, ok := s.users[args[1]]; ok {
+			*old = *u
+		} else {
+			s.users[args[1]] = u
+		}
+		return status("OK")
+	case "GETUSER":
+		if len(args) != 2 {
+			return fmt.Errorf("ERR wrong number of arguments for 'acl|getuser' command")
+		}
+		u, ok := s.users[args[1]]
+		if !ok {
+			return nil
+		}
+		flags := []interface{}{"off"}
+		if u.enabled {
+			flags[0] = "on"
+		}
+		if u.nopass {
+			flags = append(flags, "nopass")
+		}
+		passwords := []interface{}{}
+		for _, h := range u.sortedPasswords() {
+			passwords = append(passwords, h)
+		}
+		return []interface{}{
+			"flags", flags,
+			"passwords", passwords,
+			"commands", strings.Join(u.rules, " "),
+			"keys", strings.Join(u.keys, " "),
+			"channels", strings.Join(u.channels, " "),
+			"selectors", []interface{}{},
+		}
+	case "DELUSER":
+		n := 0
+		for _, name := range args[1:] {
+			if name == "default" {
+				return errors.New("ERR The 'default' user cannot be removed")
+			}
+			u, ok := s.users[name]
+			if !ok {
+				continue
+			}
+			delete(s.users, name)
+			for _, cl := range s.clients {
+				if cl.user == u {
+					cl.cn.Close()
+				}
+			}
+			n++
+		}
+		return n
+	case "LIST":
+		r := []string{}
+		for _, name := range s.userNames() {
+			r = append(r, s.users[name].describe())
+		}
+		return r
+	case "USERS":
+		return s.userNames()
+	case "WHOAMI":
+		return c.user.name
+	case "CAT":
+		if len(args) == 1 {
+			cats := make([]string, 0, len(aclCategories))
+			for cat := range aclCategories {
+				cats = append(cats, cat)
+			}
+			sort.Strings(cats)
+			return cats
+		}
+		names := categoryCommands(strings.ToLower(args[1]))
+		if names == nil {
+			return fmt.Errorf("ERR Unknown category '%s'", args[1])
+		}
+		r := make([]string, len(names))
+		for i, name := range names {
+			r[i] = strings.ToLower(name)
+		}
+		return r
+	case "LOG":
+		n := 10
+		if len(args) > 1 {
+			if strings.ToUpper(args[1]) == "RESET" {
+				s.aclLog = nil
+				return status("OK")
+			}
+			count, err := parseInt(args[1])
+			if err != nil || count < 0 {
+				return errors.New("ERR value is out of range, must be positive")
+			}
+			n = int(count)
+		}
+		r := []interface{}{}
+		for _, e := range s.aclLog[:min(n, len(s.aclLog))] {
+			r = append(r, []interface{}{
+				"count", e.count,
+				"reason", e.reason,
+				"context", e.context,
+				"object", e.object,
+				"username", e.username,
+				"age-seconds", strconv.FormatFloat(time.Since(e.created).Seconds(), 'f', 3, 64),
+				"client-info", e.client,
+				"entry-id", e.id,
+				"timestamp-created", e.created.UnixMilli(),
+				"timestamp-last-updated", e.updated.UnixMilli(),
+			})
+		}
+		return r
+	case "DRYRUN":
+		if len(args) < 3 {
+			return fmt.Errorf("ERR wrong number of arguments for 'acl|dryrun' command")
+		}
+		u, ok := s.users[args[1]]
+		if !ok {
+			return fmt.Errorf("ERR User '%s' not found", args[1])
+		}
+		name := strings.ToUpper(args[2])
+		if _, ok := commands[name]; !ok {
+			return fmt.Errorf("ERR Command '%s' not found", args[2])
+		}
+		switch reason, object := u.canRun(name, args[2:]); reason {
+		case "":
+			return status("OK")
+		case "command":
+			return fmt.Sprintf("User %s has no permissions to run the '%s' command", u.name, object)
+		default:
+			return fmt.Sprintf("User %s has no permissions to access the '%s' %s", u.name, object, reason)
+		}
+	}
+	return subcommandError("ACL", args[0])
+}
+
+func (s *Server) userNames() []string {
+	names := make([]string, 0, len(s.users))
+	for name := range s.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
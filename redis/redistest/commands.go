@@ -0,0 +1,221 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"time"
+)
+
+// The flags of a command.
+const (
+	write    = 1 << iota // modifies its keys, the clients tracking or watching them are notified
+	readOnly             // reads its keys, the clients tracking them are notified when they change
+	noAuth               // allowed before AUTH
+	pubsub               // allowed in the subscribed state
+	noQueue              // executed immediately inside MULTI
+)
+
+// command is an entry of the command table. The arity counts the command name, a negative arity is a minimum.
+// The keys are the arguments first to last (-1 for the last argument) by step, the command name being 0.
+type command struct {
+	fn    func(c *client, args []string) interface{}
+	arity int
+	flags int
+	first int
+	last  int
+	step  int
+}
+
+// keysOf returns the keys of a command.
+func keysOf(cmd command, args []string) []string {
+	if cmd.first == 0 {
+		return nil
+	}
+	last := cmd.last
+	if last < 0 {
+		last += len(args)
+	}
+	var keys []string
+	for i := cmd.first; i <= last && i < len(args); i += cmd.step {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		// connection
+		"AUTH":   {auth, -2, noAuth | noQueue, 0, 0, 0},
+		"CLIENT": {clientCmd, -2, 0, 0, 0, 0},
+		"ECHO":   {echo, 2, 0, 0, 0, 0},
+		"PING":   {ping, -1, pubsub, 0, 0, 0},
+		"QUIT":   {quit, -1, noAuth | pubsub | noQueue, 0, 0, 0},
+		"SELECT": {selectCmd, 2, 0, 0, 0, 0},
+
+		// server
+		"ACL":       {aclCmd, -2, 0, 0, 0, 0},
+		"BGSAVE":    {bgSave, -1, 0, 0, 0, 0},
+		"CONFIG":    {config, -2, 0, 0, 0, 0},
+		"DBSIZE":    {dbSize, 1, 0, 0, 0, 0},
+		"DEBUG":     {debugCmd, -2, 0, 0, 0, 0},
+		"FLUSHALL":  {flushAll, -1, 0, 0, 0, 0},
+		"FLUSHDB":   {flushDB, -1, 0, 0, 0, 0},
+		"INFO":      {info, -1, 0, 0, 0, 0},
+		"LASTSAVE":  {lastSave, 1, 0, 0, 0, 0},
+		"MONITOR":   {monitor, 1, noQueue, 0, 0, 0},
+		"REPLICAOF": {replicaOf, 3, 0, 0, 0, 0},
+		"ROLE":      {role, 1, 0, 0, 0, 0},
+		"SAVE":      {save, 1, 0, 0, 0, 0},
+		"SENTINEL":  {sentinelCmd, -2, 0, 0, 0, 0},
+		"SHUTDOWN":  {shutdown, -1, noQueue, 0, 0, 0},
+		"SLOWLOG":   {slowlog, -2, 0, 0, 0, 0},
+		"TIME":      {timeCmd, 1, 0, 0, 0, 0},
+		"WAIT":      {waitCmd, 3, 0, 0, 0, 0},
+
+		// transactions
+		"DISCARD": {discard, 1, noQueue, 0, 0, 0},
+		"EXEC":    {exec, 1, noQueue, 0, 0, 0},
+		"MULTI":   {multi, 1, noQueue, 0, 0, 0},
+		"UNWATCH": {unwatch, 1, noQueue, 0, 0, 0},
+		"WATCH":   {watchCmd, -2, noQueue, 1, -1, 1},
+
+		// hyperloglog
+		"PFADD":   {pfAdd, -2, write, 1, 1, 1},
+		"PFCOUNT": {pfCount, -2, readOnly, 1, -1, 1},
+		"PFMERGE": {pfMerge, -2, write, 1, -1, 1},
+
+		// keys
+		"DEL":       {del, -2, write, 1, -1, 1},
+		"DUMP":      {dump, 2, readOnly, 1, 1, 1},
+		"EXISTS":    {exists, -2, readOnly, 1, -1, 1},
+		"EXPIRE":    {expireCmd(time.Second, false), -3, write, 1, 1, 1},
+		"EXPIREAT":  {expireCmd(time.Second, true), -3, write, 1, 1, 1},
+		"KEYS":      {keys, 2, readOnly, 0, 0, 0},
+		"MOVE":      {move, 3, write, 1, 1, 1},
+		"PERSIST":   {persist, 2, write, 1, 1, 1},
+		"PEXPIRE":   {expireCmd(time.Millisecond, false), -3, write, 1, 1, 1},
+		"PEXPIREAT": {expireCmd(time.Millisecond, true), -3, write, 1, 1, 1},
+		"PTTL":      {ttlCmd(time.Millisecond), 2, readOnly, 1, 1, 1},
+		"RANDOMKEY": {randomKey, 1, readOnly, 0, 0, 0},
+		"RENAME":    {rename, 3, write, 1, 2, 1},
+		"RENAMENX":  {renameNx, 3, write, 1, 2, 1},
+		"RESTORE":   {restore, -4, write, 1, 1, 1},
+		"SCAN":      {scan, -2, readOnly, 0, 0, 0},
+		"TTL":       {ttlCmd(time.Second), 2, readOnly, 1, 1, 1},
+		"TYPE":      {typeCmd, 2, readOnly, 1, 1, 1},
+		"UNLINK":    {del, -2, write, 1, -1, 1},
+
+		// strings
+		"APPEND":      {appendCmd, 3, write, 1, 1, 1},
+		"BITCOUNT":    {bitCount, -2, readOnly, 1, 1, 1},
+		"BITFIELD":    {bitfieldCmd(false), -2, write, 1, 1, 1},
+		"BITFIELD_RO": {bitfieldCmd(true), -2, readOnly, 1, 1, 1},
+		"BITOP":       {bitOp, -4, write, 2, -1, 1},
+		"BITPOS":      {bitPos, -3, readOnly, 1, 1, 1},
+		"DECR":        {decr, 2, write, 1, 1, 1},
+		"DECRBY":      {decrByCmd, 3, write, 1, 1, 1},
+		"GET":         {get, 2, readOnly, 1, 1, 1},
+		"GETBIT":      {getBit, 3, readOnly, 1, 1, 1},
+		"GETDEL":      {getDel, 2, write, 1, 1, 1},
+		"GETRANGE":    {getRange, 4, readOnly, 1, 1, 1},
+		"GETSET":      {getSet, 3, write, 1, 1, 1},
+		"INCR":        {incr, 2, write, 1, 1, 1},
+		"INCRBY":      {incrByCmd, 3, write, 1, 1, 1},
+		"INCRBYFLOAT": {incrByFloat, 3, write, 1, 1, 1},
+		"MGET":        {mget, -2, readOnly, 1, -1, 1},
+		"MSET":        {mset, -3, write, 1, -1, 2},
+		"MSETNX":      {msetNx, -3, write, 1, -1, 2},
+		"PSETEX":      {setExCmd("psetex", time.Millisecond), 4, write, 1, 1, 1},
+		"SET":         {set, -3, write, 1, 1, 1},
+		"SETBIT":      {setBit, 4, write, 1, 1, 1},
+		"SETEX":       {setExCmd("setex", time.Second), 4, write, 1, 1, 1},
+		"SETNX":       {setNx, 3, write, 1, 1, 1},
+		"SETRANGE":    {setRange, 4, write, 1, 1, 1},
+		"STRLEN":      {strLen, 2, readOnly, 1, 1, 1},
+		"SUBSTR":      {getRange, 4, readOnly, 1, 1, 1},
+
+		// lists
+		"LINDEX":    {lIndex, 3, readOnly, 1, 1, 1},
+		"LINSERT":   {lInsert, 5, write, 1, 1, 1},
+		"LLEN":      {lLen, 2, readOnly, 1, 1, 1},
+		"LPOP":      {popCmd(true), -2, write, 1, 1, 1},
+		"LPUSH":     {pushCmd(true, false), -3, write, 1, 1, 1},
+		"LPUSHX":    {pushCmd(true, true), -3, write, 1, 1, 1},
+		"LRANGE":    {lRange, 4, readOnly, 1, 1, 1},
+		"LREM":      {lRem, 4, write, 1, 1, 1},
+		"LSET":      {lSet, 4, write, 1, 1, 1},
+		"LTRIM":     {lTrim, 4, write, 1, 1, 1},
+		"RPOP":      {popCmd(false), -2, write, 1, 1, 1},
+		"RPOPLPUSH": {rPopLPush, 3, write, 1, 2, 1},
+		"RPUSH":     {pushCmd(false, false), -3, write, 1, 1, 1},
+		"RPUSHX":    {pushCmd(false, true), -3, write, 1, 1, 1},
+
+		// geo
+		"GEOADD":         {geoAdd, -5, write, 1, 1, 1},
+		"GEODIST":        {geoDist, -4, readOnly, 1, 1, 1},
+		"GEOHASH":        {geoHash, -2, readOnly, 1, 1, 1},
+		"GEOPOS":         {geoPos, -2, readOnly, 1, 1, 1},
+		"GEOSEARCH":      {geoSearchCmd(false), -7, readOnly, 1, 1, 1},
+		"GEOSEARCHSTORE": {geoSearchCmd(true), -8, write, 1, 2, 1},
+
+		// hashes
+		"HDEL":         {hdel, -3, write, 1, 1, 1},
+		"HEXISTS":      {hexists, 3, readOnly, 1, 1, 1},
+		"HGET":         {hget, 3, readOnly, 1, 1, 1},
+		"HGETALL":      {hgetAllCmd(true, true), 2, readOnly, 1, 1, 1},
+		"HINCRBY":      {hincrBy, 4, write, 1, 1, 1},
+		"HINCRBYFLOAT": {hincrByFloat, 4, write, 1, 1, 1},
+		"HKEYS":        {hgetAllCmd(true, false), 2, readOnly, 1, 1, 1},
+		"HLEN":         {hlen, 2, readOnly, 1, 1, 1},
+		"HMGET":        {hmget, -3, readOnly, 1, 1, 1},
+		"HMSET":        {hsetCmd("hmset"), -4, write, 1, 1, 1},
+		"HSET":         {hsetCmd("hset"), -4, write, 1, 1, 1},
+		"HSETNX":       {hsetNx, 4, write, 1, 1, 1},
+		"HSTRLEN":      {hstrLen, 3, readOnly, 1, 1, 1},
+		"HVALS":        {hgetAllCmd(false, true), 2, readOnly, 1, 1, 1},
+
+		// sets
+		"SADD":        {sadd, -3, write, 1, 1, 1},
+		"SCARD":       {scard, 2, readOnly, 1, 1, 1},
+		"SDIFF":       {combineCmd("diff"), -2, readOnly, 1, -1, 1},
+		"SDIFFSTORE":  {combineStoreCmd("diff"), -3, write, 1, -1, 1},
+		"SINTER":      {combineCmd("inter"), -2, readOnly, 1, -1, 1},
+		"SINTERSTORE": {combineStoreCmd("inter"), -3, write, 1, -1, 1},
+		"SISMEMBER":   {sisMember, 3, readOnly, 1, 1, 1},
+		"SMEMBERS":    {smembers, 2, readOnly, 1, 1, 1},
+		"SMOVE":       {smove, 4, write, 1, 2, 1},
+		"SPOP":        {spop, -2, write, 1, 1, 1},
+		"SRANDMEMBER": {srandMember, -2, readOnly, 1, 1, 1},
+		"SREM":        {srem, -3, write, 1, 1, 1},
+		"SUNION":      {combineCmd("union"), -2, readOnly, 1, -1, 1},
+		"SUNIONSTORE": {combineStoreCmd("union"), -3, write, 1, -1, 1},
+
+		// sorted sets
+		"ZADD":             {zadd, -4, write, 1, 1, 1},
+		"ZCARD":            {zcard, 2, readOnly, 1, 1, 1},
+		"ZCOUNT":           {zcount, 4, readOnly, 1, 1, 1},
+		"ZINCRBY":          {zincrBy, 4, write, 1, 1, 1},
+		"ZRANGE":           {zrange, -4, readOnly, 1, 1, 1},
+		"ZRANGEBYSCORE":    {zrangeCmd("BYSCORE"), -4, readOnly, 1, 1, 1},
+		"ZRANK":            {zrankCmd(false), 3, readOnly, 1, 1, 1},
+		"ZREM":             {zrem, -3, write, 1, 1, 1},
+		"ZREMRANGEBYRANK":  {zremRangeByRank, 4, write, 1, 1, 1},
+		"ZREMRANGEBYSCORE": {zremRangeByScore, 4, write, 1, 1, 1},
+		"ZREVRANGE":        {zrangeCmd("REV"), -4, readOnly, 1, 1, 1},
+		"ZREVRANGEBYSCORE": {zrangeCmd("BYSCORE", "REV"), -4, readOnly, 1, 1, 1},
+		"ZREVRANK":         {zrankCmd(true), 3, readOnly, 1, 1, 1},
+		"ZSCORE":           {zscore, 3, readOnly, 1, 1, 1},
+
+		// pub/sub
+		"PSUBSCRIBE":   {subscribeCmd(true), -2, pubsub, 0, 0, 0},
+		"PUBLISH":      {publish, 3, 0, 0, 0, 0},
+		"PUBSUB":       {pubsubCmd, -2, 0, 0, 0, 0},
+		"PUNSUBSCRIBE": {unsubscribeCmd(true), -1, pubsub, 0, 0, 0},
+		"SUBSCRIBE":    {subscribeCmd(false), -2, pubsub, 0, 0, 0},
+		"UNSUBSCRIBE":  {unsubscribeCmd(false), -1, pubsub, 0, 0, 0},
+	}
+}
@@ -0,0 +1,829 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const invalidateChannel = "__redis__:invalidate"
+
+func subcommandError(cmd, sub string) error {
+	return fmt.Errorf("ERR unknown subcommand '%s'. Try %s HELP.", sub, cmd)
+}
+
+func ping(c *client, args []string) interface{} {
+	if len(args) > 1 {
+		return fmt.Errorf("ERR wrong number of arguments for 'ping' command")
+	}
+	if c.subscribed() {
+		msg := ""
+		if len(args) == 1 {
+			msg = args[0]
+		}
+		return []interface{}{"pong", msg}
+	}
+	if len(args) == 1 {
+		return args[0]
+	}
+	return status("PONG")
+}
+
+func echo(c *client, args []string) interface{} {
+	return args[0]
+}
+
+func quit(c *client, args []string) interface{} {
+	return status("OK")
+}
+
+func selectCmd(c *client, args []string) interface{} {
+	db, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+	if db < 0 || db >= databases {
+		return errors.New("ERR DB index is out of range")
+	}
+	c.db = int(db)
+	return status("OK")
+}
+
+// auth implements AUTH [username] password, the failures are logged in ACL LOG.
+func auth(c *client, args []string) interface{} {
+	if len(args) > 2 {
+		return errSyntax
+	}
+	name, password := "default", args[len(args)-1]
+	if len(args) == 2 {
+		name = args[0]
+	}
+	if c.srv.users["default"].nopass && len(args) == 1 {
+		return errors.New("ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+	}
+	u := c.srv.users[name]
+	if u == nil || !u.checkPassword(password) {
+		c.srv.logACL(c, "auth", "AUTH", name)
+		return errors.New("WRONGPASS invalid username-password pair or user is disabled.")
+	}
+	c.user, c.authed = u, true
+	return status("OK")
+}
+
+// clientCmd implements CLIENT ID, CLIENT SETNAME, CLIENT GETNAME, CLIENT TRACKING, CLIENT LIST, CLIENT INFO,
+// CLIENT KILL, CLIENT NO-EVICT, CLIENT PAUSE and CLIENT UNPAUSE, which do not pause the clients.
+func clientCmd(c *client, args []string) interface{} {
+	switch sub := strings.ToUpper(args[0]); sub {
+	case "ID":
+		return c.id
+	case "LIST":
+		return clientList(c, args[1:])
+	case "INFO":
+		return c.info() + "\n"
+	case "KILL":
+		if len(args) < 2 {
+			return fmt.Errorf("ERR wrong number of arguments for 'client|kill' command")
+		}
+		return clientKill(c, args[1:])
+	case "NO-EVICT":
+		if len(args) != 2 {
+			return fmt.Errorf("ERR wrong number of arguments for 'client|no-evict' command")
+		}
+		switch strings.ToUpper(args[1]) {
+		case "ON":
+			c.noEvict = true
+		case "OFF":
+			c.noEvict = false
+		default:
+			return errSyntax
+		}
+		return status("OK")
+	case "PAUSE":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("ERR wrong number of arguments for 'client|pause' command")
+		}
+		if n, err := parseInt(args[1]); err != nil || n < 0 {
+			return errors.New("ERR timeout is not an integer or out of range")
+		}
+		if len(args) == 3 && strings.ToUpper(args[2]) != "WRITE" && strings.ToUpper(args[2]) != "ALL" {
+			return errSyntax
+		}
+		return status("OK")
+	case "UNPAUSE":
+		return status("OK")
+	case "SETNAME":
+		if len(args) != 2 {
+			return fmt.Errorf("ERR wrong number of arguments for 'client|setname' command")
+		}
+		if strings.ContainsAny(args[1], " \n") {
+			return errors.New("ERR Client names cannot contain spaces, newlines or special characters.")
+		}
+		c.name = args[1]
+		return status("OK")
+	case "GETNAME":
+		if c.name == "" {
+			return nil
+		}
+		return c.name
+	case "TRACKING":
+		if len(args) < 2 {
+			return fmt.Errorf("ERR wrong number of arguments for 'client|tracking' command")
+		}
+		return tracking(c, args[1:])
+	}
+	return subcommandError("CLIENT", args[0])
+}
+
+// info is the line of a client in the reply of CLIENT LIST.
+func (c *client) info() string {
+	flags := ""
+	if c.subscribed() {
+		flags += "P"
+	}
+	if c.multi != nil {
+		flags += "x"
+	}
+	if c.noEvict {
+		flags += "e"
+	}
+	if c.monitor {
+		flags += "O"
+	}
+	if flags == "" {
+		flags = "N"
+	}
+	multi := -1
+	if c.multi != nil {
+		multi = len(c.multi)
+	}
+	now := c.srv.now()
+	return fmt.Sprintf("id=%d addr=%s laddr=%s fd=%d name=%s age=%d idle=%d flags=%s db=%d sub=%d psub=%d multi=%d "+
+		"qbuf=0 qbuf-free=0 argv-mem=0 obl=0 oll=0 omem=0 tot-mem=0 events=r cmd=%s user=%s",
+		c.id, c.cn.RemoteAddr(), c.cn.LocalAddr(), c.id+7, c.name, int(now.Sub(c.created).Seconds()),
+		int(now.Sub(c.lastTime).Seconds()), flags, c.db, len(c.channels), len(c.patterns), multi, c.lastCmd, c.user.name)
+}
+
+// clientType is the type of a client for CLIENT LIST TYPE and CLIENT KILL TYPE.
+func (c *client) clientType() string {
+	if c.subscribed() {
+		return "pubsub"
+	}
+	return "normal"
+}
+
+func (s *Server) sortedClients() []*client {
+	ids := make([]int64, 0, len(s.clients))
+	for id := range s.clients {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	r := make([]*client, len(ids))
+	for i, id := range ids {
+		r[i] = s.clients[id]
+	}
+	return r
+}
+
+// clientList implements CLIENT LIST [TYPE type] [ID id [id ...]].
+func clientList(c *client, args []string) interface{} {
+	var typ string
+	var ids map[int64]bool
+	if len(args) > 0 {
+		switch strings.ToUpper(args[0]) {
+		case "TYPE":
+			if len(args) != 2 {
+				return errSyntax
+			}
+			typ = strings.ToLower(args[1])
+			if typ != "normal" && typ != "master" && typ != "replica" && typ != "pubsub" {
+				return fmt.Errorf("ERR Unknown client type '%s'", args[1])
+			}
+		case "ID":
+			if len(args) < 2 {
+				return errSyntax
+			}
+			ids = make(map[int64]bool)
+			for _, a := range args[1:] {
+				id, err := parseInt(a)
+				if err != nil || id <= 0 {
+					return errors.New("ERR Invalid client ID")
+				}
+				ids[id] = true
+			}
+		default:
+			return errSyntax
+		}
+	}
+	var b strings.Builder
+	for _, cl := range c.srv.sortedClients() {
+		if (typ != "" && cl.clientType() != typ) || (ids != nil && !ids[cl.id]) {
+			continue
+		}
+		b.WriteString(cl.info() + "\n")
+	}
+	return b.String()
+}
+
+// clientKill implements CLIENT KILL addr and CLIENT KILL filter value [filter value ...], the filters are
+// ID, ADDR, LADDR, USER, TYPE, SKIPME and MAXAGE.
+func clientKill(c *client, args []string) interface{} {
+	s := c.srv
+	if len(args) == 1 {
+		for _, cl := range s.clients {
+			if cl.cn.RemoteAddr().String() == args[0] {
+				cl.cn.Close()
+				return status("OK")
+			}
+		}
+		return errors.New("ERR No such client")
+	}
+	if len(args)%2 != 0 {
+		return errSyntax
+	}
+	skipMe := true
+	var filters []func(cl *client) bool
+	for i := 0; i < len(args); i += 2 {
+		v := args[i+1]
+		switch strings.ToUpper(args[i]) {
+		case "ID":
+			id, err := parseInt(v)
+			if err != nil {
+				return errors.New("ERR client-id should be greater than 0")
+			}
+			filters = append(filters, func(cl *client) bool { return cl.id == id })
+		case "ADDR":
+			filters = append(filters, func(cl *client) bool { return cl.cn.RemoteAddr().String() == v })
+		case "LADDR":
+			filters = append(filters, func(cl *client) bool { return cl.cn.LocalAddr().String() == v })
+		case "USER":
+			filters = append(filters, func(cl *client) bool { return cl.user.name == v })
+		case "TYPE":
+			typ := strings.ToLower(v)
+			filters = append(filters, func(cl *client) bool { return cl.clientType() == typ })
+		case "SKIPME":
+			switch strings.ToLower(v) {
+			case "yes":
+				skipMe = true
+			case "no":
+				skipMe = false
+			default:
+				return errSyntax
+			}
+		case "MAXAGE":
+			age, err := parseInt(v)
+			if err != nil {
+				return err
+			}
+			filters = append(filters, func(cl *client) bool { return s.now().Sub(cl.created) >= time.Duration(age)*time.Second })
+		default:
+			return errSyntax
+		}
+	}
+	n := 0
+next:
+	for _, cl := range s.sortedClients() {
+		if skipMe && cl == c {
+			continue
+		}
+		for _, f := range filters {
+			if !f(cl) {
+				continue next
+			}
+		}
+		cl.cn.Close()
+		n++
+	}
+	return n
+}
+
+// slowEntry is an entry of the slow log.
+type slowEntry struct {
+	id       int64
+	time     time.Time
+	duration time.Duration
+	args     []string
+	addr     string
+	name     string
+}
+
+// logSlow logs a command slower than slowlog-log-slower-than microseconds, a negative value disables the log.
+func (s *Server) logSlow(c *client, args []string, d time.Duration) {
+	slower, _ := strconv.ParseInt(s.config["slowlog-log-slower-than"], 10, 64)
+	if slower < 0 || d < time.Duration(slower)*time.Microsecond {
+		return
+	}
+	s.slowID++
+	e := slowEntry{id: s.slowID, time: s.now(), duration: d, args: args, addr: c.cn.RemoteAddr().String(), name: c.name}
+	s.slowlog = append([]slowEntry{e}, s.slowlog...)
+	if n, _ := strconv.Atoi(s.config["slowlog-max-len"]); len(s.slowlog) > n {
+		s.slowlog = s.slowlog[:n]
+	}
+}
+
+// slowlog implements SLOWLOG GET [count], SLOWLOG LEN and SLOWLOG RESET.
+func slowlog(c *client, args []string) interface{} {
+	s := c.srv
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		n := 10
+		if len(args) > 1 {
+			count, err := parseInt(args[1])
+			if err != nil || count < -1 {
+				return errors.New("ERR count should be greater than or equal to -1")
+			}
+			if n = int(count); n == -1 {
+				n = len(s.slowlog)
+			}
+		}
+		r := []interface{}{}
+		for _, e := range s.slowlog[:min(n, len(s.slowlog))] {
+			args := make([]interface{}, len(e.args))
+			for i, a := range e.args {
+				args[i] = a
+			}
+			r = append(r, []interface{}{e.id, e.time.Unix(), e.duration.Microseconds(), args, e.addr, e.name})
+		}
+		return r
+	case "LEN":
+		return len(s.slowlog)
+	case "RESET":
+		s.slowlog = nil
+		return status("OK")
+	}
+	return subcommandError("SLOWLOG", args[0])
+}
+
+// monitor implements MONITOR, the commands executed afterwards are published to the client as status lines.
+func monitor(c *client, args []string) interface{} {
+	c.monitor = true
+	return status("OK")
+}
+
+// feedMonitors publishes a command to the monitoring clients:
+//
+//	1339518083.107412 [0 127.0.0.1:60866] "keys" "*"
+func (s *Server) feedMonitors(c *client, args []string) {
+	var line strings.Builder
+	now := s.now()
+	fmt.Fprintf(&line, "%d.%06d [%d %s]", now.Unix(), now.Nanosecond()/1000, c.db, c.cn.RemoteAddr())
+	for _, a := range args {
+		line.WriteString(" ")
+		line.WriteString(repr(a))
+	}
+	for _, m := range s.clients {
+		if m.monitor {
+			s.pushes = append(s.pushes, push{c: m, msg: status(line.String())})
+		}
+	}
+}
+
+// repr quotes a string as sdscatrepr of Redis.
+func repr(a string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(a); i++ {
+		switch ch := a[i]; ch {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		default:
+			if ch < ' ' || ch > '~' {
+				fmt.Fprintf(&b, "\\x%02x", ch)
+			} else {
+				b.WriteByte(ch)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tracking implements CLIENT TRACKING ON|OFF [REDIRECT id] [BCAST] [PREFIX prefix ...],
+// the invalidation messages are published to the redirection client on __redis__:invalidate as in RESP2.
+func tracking(c *client, args []string) interface{} {
+	var redirect int64
+	var bcast bool
+	var prefixes []string
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "REDIRECT":
+			if i++; i == len(args) {
+				return errSyntax
+			}
+			id, err := parseInt(args[i])
+			if err != nil {
+				return err
+			}
+			if c.srv.clients[id] == nil {
+				return errors.New("ERR The client ID you want redirect to does not exist")
+			}
+			redirect = id
+		case "BCAST":
+			bcast = true
+		case "PREFIX":
+			if i++; i == len(args) {
+				return errSyntax
+			}
+			prefixes = append(prefixes, args[i])
+		default:
+			return errSyntax
+		}
+	}
+	switch strings.ToUpper(args[0]) {
+	case "ON":
+		if len(prefixes) > 0 && !bcast {
+			return errors.New("ERR PREFIX option requires BCAST mode to be enabled")
+		}
+		c.srv.untrack(c)
+		c.tracking, c.redirect, c.bcast, c.prefixes = true, redirect, bcast, prefixes
+	case "OFF":
+		c.srv.untrack(c)
+		c.tracking, c.redirect, c.bcast, c.prefixes = false, 0, false, nil
+	default:
+		return errSyntax
+	}
+	return status("OK")
+}
+
+// track remembers the keys read by a client, it is notified when they change.
+func (s *Server) track(c *client, keys ...string) {
+	for _, k := range keys {
+		if s.tracked[k] == nil {
+			s.tracked[k] = make(map[int64]bool)
+		}
+		s.tracked[k][c.id] = true
+	}
+}
+
+func (s *Server) untrack(c *client) {
+	for k, ids := range s.tracked {
+		delete(ids, c.id)
+		if len(ids) == 0 {
+			delete(s.tracked, k)
+		}
+	}
+}
+
+// invalidate notifies the clients tracking the keys which are modified, and makes EXEC fail for the clients watching them.
+func (s *Server) invalidate(keys ...string) {
+	for _, k := range keys {
+		s.versions[k]++
+		for id := range s.tracked[k] {
+			if c := s.clients[id]; c != nil && c.tracking {
+				s.notify(c, []interface{}{k})
+			}
+		}
+		delete(s.tracked, k)
+		for _, c := range s.clients {
+			if !c.bcast {
+				continue
+			}
+			for _, p := range c.prefixes {
+				if strings.HasPrefix(k, p) {
+					s.notify(c, []interface{}{k})
+					break
+				}
+			}
+			if len(c.prefixes) == 0 {
+				s.notify(c, []interface{}{k})
+			}
+		}
+	}
+}
+
+// notify publishes an invalidation message to the redirection client of a tracking client,
+// the keys are a null array when all the keys are flushed.
+func (s *Server) notify(c *client, keys interface{}) {
+	r := s.clients[c.redirect]
+	if r == nil || !r.channels[invalidateChannel] {
+		return
+	}
+	s.publish(r, "message", invalidateChannel, keys)
+}
+
+// flush removes the keys of a database, or of all the databases if db is -1.
+func (s *Server) flush(db int) {
+	for i := range s.dbs {
+		if db < 0 || db == i {
+			s.dbs[i] = make(map[string]*item)
+		}
+	}
+	s.epoch++
+	for _, c := range s.clients {
+		if c.tracking {
+			s.notify(c, nullArray{})
+		}
+	}
+	s.tracked = make(map[string]map[int64]bool)
+}
+
+func flushAll(c *client, args []string) interface{} {
+	c.srv.flush(-1)
+	return status("OK")
+}
+
+func flushDB(c *client, args []string) interface{} {
+	c.srv.flush(c.db)
+	return status("OK")
+}
+
+func dbSize(c *client, args []string) interface{} {
+	c.srv.purge(c.db)
+	return len(c.keys())
+}
+
+func save(c *client, args []string) interface{} {
+	c.srv.lastSave = c.srv.now()
+	return status("OK")
+}
+
+func bgSave(c *client, args []string) interface{} {
+	c.srv.lastSave = c.srv.now()
+	return status("Background saving started")
+}
+
+func lastSave(c *client, args []string) interface{} {
+	return c.srv.lastSave.Unix()
+}
+
+func timeCmd(c *client, args []string) interface{} {
+	now := c.srv.now()
+	return []string{strconv.FormatInt(now.Unix(), 10), strconv.Itoa(now.Nanosecond() / 1000)}
+}
+
+func role(c *client, args []string) interface{} {
+	if host, port, ok := strings.Cut(c.srv.master, ":"); ok {
+		p, _ := strconv.Atoi(port)
+		return []interface{}{"slave", host, p, "connected", 0}
+	}
+	return []interface{}{"master", 0, []interface{}{}}
+}
+
+// replicaOf implements REPLICAOF host port and REPLICAOF NO ONE. The server only reports itself as a replica
+// with a link up in ROLE and INFO, it neither replicates the data of its primary nor rejects the writes.
+func replicaOf(c *client, args []string) interface{} {
+	if strings.ToUpper(args[0]) == "NO" && strings.ToUpper(args[1]) == "ONE" {
+		c.srv.master = ""
+		return status("OK")
+	}
+	if _, err := parseInt(args[1]); err != nil {
+		return errors.New("ERR Invalid master port")
+	}
+	c.srv.master = args[0] + ":" + args[1]
+	return status("OK")
+}
+
+// sentinelCmd implements SENTINEL get-master-addr-by-name master-name, the masters are set by SetSentinelMaster.
+func sentinelCmd(c *client, args []string) interface{} {
+	if strings.ToUpper(args[0]) != "GET-MASTER-ADDR-BY-NAME" {
+		return subcommandError("SENTINEL", args[0])
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("ERR wrong number of arguments for 'sentinel|get-master-addr-by-name' command")
+	}
+	host, port, ok := strings.Cut(c.srv.sentinel[args[1]], ":")
+	if !ok {
+		return nullArray{}
+	}
+	return []string{host, port}
+}
+
+func waitCmd(c *client, args []string) interface{} {
+	return 0
+}
+
+// info implements INFO [section], the sections are server, clients, memory, persistence, replication and keyspace.
+func info(c *client, args []string) interface{} {
+	s := c.srv
+	section := "default"
+	if len(args) > 0 {
+		section = strings.ToLower(args[0])
+	}
+	var b strings.Builder
+	add := func(name string, lines ...string) {
+		if section != "default" && section != "all" && section != strings.ToLower(name) {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString("# " + name + "\r\n")
+		for _, l := range lines {
+			b.WriteString(l + "\r\n")
+		}
+	}
+	_, port, _ := strings.Cut(s.Addr(), ":")
+	add("Server",
+		"redis_version:7.2.0",
+		"redis_mode:standalone",
+		"os:"+runtime.GOOS,
+		"arch_bits:64",
+		"tcp_port:"+port,
+	)
+	add("Clients", fmt.Sprintf("connected_clients:%d", len(s.clients)), "blocked_clients:0")
+	add("Memory", "maxmemory:"+s.config["maxmemory"], "maxmemory_policy:noeviction")
+	add("Persistence", "loading:0", fmt.Sprintf("rdb_last_save_time:%d", s.lastSave.Unix()))
+	if host, port, ok := strings.Cut(s.master, ":"); ok {
+		add("Replication", "role:slave", "master_host:"+host, "master_port:"+port, "master_link_status:up",
+			"master_last_io_seconds_ago:0", "slave_read_only:1", "connected_slaves:0", "master_repl_offset:0")
+	} else {
+		add("Replication", "role:master", "connected_slaves:0", "master_repl_offset:0")
+	}
+	var dbs []string
+	for i := range s.dbs {
+		s.purge(i)
+		if n := len(s.dbs[i]); n > 0 {
+			expires := 0
+			for _, it := range s.dbs[i] {
+				if !it.expire.IsZero() {
+					expires++
+				}
+			}
+			dbs = append(dbs, fmt.Sprintf("db%d:keys=%d,expires=%d,avg_ttl=0", i, n, expires))
+		}
+	}
+	add("Keyspace", dbs...)
+	return b.String()
+}
+
+// config implements CONFIG GET pattern, CONFIG SET parameter value, CONFIG RESETSTAT and CONFIG REWRITE,
+// which does not write any file.
+func config(c *client, args []string) interface{} {
+	s := c.srv
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if len(args) != 2 {
+			return fmt.Errorf("ERR wrong number of arguments for 'config|get' command")
+		}
+		var names []string
+		for k := range s.config {
+			if match(strings.ToLower(args[1]), k) {
+				names = append(names, k)
+			}
+		}
+		sort.Strings(names)
+		r := []string{}
+		for _, k := range names {
+			r = append(r, k, s.config[k])
+		}
+		return r
+	case "SET":
+		if len(args) != 3 {
+			return fmt.Errorf("ERR wrong number of arguments for 'config|set' command")
+		}
+		k := strings.ToLower(args[1])
+		if _, ok := s.config[k]; !ok {
+			return fmt.Errorf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", args[1])
+		}
+		switch k {
+		case "databases":
+			return errors.New("ERR CONFIG SET failed (possibly related to argument 'databases') - can't set immutable config")
+		case "requirepass":
+			s.setPassword(args[2])
+		}
+		s.config[k] = args[2]
+		return status("OK")
+	case "RESETSTAT", "REWRITE":
+		return status("OK")
+	}
+	return subcommandError("CONFIG", args[0])
+}
+
+// debugCmd implements DEBUG OBJECT key, the encodings are those of a small value in Redis.
+func debugCmd(c *client, args []string) interface{} {
+	if strings.ToUpper(args[0]) != "OBJECT" {
+		return subcommandError("DEBUG", args[0])
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("ERR wrong number of arguments for 'debug|object' command")
+	}
+	it := c.get(args[1])
+	if it == nil {
+		return errors.New("ERR no such key")
+	}
+	encoding, n := "listpack", 0
+	switch it.kind {
+	case "string":
+		encoding, n = "embstr", len(it.str)
+		if _, err := parseInt(it.str); err == nil {
+			encoding = "int"
+		} else if len(it.str) > 44 {
+			encoding = "raw"
+		}
+	case "list":
+		encoding, n = "quicklist", len(it.list)
+	case "hash":
+		n = len(it.hash)
+	case "set":
+		n = len(it.set)
+	case "zset":
+		n = len(it.zset)
+	}
+	return status(fmt.Sprintf("Value at:0x0 refcount:1 encoding:%s serializedlength:%d lru:0 lru_seconds_idle:0", encoding, n))
+}
+
+// shutdown implements SHUTDOWN [NOSAVE|SAVE], the server is closed without reply.
+func shutdown(c *client, args []string) interface{} {
+	for _, a := range args {
+		switch strings.ToUpper(a) {
+		case "NOSAVE", "SAVE", "NOW", "FORCE":
+		default:
+			return errSyntax
+		}
+	}
+	return closeServer{}
+}
+
+func multi(c *client, args []string) interface{} {
+	if c.multi != nil {
+		return errors.New("ERR MULTI calls can not be nested")
+	}
+	c.multi, c.multiErr = [][]string{}, false
+	return status("OK")
+}
+
+func discard(c *client, args []string) interface{} {
+	if c.multi == nil {
+		return errors.New("ERR DISCARD without MULTI")
+	}
+	c.multi, c.watched = nil, nil
+	return status("OK")
+}
+
+// exec executes the queued commands, a null array is replied if a watched key was modified.
+func exec(c *client, args []string) interface{} {
+	if c.multi == nil {
+		return errors.New("ERR EXEC without MULTI")
+	}
+	cmds, failed, watched := c.multi, c.multiErr, c.watched
+	c.multi, c.watched = nil, nil
+	if failed {
+		return errors.New("EXECABORT Transaction discarded because of previous errors.")
+	}
+	if watched != nil && !c.srv.unchanged(watched) {
+		return nullArray{}
+	}
+	r := make([]interface{}, len(cmds))
+	for i, args := range cmds {
+		r[i] = c.srv.call(c, strings.ToUpper(args[0]), args)
+	}
+	return r
+}
+
+// watch is the versions of the keys watched by a client.
+type watch struct {
+	epoch    int64
+	versions map[string]int64
+}
+
+func (s *Server) unchanged(w *watch) bool {
+	if w.epoch != s.epoch {
+		return false
+	}
+	for k, v := range w.versions {
+		if s.versions[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func watchCmd(c *client, args []string) interface{} {
+	if c.multi != nil {
+		return errors.New("ERR WATCH inside MULTI is not allowed")
+	}
+	if c.watched == nil {
+		c.watched = &watch{epoch: c.srv.epoch, versions: make(map[string]int64)}
+	}
+	for _, k := range args {
+		// An expired key is a modified key.
+		c.get(k)
+		if _, ok := c.watched.versions[k]; !ok {
+			c.watched.versions[k] = c.srv.versions[k]
+		}
+	}
+	return status("OK")
+}
+
+func unwatch(c *client, args []string) interface{} {
+	c.watched = nil
+	return status("OK")
+}
@@ -0,0 +1,358 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// The positions are stored in sorted sets, scored by the 52 bits geohash of Redis.
+const (
+	geoLatMin   = -85.05112878
+	geoLatMax   = 85.05112878
+	geoLonMin   = -180.0
+	geoLonMax   = 180.0
+	geoStep     = 26
+	earthRadius = 6372797.560856
+)
+
+var geoUnits = map[string]float64{"m": 1, "km": 1000, "ft": 0.3048, "mi": 1609.34}
+
+var errGeoUnit = errors.New("ERR unsupported unit provided. please use M, KM, FT, MI")
+
+// spread moves the bits of x to the even positions.
+func spread(x uint32) uint64 {
+	v := uint64(x)
+	v = (v | v<<16) & 0x0000FFFF0000FFFF
+	v = (v | v<<8) & 0x00FF00FF00FF00FF
+	v = (v | v<<4) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v<<2) & 0x3333333333333333
+	v = (v | v<<1) & 0x5555555555555555
+	return v
+}
+
+// squash is the inverse of spread.
+func squash(v uint64) uint32 {
+	v &= 0x5555555555555555
+	v = (v | v>>1) & 0x3333333333333333
+	v = (v | v>>2) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v>>4) & 0x00FF00FF00FF00FF
+	v = (v | v>>8) & 0x0000FFFF0000FFFF
+	v = (v | v>>16) & 0x00000000FFFFFFFF
+	return uint32(v)
+}
+
+// geoEncode interleaves the latitude in the even bits and the longitude in the odd bits,
+// each scaled to 26 bits of its range.
+func geoEncode(lon, lat, latMin, latMax float64) uint64 {
+	ilat := uint32((lat - latMin) / (latMax - latMin) * (1 << geoStep))
+	ilon := uint32((lon - geoLonMin) / (geoLonMax - geoLonMin) * (1 << geoStep))
+	return spread(ilat) | spread(ilon)<<1
+}
+
+// geoDecode returns the center of the area of a geohash.
+func geoDecode(bits uint64) (lon, lat float64) {
+	ilat, ilon := float64(squash(bits)), float64(squash(bits>>1))
+	lat = geoLatMin + (ilat+0.5)/(1<<geoStep)*(geoLatMax-geoLatMin)
+	lon = geoLonMin + (ilon+0.5)/(1<<geoStep)*(geoLonMax-geoLonMin)
+	return math.Max(geoLonMin, math.Min(geoLonMax, lon)), math.Max(geoLatMin, math.Min(geoLatMax, lat))
+}
+
+// geoDistance is the haversine distance in meters.
+func geoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	lat1r, lat2r := lat1*math.Pi/180, lat2*math.Pi/180
+	u := math.Sin((lat2r - lat1r) / 2)
+	v := math.Sin((lon2 - lon1) * math.Pi / 180 / 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1r)*math.Cos(lat2r)*v*v))
+}
+
+func parseLonLat(lon, lat string) (float64, float64, error) {
+	x, err := parseFloat(lon)
+	if err != nil {
+		return 0, 0, err
+	}
+	y, err := parseFloat(lat)
+	if err != nil {
+		return 0, 0, err
+	}
+	if x < geoLonMin || x > geoLonMax || y < geoLatMin || y > geoLatMax {
+		return 0, 0, fmt.Errorf("ERR invalid longitude,latitude pair %f,%f", x, y)
+	}
+	return x, y, nil
+}
+
+func parseGeoUnit(unit string) (float64, error) {
+	if f, ok := geoUnits[strings.ToLower(unit)]; ok {
+		return f, nil
+	}
+	return 0, errGeoUnit
+}
+
+// geoAdd implements GEOADD key [NX|XX] [CH] longitude latitude member [...] on ZADD.
+func geoAdd(c *client, args []string) interface{} {
+	i := 1
+	for ; i < len(args); i++ {
+		if o := strings.ToUpper(args[i]); o != "NX" && o != "XX" && o != "CH" {
+			break
+		}
+	}
+	triples := args[i:]
+	if len(triples) == 0 || len(triples)%3 != 0 {
+		return errSyntax
+	}
+	zargs := append([]string{}, args[:i]...)
+	for j := 0; j < len(triples); j += 3 {
+		lon, lat, err := parseLonLat(triples[j], triples[j+1])
+		if err != nil {
+			return err
+		}
+		zargs = append(zargs, formatFloat(float64(geoEncode(lon, lat, geoLatMin, geoLatMax))), triples[j+2])
+	}
+	return zadd(c, zargs)
+}
+
+// geoPosition returns the position of a member, ok is false if it does not exist.
+func (c *client) geoPosition(key, member string) (lon, lat float64, ok bool, err error) {
+	it, err := c.getKind(key, "zset")
+	if it == nil || err != nil {
+		return 0, 0, false, err
+	}
+	score, ok := it.zset[member]
+	if !ok {
+		return 0, 0, false, nil
+	}
+	lon, lat = geoDecode(uint64(score))
+	return lon, lat, true, nil
+}
+
+func geoDist(c *client, args []string) interface{} {
+	unit := 1.0
+	if len(args) == 4 {
+		var err error
+		if unit, err = parseGeoUnit(args[3]); err != nil {
+			return err
+		}
+	} else if len(args) > 4 {
+		return errSyntax
+	}
+	lon1, lat1, ok1, err := c.geoPosition(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	lon2, lat2, ok2, _ := c.geoPosition(args[0], args[2])
+	if !ok1 || !ok2 {
+		return nil
+	}
+	return fmt.Sprintf("%.4f", geoDistance(lon1, lat1, lon2, lat2)/unit)
+}
+
+// geoHash implements GEOHASH, the standard geohash of 11 characters, encoded for latitudes of [-90, 90].
+func geoHash(c *client, args []string) interface{} {
+	const alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+	if _, err := c.getKind(args[0], "zset"); err != nil {
+		return err
+	}
+	r := []interface{}{}
+	for _, m := range args[1:] {
+		lon, lat, ok, _ := c.geoPosition(args[0], m)
+		if !ok {
+			r = append(r, nil)
+			continue
+		}
+		bits := geoEncode(lon, lat, -90, 90)
+		hash := make([]byte, 11)
+		for i := range hash {
+			idx := 0
+			if i < 10 {
+				idx = int(bits>>(52-(i+1)*5)) & 0x1f
+			}
+			hash[i] = alphabet[idx]
+		}
+		r = append(r, string(hash))
+	}
+	return r
+}
+
+func geoPos(c *client, args []string) interface{} {
+	if _, err := c.getKind(args[0], "zset"); err != nil {
+		return err
+	}
+	r := []interface{}{}
+	for _, m := range args[1:] {
+		lon, lat, ok, _ := c.geoPosition(args[0], m)
+		if !ok {
+			r = append(r, nullArray{})
+			continue
+		}
+		r = append(r, []string{formatFloat(lon), formatFloat(lat)})
+	}
+	return r
+}
+
+// geoMatch is a member found by GEOSEARCH.
+type geoMatch struct {
+	member   string
+	score    float64
+	lon, lat float64
+	dist     float64
+}
+
+// geoSearchCmd implements GEOSEARCH key FROMMEMBER member|FROMLONLAT longitude latitude
+// BYRADIUS radius unit|BYBOX width height unit [ASC|DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH],
+// and GEOSEARCHSTORE destination source ... [STOREDIST] when store is true.
+func geoSearchCmd(store bool) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		var dest string
+		if store {
+			dest, args = args[0], args[1:]
+		}
+		key := args[0]
+		var from, by int
+		var lon, lat, radius, width, height, unit float64
+		var member string
+		var fromMember, box, desc, withCoord, withDist, withHash, storeDist bool
+		count := -1
+		for i := 1; i < len(args); i++ {
+			n := len(args) - i - 1
+			var err error
+			switch o := strings.ToUpper(args[i]); {
+			case o == "FROMMEMBER" && n >= 1:
+				from++
+				fromMember, member = true, args[i+1]
+				i++
+			case o == "FROMLONLAT" && n >= 2:
+				from++
+				lon, lat, err = parseLonLat(args[i+1], args[i+2])
+				i += 2
+			case o == "BYRADIUS" && n >= 2:
+				by++
+				if radius, err = parseFloat(args[i+1]); err == nil && radius < 0 {
+					err = errors.New("ERR radius cannot be negative")
+				}
+				if err == nil {
+					unit, err = parseGeoUnit(args[i+2])
+				}
+				i += 2
+			case o == "BYBOX" && n >= 3:
+				by++
+				box = true
+				if width, err = parseFloat(args[i+1]); err == nil {
+					height, err = parseFloat(args[i+2])
+				}
+				if err == nil && (width < 0 || height < 0) {
+					err = errors.New("ERR height or width cannot be negative")
+				}
+				if err == nil {
+					unit, err = parseGeoUnit(args[i+3])
+				}
+				i += 3
+			case o == "ASC":
+				desc = false
+			case o == "DESC":
+				desc = true
+			case o == "COUNT" && n >= 1:
+				var cnt int64
+				if cnt, err = parseInt(args[i+1]); err == nil && cnt <= 0 {
+					err = errors.New("ERR COUNT must be > 0")
+				}
+				count = int(cnt)
+				i++
+				if i+1 < len(args) && strings.ToUpper(args[i+1]) == "ANY" {
+					i++
+				}
+			case o == "WITHCOORD" && !store:
+				withCoord = true
+			case o == "WITHDIST" && !store:
+				withDist = true
+			case o == "WITHHASH" && !store:
+				withHash = true
+			case o == "STOREDIST" && store:
+				storeDist = true
+			default:
+				err = errSyntax
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if from != 1 {
+			return errors.New("ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH")
+		}
+		if by != 1 {
+			return errors.New("ERR exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH")
+		}
+		it, err := c.getKind(key, "zset")
+		if err != nil {
+			return err
+		}
+		if fromMember {
+			var ok bool
+			if lon, lat, ok, _ = c.geoPosition(key, member); !ok {
+				return errors.New("ERR could not decode requested zset member")
+			}
+		}
+		var matches []geoMatch
+		if it != nil {
+			for m, score := range it.zset {
+				x, y := geoDecode(uint64(score))
+				d := geoDistance(lon, lat, x, y)
+				if !box && d > radius*unit {
+					continue
+				}
+				if box && (geoDistance(lon, lat, lon, y) > height*unit/2 || geoDistance(lon, y, x, y) > width*unit/2) {
+					continue
+				}
+				matches = append(matches, geoMatch{member: m, score: score, lon: x, lat: y, dist: d / unit})
+			}
+		}
+		sort.Slice(matches, func(i, j int) bool {
+			if matches[i].dist != matches[j].dist {
+				return matches[i].dist < matches[j].dist != desc
+			}
+			return matches[i].member < matches[j].member
+		})
+		if count >= 0 && len(matches) > count {
+			matches = matches[:count]
+		}
+		if store {
+			c.del(dest)
+			if len(matches) == 0 {
+				return 0
+			}
+			d, _ := c.create(dest, "zset")
+			for _, m := range matches {
+				if storeDist {
+					d.zset[m.member] = m.dist
+				} else {
+					d.zset[m.member] = m.score
+				}
+			}
+			return len(matches)
+		}
+		r := []interface{}{}
+		for _, m := range matches {
+			if !withCoord && !withDist && !withHash {
+				r = append(r, m.member)
+				continue
+			}
+			e := []interface{}{m.member}
+			if withDist {
+				e = append(e, fmt.Sprintf("%.4f", m.dist))
+			}
+			if withHash {
+				e = append(e, int64(m.score))
+			}
+			if withCoord {
+				e = append(e, []string{formatFloat(m.lon), formatFloat(m.lat)})
+			}
+			r = append(r, e)
+		}
+		return r
+	}
+}
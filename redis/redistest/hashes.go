@@ -0,0 +1,204 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+func sortedFields(h map[string]string) []string {
+	fields := make([]string, 0, len(h))
+	for f := range h {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// hsetCmd returns HSET, which replies the number of fields added, or HMSET, which replies OK.
+func hsetCmd(name string) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		if len(args)%2 != 1 {
+			return errors.New("ERR wrong number of arguments for '" + name + "' command")
+		}
+		it, err := c.create(args[0], "hash")
+		if err != nil {
+			return err
+		}
+		n := 0
+		for i := 1; i < len(args); i += 2 {
+			if _, ok := it.hash[args[i]]; !ok {
+				n++
+			}
+			it.hash[args[i]] = args[i+1]
+		}
+		if name == "hmset" {
+			return status("OK")
+		}
+		return n
+	}
+}
+
+func hsetNx(c *client, args []string) interface{} {
+	it, err := c.create(args[0], "hash")
+	if err != nil {
+		return err
+	}
+	if _, ok := it.hash[args[1]]; ok {
+		return 0
+	}
+	it.hash[args[1]] = args[2]
+	return 1
+}
+
+func hget(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "hash")
+	if err != nil || it == nil {
+		return err
+	}
+	if v, ok := it.hash[args[1]]; ok {
+		return v
+	}
+	return nil
+}
+
+func hmget(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "hash")
+	if err != nil {
+		return err
+	}
+	r := make([]interface{}, len(args)-1)
+	for i, f := range args[1:] {
+		if v, ok := it.hashValue(f); ok {
+			r[i] = v
+		}
+	}
+	return r
+}
+
+// hashValue returns the value of a field of a hash, the item may be nil.
+func (it *item) hashValue(field string) (string, bool) {
+	if it == nil {
+		return "", false
+	}
+	v, ok := it.hash[field]
+	return v, ok
+}
+
+func hdel(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "hash")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	n := 0
+	for _, f := range args[1:] {
+		if _, ok := it.hash[f]; ok {
+			delete(it.hash, f)
+			n++
+		}
+	}
+	c.cleanup(args[0], it)
+	return n
+}
+
+func hexists(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "hash")
+	if err != nil {
+		return err
+	}
+	if _, ok := it.hashValue(args[1]); ok {
+		return 1
+	}
+	return 0
+}
+
+func hlen(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "hash")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	return len(it.hash)
+}
+
+func hstrLen(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "hash")
+	if err != nil {
+		return err
+	}
+	v, _ := it.hashValue(args[1])
+	return len(v)
+}
+
+// hgetAllCmd returns HKEYS, HVALS or HGETALL.
+func hgetAllCmd(fields, values bool) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		it, err := c.getKind(args[0], "hash")
+		if err != nil {
+			return err
+		}
+		r := []string{}
+		if it == nil {
+			return r
+		}
+		for _, f := range sortedFields(it.hash) {
+			if fields {
+				r = append(r, f)
+			}
+			if values {
+				r = append(r, it.hash[f])
+			}
+		}
+		return r
+	}
+}
+
+func hincrBy(c *client, args []string) interface{} {
+	n, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	it, err := c.create(args[0], "hash")
+	if err != nil {
+		return err
+	}
+	v := int64(0)
+	if s, ok := it.hash[args[1]]; ok {
+		if v, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return errors.New("ERR hash value is not an integer")
+		}
+	}
+	if (n > 0 && v > math.MaxInt64-n) || (n < 0 && v < math.MinInt64-n) {
+		return errors.New("ERR increment or decrement would overflow")
+	}
+	v += n
+	it.hash[args[1]] = strconv.FormatInt(v, 10)
+	return v
+}
+
+func hincrByFloat(c *client, args []string) interface{} {
+	n, err := parseFloat(args[2])
+	if err != nil {
+		return err
+	}
+	it, err := c.create(args[0], "hash")
+	if err != nil {
+		return err
+	}
+	v := 0.0
+	if s, ok := it.hash[args[1]]; ok {
+		if v, err = parseFloat(s); err != nil {
+			return errors.New("ERR hash value is not a float")
+		}
+	}
+	v += n
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return errors.New("ERR increment would produce NaN or Infinity")
+	}
+	it.hash[args[1]] = formatFloat(v)
+	return it.hash[args[1]]
+}
@@ -0,0 +1,111 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// The HyperLogLogs are strings, as in Redis, but they hold the elements themselves, so their cardinality is exact:
+//
+//	HYLL (uvarint length, element)...
+const hllMagic = "HYLL"
+
+var errNotHLL = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+
+// getHLL returns the elements of the HyperLogLog of a key, nil if the key does not exist.
+func (c *client) getHLL(key string) (map[string]bool, error) {
+	s, ok, err := c.getString(key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if !strings.HasPrefix(s, hllMagic) {
+		return nil, errNotHLL
+	}
+	elements := make(map[string]bool)
+	p := []byte(s[len(hllMagic):])
+	for len(p) > 0 {
+		n, k := binary.Uvarint(p)
+		if k <= 0 || uint64(len(p)-k) < n {
+			return nil, errNotHLL
+		}
+		elements[string(p[k:k+int(n)])] = true
+		p = p[k+int(n):]
+	}
+	return elements, nil
+}
+
+// setHLL stores the elements as the HyperLogLog of a key, its time to live is kept.
+func (c *client) setHLL(key string, elements map[string]bool) {
+	sorted := make([]string, 0, len(elements))
+	for e := range elements {
+		sorted = append(sorted, e)
+	}
+	sort.Strings(sorted)
+	b := []byte(hllMagic)
+	for _, e := range sorted {
+		b = binary.AppendUvarint(b, uint64(len(e)))
+		b = append(b, e...)
+	}
+	it, _ := c.create(key, "string")
+	it.str = string(b)
+}
+
+func pfAdd(c *client, args []string) interface{} {
+	elements, err := c.getHLL(args[0])
+	if err != nil {
+		return err
+	}
+	changed := elements == nil
+	if elements == nil {
+		elements = make(map[string]bool)
+	}
+	for _, e := range args[1:] {
+		if !elements[e] {
+			elements[e] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return 0
+	}
+	c.setHLL(args[0], elements)
+	return 1
+}
+
+// union returns the union of the HyperLogLogs of keys.
+func (c *client) union(keys []string) (map[string]bool, error) {
+	all := make(map[string]bool)
+	for _, k := range keys {
+		elements, err := c.getHLL(k)
+		if err != nil {
+			return nil, err
+		}
+		for e := range elements {
+			all[e] = true
+		}
+	}
+	return all, nil
+}
+
+func pfCount(c *client, args []string) interface{} {
+	all, err := c.union(args)
+	if err != nil {
+		return err
+	}
+	return len(all)
+}
+
+func pfMerge(c *client, args []string) interface{} {
+	all, err := c.union(args)
+	if err != nil {
+		return err
+	}
+	c.setHLL(args[0], all)
+	return status("OK")
+}
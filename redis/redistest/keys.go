@@ -0,0 +1,608 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInt    = errors.New("ERR value is not an integer or out of range")
+	errNotFloat  = errors.New("ERR value is not a valid float")
+	errSyntax    = errors.New("ERR syntax error")
+	errNoKey     = errors.New("ERR no such key")
+	errNoAuth    = errors.New("NOAUTH Authentication required.")
+)
+
+// item is the value of a key, kind is string, list, hash, set or zset.
+type item struct {
+	kind   string
+	str    string
+	list   []string
+	hash   map[string]string
+	set    map[string]bool
+	zset   map[string]float64
+	expire time.Time
+}
+
+func newItem(kind string) *item {
+	it := &item{kind: kind}
+	switch kind {
+	case "hash":
+		it.hash = make(map[string]string)
+	case "set":
+		it.set = make(map[string]bool)
+	case "zset":
+		it.zset = make(map[string]float64)
+	}
+	return it
+}
+
+// empty reports whether an aggregate item has no element left, the empty aggregates are removed as in Redis.
+func (it *item) empty() bool {
+	switch it.kind {
+	case "list":
+		return len(it.list) == 0
+	case "hash":
+		return len(it.hash) == 0
+	case "set":
+		return len(it.set) == 0
+	case "zset":
+		return len(it.zset) == 0
+	}
+	return false
+}
+
+// lookup returns the item of a key of a database, the expired keys are removed.
+func (s *Server) lookup(db int, key string) *item {
+	it, ok := s.dbs[db][key]
+	if !ok {
+		return nil
+	}
+	if !it.expire.IsZero() && !s.now().Before(it.expire) {
+		delete(s.dbs[db], key)
+		s.invalidate(key)
+		return nil
+	}
+	return it
+}
+
+// purge removes the expired keys of a database.
+func (s *Server) purge(db int) {
+	for k := range s.dbs[db] {
+		s.lookup(db, k)
+	}
+}
+
+// get returns the item of a key of the database of the client.
+func (c *client) get(key string) *item {
+	return c.srv.lookup(c.db, key)
+}
+
+// getKind returns the item of a key if it holds a kind of value, nil if the key does not exist.
+func (c *client) getKind(key, kind string) (*item, error) {
+	it := c.get(key)
+	if it != nil && it.kind != kind {
+		return nil, errWrongType
+	}
+	return it, nil
+}
+
+// create returns the item of a key holding a kind of value, it is created if the key does not exist.
+func (c *client) create(key, kind string) (*item, error) {
+	it, err := c.getKind(key, kind)
+	if err != nil || it != nil {
+		return it, err
+	}
+	it = newItem(kind)
+	c.keys()[key] = it
+	return it, nil
+}
+
+// del removes a key, it reports whether the key existed.
+func (c *client) del(key string) bool {
+	if c.get(key) == nil {
+		return false
+	}
+	delete(c.keys(), key)
+	return true
+}
+
+// cleanup removes a key whose aggregate is empty.
+func (c *client) cleanup(key string, it *item) {
+	if it != nil && it.empty() {
+		delete(c.keys(), key)
+	}
+}
+
+func parseInt(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errNotInt
+	}
+	return n, nil
+}
+
+func parseFloat(s string) (float64, error) {
+	switch strings.ToLower(s) {
+	case "+inf", "inf":
+		return inf, nil
+	case "-inf":
+		return -inf, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != f {
+		return 0, errNotFloat
+	}
+	return f, nil
+}
+
+func formatFloat(f float64) string {
+	switch f {
+	case inf:
+		return "inf"
+	case -inf:
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func del(c *client, args []string) interface{} {
+	n := 0
+	for _, k := range args {
+		if c.del(k) {
+			n++
+		}
+	}
+	return n
+}
+
+func exists(c *client, args []string) interface{} {
+	n := 0
+	for _, k := range args {
+		if c.get(k) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// expireAt sets the expiry of a key to t, a key whose expiry is not in the future is removed.
+func expireAt(c *client, key string, t time.Time, opts []string) interface{} {
+	it := c.get(key)
+	if it == nil {
+		return 0
+	}
+	for _, o := range opts {
+		switch strings.ToUpper(o) {
+		case "NX":
+			if !it.expire.IsZero() {
+				return 0
+			}
+		case "XX":
+			if it.expire.IsZero() {
+				return 0
+			}
+		case "GT":
+			if it.expire.IsZero() || !t.After(it.expire) {
+				return 0
+			}
+		case "LT":
+			if !it.expire.IsZero() && !t.Before(it.expire) {
+				return 0
+			}
+		default:
+			return fmt.Errorf("ERR Unsupported option %s", o)
+		}
+	}
+	if !t.After(c.srv.now()) {
+		delete(c.keys(), key)
+		return 1
+	}
+	it.expire = t
+	return 1
+}
+
+// expireCmd returns EXPIRE, PEXPIRE, EXPIREAT or PEXPIREAT, unit is the unit of the time and abs tells a unix time.
+func expireCmd(unit time.Duration, abs bool) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+		var t time.Time
+		if abs {
+			t = time.Unix(0, 0).Add(time.Duration(n) * unit)
+		} else {
+			t = c.srv.now().Add(time.Duration(n) * unit)
+		}
+		return expireAt(c, args[0], t, args[2:])
+	}
+}
+
+// ttlCmd returns TTL or PTTL.
+func ttlCmd(unit time.Duration) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		it := c.get(args[0])
+		switch {
+		case it == nil:
+			return -2
+		case it.expire.IsZero():
+			return -1
+		}
+		d := it.expire.Sub(c.srv.now())
+		return int64((d + unit/2) / unit)
+	}
+}
+
+func persist(c *client, args []string) interface{} {
+	it := c.get(args[0])
+	if it == nil || it.expire.IsZero() {
+		return 0
+	}
+	it.expire = time.Time{}
+	return 1
+}
+
+// sortedKeys returns the keys of the database of the client in order.
+func (c *client) sortedKeys() []string {
+	c.srv.purge(c.db)
+	keys := make([]string, 0, len(c.keys()))
+	for k := range c.keys() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func keys(c *client, args []string) interface{} {
+	r := []string{}
+	for _, k := range c.sortedKeys() {
+		if match(args[0], k) {
+			r = append(r, k)
+		}
+	}
+	return r
+}
+
+func randomKey(c *client, args []string) interface{} {
+	keys := c.sortedKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys[rand.Intn(len(keys))]
+}
+
+func rename(c *client, args []string) interface{} {
+	if r := renameKey(c, args[0], args[1], false); r != 1 {
+		return r
+	}
+	return status("OK")
+}
+
+func renameNx(c *client, args []string) interface{} {
+	return renameKey(c, args[0], args[1], true)
+}
+
+func renameKey(c *client, key, newkey string, nx bool) interface{} {
+	it := c.get(key)
+	if it == nil {
+		return errNoKey
+	}
+	if nx && c.get(newkey) != nil {
+		return 0
+	}
+	delete(c.keys(), key)
+	c.keys()[newkey] = it
+	return 1
+}
+
+func typeCmd(c *client, args []string) interface{} {
+	it := c.get(args[0])
+	if it == nil {
+		return status("none")
+	}
+	return status(it.kind)
+}
+
+func move(c *client, args []string) interface{} {
+	db, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	if db < 0 || db >= databases {
+		return errors.New("ERR DB index is out of range")
+	}
+	if int(db) == c.db {
+		return errors.New("ERR source and destination objects are the same")
+	}
+	it := c.get(args[0])
+	if it == nil || c.srv.lookup(int(db), args[0]) != nil {
+		return 0
+	}
+	delete(c.keys(), args[0])
+	c.srv.dbs[db][args[0]] = it
+	return 1
+}
+
+func scan(c *client, args []string) interface{} {
+	cursor, err := parseInt(args[0])
+	if err != nil || cursor < 0 {
+		return errors.New("ERR invalid cursor")
+	}
+	pattern, count, kind := "*", int64(10), ""
+	for i := 1; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			return errSyntax
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			if count, err = parseInt(args[i+1]); err != nil || count < 1 {
+				return errSyntax
+			}
+		case "TYPE":
+			kind = strings.ToLower(args[i+1])
+		default:
+			return errSyntax
+		}
+	}
+	keys := c.sortedKeys()
+	r := []string{}
+	i := int(cursor)
+	for ; i < len(keys) && int64(i) < cursor+count; i++ {
+		if match(pattern, keys[i]) && (kind == "" || c.keys()[keys[i]].kind == kind) {
+			r = append(r, keys[i])
+		}
+	}
+	if i >= len(keys) {
+		i = 0
+	}
+	return []interface{}{strconv.Itoa(i), r}
+}
+
+// The DUMP payload is the kind of the value, its elements, the version and a CRC64 checksum as in Redis,
+// but the encoding of the elements is not the RDB encoding: a payload is restored by this server only.
+var (
+	dumpKinds   = []string{"string", "list", "set", "zset", "hash"}
+	dumpVersion = []byte{11, 0}
+	crcTable    = crc64.MakeTable(crc64.ECMA)
+)
+
+func dump(c *client, args []string) interface{} {
+	it := c.get(args[0])
+	if it == nil {
+		return nil
+	}
+	var b []byte
+	str := func(s string) {
+		b = binary.AppendUvarint(b, uint64(len(s)))
+		b = append(b, s...)
+	}
+	for i, k := range dumpKinds {
+		if k == it.kind {
+			b = append(b, byte(i))
+		}
+	}
+	switch it.kind {
+	case "string":
+		str(it.str)
+	case "list":
+		b = binary.AppendUvarint(b, uint64(len(it.list)))
+		for _, e := range it.list {
+			str(e)
+		}
+	case "set":
+		b = binary.AppendUvarint(b, uint64(len(it.set)))
+		for _, m := range sortedSet(it.set) {
+			str(m)
+		}
+	case "zset":
+		b = binary.AppendUvarint(b, uint64(len(it.zset)))
+		for _, m := range sortedZset(it.zset) {
+			str(m.member)
+			str(formatFloat(m.score))
+		}
+	case "hash":
+		b = binary.AppendUvarint(b, uint64(len(it.hash)))
+		for _, f := range sortedFields(it.hash) {
+			str(f)
+			str(it.hash[f])
+		}
+	}
+	b = append(b, dumpVersion...)
+	return string(binary.LittleEndian.AppendUint64(b, crc64.Checksum(b, crcTable)))
+}
+
+var errPayload = errors.New("ERR DUMP payload version or checksum are wrong")
+
+// undump decodes a DUMP payload.
+func undump(p string) (*item, error) {
+	b := []byte(p)
+	if len(b) < 11 {
+		return nil, errPayload
+	}
+	body, sum := b[:len(b)-8], b[len(b)-8:]
+	if binary.LittleEndian.Uint64(sum) != crc64.Checksum(body, crcTable) || int(body[0]) >= len(dumpKinds) {
+		return nil, errPayload
+	}
+	b = body[1 : len(body)-len(dumpVersion)]
+	bad := false
+	uvarint := func() int {
+		n, l := binary.Uvarint(b)
+		if l <= 0 || n > uint64(len(b)) {
+			bad = true
+			return 0
+		}
+		b = b[l:]
+		return int(n)
+	}
+	str := func() string {
+		n := uvarint()
+		if bad || n > len(b) {
+			bad = true
+			return ""
+		}
+		s := string(b[:n])
+		b = b[n:]
+		return s
+	}
+	it := newItem(dumpKinds[body[0]])
+	switch it.kind {
+	case "string":
+		it.str = str()
+	case "list":
+		for n := uvarint(); n > 0 && !bad; n-- {
+			it.list = append(it.list, str())
+		}
+	case "set":
+		for n := uvarint(); n > 0 && !bad; n-- {
+			it.set[str()] = true
+		}
+	case "zset":
+		for n := uvarint(); n > 0 && !bad; n-- {
+			m := str()
+			f, err := parseFloat(str())
+			if err != nil {
+				bad = true
+			}
+			it.zset[m] = f
+		}
+	case "hash":
+		for n := uvarint(); n > 0 && !bad; n-- {
+			f := str()
+			it.hash[f] = str()
+		}
+	}
+	if bad || len(b) != 0 {
+		return nil, errPayload
+	}
+	return it, nil
+}
+
+func restore(c *client, args []string) interface{} {
+	ttl, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	if ttl < 0 {
+		return errors.New("ERR Invalid TTL value, must be >= 0")
+	}
+	replace, abs := false, false
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "REPLACE":
+			replace = true
+		case "ABSTTL":
+			abs = true
+		case "IDLETIME", "FREQ":
+			if i++; i == len(args) {
+				return errSyntax
+			}
+		default:
+			return errSyntax
+		}
+	}
+	if !replace && c.get(args[0]) != nil {
+		return errors.New("BUSYKEY Target key name already exists.")
+	}
+	it, err := undump(args[2])
+	if err != nil {
+		return err
+	}
+	switch {
+	case abs && ttl > 0:
+		it.expire = time.UnixMilli(ttl)
+	case ttl > 0:
+		it.expire = c.srv.now().Add(time.Duration(ttl) * time.Millisecond)
+	}
+	c.keys()[args[0]] = it
+	if !it.expire.IsZero() && !it.expire.After(c.srv.now()) {
+		delete(c.keys(), args[0])
+	}
+	return status("OK")
+}
+
+// match reports whether a string matches a glob-style pattern of KEYS, SCAN and PSUBSCRIBE:
+// * matches any sequence, ? any character, [abc], [^a] and [a-z] a class, \ escapes a character.
+func match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if match(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			p := pattern[1:]
+			not := len(p) > 0 && p[0] == '^'
+			if not {
+				p = p[1:]
+			}
+			ok := false
+			for len(p) > 0 && p[0] != ']' {
+				switch {
+				case p[0] == '\\' && len(p) > 1:
+					ok = ok || p[1] == s[0]
+					p = p[2:]
+				case len(p) > 2 && p[1] == '-' && p[2] != ']':
+					lo, hi := p[0], p[2]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					ok = ok || (s[0] >= lo && s[0] <= hi)
+					p = p[3:]
+				default:
+					ok = ok || p[0] == s[0]
+					p = p[1:]
+				}
+			}
+			if ok == not {
+				return false
+			}
+			if len(p) > 0 {
+				p = p[1:]
+			}
+			pattern, s = p, s[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
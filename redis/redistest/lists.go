@@ -0,0 +1,248 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"errors"
+	"strings"
+)
+
+// pushCmd returns LPUSH, RPUSH, LPUSHX or RPUSHX.
+func pushCmd(left, exists bool) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		it, err := c.getKind(args[0], "list")
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			if exists {
+				return 0
+			}
+			it, _ = c.create(args[0], "list")
+		}
+		for _, v := range args[1:] {
+			if left {
+				it.list = append([]string{v}, it.list...)
+			} else {
+				it.list = append(it.list, v)
+			}
+		}
+		return len(it.list)
+	}
+}
+
+// popCmd returns LPOP or RPOP.
+func popCmd(left bool) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		count := int64(-1)
+		if len(args) > 1 {
+			n, err := parseInt(args[1])
+			if err != nil || n < 0 {
+				return errors.New("ERR value is out of range, must be positive")
+			}
+			count = n
+		}
+		it, err := c.getKind(args[0], "list")
+		if err != nil {
+			return err
+		}
+		if it == nil {
+			if count >= 0 {
+				return nullArray{}
+			}
+			return nil
+		}
+		n := int(count)
+		if count < 0 {
+			n = 1
+		}
+		if n > len(it.list) {
+			n = len(it.list)
+		}
+		r := make([]string, n)
+		for i := range r {
+			if left {
+				r[i], it.list = it.list[0], it.list[1:]
+			} else {
+				r[i], it.list = it.list[len(it.list)-1], it.list[:len(it.list)-1]
+			}
+		}
+		c.cleanup(args[0], it)
+		if count < 0 {
+			return r[0]
+		}
+		return r
+	}
+}
+
+func lLen(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "list")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	return len(it.list)
+}
+
+// orZero returns the error or 0, the reply of the commands counting the elements of a missing key.
+func orZero(err error) interface{} {
+	if err != nil {
+		return err
+	}
+	return 0
+}
+
+func lRange(c *client, args []string) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	end, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(args[0], "list")
+	if err != nil {
+		return err
+	}
+	if it == nil {
+		return []string{}
+	}
+	i, j := span(start, end, len(it.list))
+	return append([]string{}, it.list[i:j]...)
+}
+
+// index converts an index which may be negative, ok is false if it is out of range.
+func index(i int64, n int) (int, bool) {
+	if i < 0 {
+		i += int64(n)
+	}
+	return int(i), i >= 0 && i < int64(n)
+}
+
+func lIndex(c *client, args []string) interface{} {
+	i, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(args[0], "list")
+	if err != nil || it == nil {
+		return err
+	}
+	if i, ok := index(i, len(it.list)); ok {
+		return it.list[i]
+	}
+	return nil
+}
+
+func lSet(c *client, args []string) interface{} {
+	i, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(args[0], "list")
+	if err != nil {
+		return err
+	}
+	if it == nil {
+		return errNoKey
+	}
+	j, ok := index(i, len(it.list))
+	if !ok {
+		return errors.New("ERR index out of range")
+	}
+	it.list[j] = args[2]
+	return status("OK")
+}
+
+func lRem(c *client, args []string) interface{} {
+	count, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(args[0], "list")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	n := 0
+	if count >= 0 {
+		l := it.list[:0]
+		for _, e := range it.list {
+			if e == args[2] && (count == 0 || int64(n) < count) {
+				n++
+				continue
+			}
+			l = append(l, e)
+		}
+		it.list = l
+	} else {
+		for i := len(it.list) - 1; i >= 0 && int64(n) < -count; i-- {
+			if it.list[i] == args[2] {
+				it.list = append(it.list[:i], it.list[i+1:]...)
+				n++
+			}
+		}
+	}
+	c.cleanup(args[0], it)
+	return n
+}
+
+func lTrim(c *client, args []string) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	end, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(args[0], "list")
+	if err != nil {
+		return err
+	}
+	if it != nil {
+		i, j := span(start, end, len(it.list))
+		it.list = append([]string{}, it.list[i:j]...)
+		c.cleanup(args[0], it)
+	}
+	return status("OK")
+}
+
+func lInsert(c *client, args []string) interface{} {
+	where := strings.ToUpper(args[1])
+	if where != "BEFORE" && where != "AFTER" {
+		return errSyntax
+	}
+	it, err := c.getKind(args[0], "list")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	for i, e := range it.list {
+		if e != args[2] {
+			continue
+		}
+		if where == "AFTER" {
+			i++
+		}
+		it.list = append(it.list[:i], append([]string{args[3]}, it.list[i:]...)...)
+		return len(it.list)
+	}
+	return -1
+}
+
+func rPopLPush(c *client, args []string) interface{} {
+	src, err := c.getKind(args[0], "list")
+	if err != nil || src == nil {
+		return err
+	}
+	if _, err := c.getKind(args[1], "list"); err != nil {
+		return err
+	}
+	v := src.list[len(src.list)-1]
+	src.list = src.list[:len(src.list)-1]
+	c.cleanup(args[0], src)
+	dst, _ := c.create(args[1], "list")
+	dst.list = append([]string{v}, dst.list...)
+	return v
+}
@@ -0,0 +1,112 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"sort"
+	"strings"
+)
+
+// publish queues a message to a client, it is written after the reply of the command.
+func (s *Server) publish(c *client, msg ...interface{}) {
+	s.pushes = append(s.pushes, push{c: c, msg: msg})
+}
+
+func (s *Server) unsubscribe(table map[string]map[*client]bool, name string, c *client) {
+	delete(table[name], c)
+	if len(table[name]) == 0 {
+		delete(table, name)
+	}
+}
+
+// subscribeCmd returns SUBSCRIBE or PSUBSCRIBE.
+func subscribeCmd(pattern bool) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		kind, table, mine := "subscribe", c.srv.channels, &c.channels
+		if pattern {
+			kind, table, mine = "psubscribe", c.srv.patterns, &c.patterns
+		}
+		if *mine == nil {
+			*mine = make(map[string]bool)
+		}
+		r := replies{}
+		for _, name := range args {
+			(*mine)[name] = true
+			if table[name] == nil {
+				table[name] = make(map[*client]bool)
+			}
+			table[name][c] = true
+			r = append(r, []interface{}{kind, name, len(c.channels) + len(c.patterns)})
+		}
+		return r
+	}
+}
+
+// unsubscribeCmd returns UNSUBSCRIBE or PUNSUBSCRIBE, without arguments they unsubscribe from all.
+func unsubscribeCmd(pattern bool) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		kind, table, mine := "unsubscribe", c.srv.channels, c.channels
+		if pattern {
+			kind, table, mine = "punsubscribe", c.srv.patterns, c.patterns
+		}
+		names := args
+		if len(names) == 0 {
+			names = sortedSet(mine)
+		}
+		if len(names) == 0 {
+			return []interface{}{kind, nil, len(c.channels) + len(c.patterns)}
+		}
+		r := replies{}
+		for _, name := range names {
+			delete(mine, name)
+			c.srv.unsubscribe(table, name, c)
+			r = append(r, []interface{}{kind, name, len(c.channels) + len(c.patterns)})
+		}
+		return r
+	}
+}
+
+func publish(c *client, args []string) interface{} {
+	s, n := c.srv, 0
+	for sc := range s.channels[args[0]] {
+		s.publish(sc, "message", args[0], args[1])
+		n++
+	}
+	for p, subs := range s.patterns {
+		if !match(p, args[0]) {
+			continue
+		}
+		for sc := range subs {
+			s.publish(sc, "pmessage", p, args[0], args[1])
+			n++
+		}
+	}
+	return n
+}
+
+// pubsubCmd implements PUBSUB CHANNELS [pattern], PUBSUB NUMSUB [channel ...] and PUBSUB NUMPAT.
+func pubsubCmd(c *client, args []string) interface{} {
+	s := c.srv
+	switch strings.ToUpper(args[0]) {
+	case "CHANNELS":
+		r := []string{}
+		for ch := range s.channels {
+			if len(args) < 2 || match(args[1], ch) {
+				r = append(r, ch)
+			}
+		}
+		sort.Strings(r)
+		return r
+	case "NUMSUB":
+		r := []interface{}{}
+		for _, ch := range args[1:] {
+			r = append(r, ch, len(s.channels[ch]))
+		}
+		return r
+	case "NUMPAT":
+		return len(s.patterns)
+	}
+	return subcommandError("PUBSUB", args[0])
+}
@@ -0,0 +1,55 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"github.com/qqbuby/goredis/redis/server"
+)
+
+// status is a simple string reply, e.g. +OK.
+type status string
+
+// nullArray is the null array reply, *-1.
+type nullArray struct{}
+
+// replies are several replies to one command, e.g. the confirmations of SUBSCRIBE.
+type replies []interface{}
+
+// closeServer closes the server instead of replying, e.g. for SHUTDOWN.
+type closeServer struct{}
+
+// maxBulkLen is the maximum length of a string, 512MB as Redis.
+const maxBulkLen = 512 << 20
+
+// writeReply writes the reply of a command:
+//
+//	status         simple string
+//	error          error
+//	int, int64     integer
+//	string         bulk string
+//	nil            null bulk string
+//	nullArray      null array
+//	[]interface{}  array
+//	[]string       array of bulk strings
+//	replies        several replies
+func writeReply(w server.ReplyWriter, reply interface{}) {
+	switch r := reply.(type) {
+	case status:
+		w.WriteStatus(string(r))
+	case nullArray:
+		w.WriteNullArray()
+	case []interface{}:
+		w.WriteArray(len(r))
+		for _, e := range r {
+			writeReply(w, e)
+		}
+	case replies:
+		for _, e := range r {
+			writeReply(w, e)
+		}
+	default:
+		w.WriteValue(r)
+	}
+}
@@ -0,0 +1,315 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+// Package redistest provides an in-memory Redis server for the tests, listening on a random local port.
+//
+//	srv, err := redistest.NewServer()
+//	...
+//	defer srv.Close()
+//	client, err := redis.NewClient(srv.URL())
+//
+// The server implements the strings, the keys with expiry, the lists, the hashes, the sets, the sorted sets,
+// the geospatial indexes, the HyperLogLogs with exact counts, SELECT, AUTH and the ACL users, MULTI/EXEC, Pub/Sub,
+// the server commands, e.g. INFO or CONFIG, MONITOR, SENTINEL get-master-addr-by-name and the client side caching invalidation of CLIENT TRACKING.
+// The expiry follows the clock of the server, which FastForward moves forward.
+package redistest
+
+import (
+	"fmt"
+	"github.com/qqbuby/goredis/redis/server"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const databases = 16
+
+// Server is an in-memory Redis server, it is safe for concurrent use.
+type Server struct {
+	srv      *server.Server
+	ln       net.Listener
+	done     chan struct{}
+	mu       sync.Mutex
+	dbs      [databases]map[string]*item
+	offset   time.Duration
+	users    map[string]*aclUser
+	aclLog   []*aclLogEntry
+	aclLogID int64
+	config   map[string]string
+	lastSave time.Time
+	master   string            // the host:port of REPLICAOF, empty for a primary
+	sentinel map[string]string // the masters of SetSentinelMaster, name -> host:port
+	clients  map[int64]*client
+	channels map[string]map[*client]bool
+	patterns map[string]map[*client]bool
+	tracked  map[string]map[int64]bool
+	versions map[string]int64
+	epoch    int64
+	pushes   []push
+	slowlog  []slowEntry
+	slowID   int64
+}
+
+// NewServer starts a server on a random port of 127.0.0.1.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:       ln,
+		done:     make(chan struct{}),
+		config:   map[string]string{"databases": strconv.Itoa(databases), "maxmemory": "0", "requirepass": "", "slowlog-log-slower-than": "10000", "slowlog-max-len": "128"},
+		clients:  make(map[int64]*client),
+		channels: make(map[string]map[*client]bool),
+		patterns: make(map[string]map[*client]bool),
+		tracked:  make(map[string]map[int64]bool),
+		versions: make(map[string]int64),
+	}
+	for i := range s.dbs {
+		s.dbs[i] = make(map[string]*item)
+	}
+	def := newACLUser("default")
+	for _, rule := range []string{"on", "nopass", "allkeys", "allchannels", "allcommands"} {
+		def.setRule(rule)
+	}
+	s.users = map[string]*aclUser{"default": def}
+	s.lastSave = s.now()
+	s.srv = &server.Server{Handler: s, OnConnect: s.connect, OnDisconnect: s.disconnect}
+	go func() {
+		s.srv.Serve(ln)
+		close(s.done)
+	}()
+	return s, nil
+}
+
+// Addr returns the address of the server, e.g. "127.0.0.1:40503".
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// URL returns the url of the server for redis.NewClient, e.g. "tcp://127.0.0.1:40503".
+func (s *Server) URL() string {
+	return "tcp://" + s.Addr()
+}
+
+// Close stops the server and closes the connections of its clients.
+func (s *Server) Close() {
+	s.srv.Close()
+	<-s.done
+}
+
+// SetPassword requires the clients to AUTH with the password, an empty password disables the authentication.
+func (s *Server) SetPassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setPassword(password)
+}
+
+// setPassword sets the password of the default user, an empty password is nopass.
+func (s *Server) setPassword(password string) {
+	s.config["requirepass"] = password
+	def := s.users["default"]
+	def.setRule("resetpass")
+	if password == "" {
+		def.setRule("nopass")
+	} else {
+		def.setRule(">" + password)
+	}
+}
+
+// SetSentinelMaster makes the server answer as a sentinel monitoring the master name at addr, host:port,
+// an empty addr removes the master. The +switch-master events are not published, the tests PUBLISH them.
+func (s *Server) SetSentinelMaster(name, addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sentinel == nil {
+		s.sentinel = make(map[string]string)
+	}
+	if addr == "" {
+		delete(s.sentinel, name)
+	} else {
+		s.sentinel[name] = addr
+	}
+}
+
+// FastForward moves the clock of the server forward, the keys whose time to live elapses expire.
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+}
+
+// FlushAll removes the keys of all the databases.
+func (s *Server) FlushAll() {
+	s.mu.Lock()
+	s.flush(-1)
+	pushes := s.takePushes()
+	s.mu.Unlock()
+	deliver(pushes)
+}
+
+// now is the clock of the server, which FastForward moves forward.
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+func (s *Server) connect(cn *server.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	c := &client{srv: s, id: cn.ID(), cn: cn, created: now, lastTime: now, user: s.users["default"]}
+	s.clients[c.id] = c
+	cn.SetValue(c)
+}
+
+func (s *Server) disconnect(cn *server.Conn) {
+	c := cn.Value().(*client)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, c.id)
+	for ch := range c.channels {
+		s.unsubscribe(s.channels, ch, c)
+	}
+	for p := range c.patterns {
+		s.unsubscribe(s.patterns, p, c)
+	}
+	s.untrack(c)
+}
+
+// ServeRESP executes a command, the messages it publishes are written after its reply.
+func (s *Server) ServeRESP(w server.ReplyWriter, r *server.Request) {
+	c := r.Conn.Value().(*client)
+	args := make([]string, len(r.Args)+1)
+	args[0] = r.Name
+	for i, a := range r.Args {
+		args[i+1] = string(a)
+	}
+	s.mu.Lock()
+	reply := s.call(c, r.Name, args)
+	pushes := s.takePushes()
+	s.mu.Unlock()
+	if _, ok := reply.(closeServer); ok {
+		s.srv.Close()
+		return
+	}
+	writeReply(w, reply)
+	deliver(pushes)
+	if r.Name == "QUIT" {
+		r.Conn.Close()
+	}
+}
+
+// push is a message published to a client by a command.
+type push struct {
+	c   *client
+	msg interface{}
+}
+
+func (s *Server) takePushes() []push {
+	pushes := s.pushes
+	s.pushes = nil
+	return pushes
+}
+
+// deliver writes the messages published by a command, after its reply when they are published to its own client.
+func deliver(pushes []push) {
+	for _, p := range pushes {
+		msg := p.msg
+		p.c.cn.Push(func(w server.ReplyWriter) {
+			writeReply(w, msg)
+		})
+	}
+}
+
+// call checks the authentication, the arity and the context of a command, then executes it.
+func (s *Server) call(c *client, name string, args []string) interface{} {
+	cmd, ok := commands[name]
+	if !ok {
+		if c.multi != nil {
+			c.multiErr = true
+		}
+		return fmt.Errorf("ERR unknown command '%s'", args[0])
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		if c.multi != nil {
+			c.multiErr = true
+		}
+		return fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(name))
+	}
+	if !c.authed && !c.user.nopass && cmd.flags&noAuth == 0 {
+		return errNoAuth
+	}
+	if cmd.flags&noAuth == 0 {
+		if err := s.noPerm(c, name, args); err != nil {
+			if c.multi != nil {
+				c.multiErr = true
+			}
+			return err
+		}
+	}
+	if c.subscribed() && cmd.flags&pubsub == 0 {
+		return fmt.Errorf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context", strings.ToLower(name))
+	}
+	if c.multi != nil && cmd.flags&noQueue == 0 {
+		c.multi = append(c.multi, args)
+		return status("QUEUED")
+	}
+	if cmd.flags&write != 0 {
+		s.invalidate(keysOf(cmd, args)...)
+	}
+	if name != "AUTH" && name != "MONITOR" {
+		s.feedMonitors(c, args)
+	}
+	c.lastCmd, c.lastTime = strings.ToLower(name), s.now()
+	start := time.Now()
+	reply := cmd.fn(c, args[1:])
+	if name != "SLOWLOG" {
+		s.logSlow(c, args, time.Since(start))
+	}
+	if cmd.flags&readOnly != 0 && c.tracking && !c.bcast {
+		s.track(c, keysOf(cmd, args)...)
+	}
+	return reply
+}
+
+// client is the state of a connection.
+type client struct {
+	srv  *Server
+	id   int64
+	cn   *server.Conn
+	db   int
+	name string
+
+	created  time.Time
+	lastCmd  string
+	lastTime time.Time
+	noEvict  bool
+	monitor  bool
+
+	user     *aclUser
+	authed   bool
+	multi    [][]string
+	multiErr bool
+	watched  *watch
+
+	channels map[string]bool
+	patterns map[string]bool
+
+	tracking bool
+	redirect int64
+	bcast    bool
+	prefixes []string
+}
+
+func (c *client) subscribed() bool {
+	return len(c.channels)+len(c.patterns) > 0
+}
+
+// keys returns the keys of the database of the client.
+func (c *client) keys() map[string]*item {
+	return c.srv.dbs[c.db]
+}
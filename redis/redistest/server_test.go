@@ -0,0 +1,162 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest_test
+
+import (
+	"github.com/qqbuby/goredis/redis"
+	"github.com/qqbuby/goredis/redis/redistest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newClient(t *testing.T) (*redistest.Server, redis.Client) {
+	srv, err := redistest.NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %s", err.Error())
+	}
+	client, err := redis.NewClient(srv.URL())
+	if err != nil {
+		srv.Close()
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	t.Cleanup(func() {
+		client.Close()
+		srv.Close()
+	})
+	return srv, client
+}
+
+// strings converts an array reply.
+func strings(reply interface{}) []string {
+	r := []string{}
+	for _, e := range reply.([]interface{}) {
+		s, _ := redis.String(e)
+		r = append(r, s)
+	}
+	return r
+}
+
+func TestFastForward(t *testing.T) {
+	srv, client := newClient(t)
+	client.SetEx("k", 10, "v")
+	srv.FastForward(time.Second * 9)
+	if ttl, _ := client.Ttl("k"); ttl != 1 {
+		t.Errorf("FastForward did not work properly. E:%d, R:%d", 1, ttl)
+	}
+	srv.FastForward(time.Second)
+	if v, _ := client.Get("k"); v != nil {
+		t.Errorf("FastForward did not work properly. E:%v, R:%v", nil, v)
+	}
+}
+
+func TestDataTypes(t *testing.T) {
+	_, client := newClient(t)
+	cases := []struct {
+		cmd   []interface{}
+		reply interface{}
+	}{
+		{[]interface{}{"RPUSH", "l", "a", "b", "c"}, "3"},
+		{[]interface{}{"LPOP", "l"}, "a"},
+		{[]interface{}{"LRANGE", "l", 0, -1}, []string{"b", "c"}},
+		{[]interface{}{"HSET", "h", "f1", "v1", "f2", "v2"}, "2"},
+		{[]interface{}{"HGETALL", "h"}, []string{"f1", "v1", "f2", "v2"}},
+		{[]interface{}{"SADD", "s", "m1", "m2", "m1"}, "2"},
+		{[]interface{}{"SMEMBERS", "s"}, []string{"m1", "m2"}},
+		{[]interface{}{"ZADD", "z", 2, "two", 1, "one"}, "2"},
+		{[]interface{}{"ZRANGE", "z", 0, -1, "WITHSCORES"}, []string{"one", "1", "two", "2"}},
+		{[]interface{}{"ZRANGEBYSCORE", "z", "(1", "+inf"}, []string{"two"}},
+		{[]interface{}{"GET", "l"}, "WRONGTYPE Operation against a key holding the wrong kind of value"},
+	}
+	for _, c := range cases {
+		rsp, err := client.Send(c.cmd[0].(string), c.cmd[1:]...)
+		if err != nil {
+			t.Fatalf("%v: %s", c.cmd, err.Error())
+		}
+		var r interface{}
+		switch rsp := rsp.(type) {
+		case []interface{}:
+			r = strings(rsp)
+		case error:
+			r = rsp.Error()
+		default:
+			r, _ = redis.String(rsp)
+		}
+		if !reflect.DeepEqual(r, c.reply) {
+			t.Errorf("%v did not work properly. E:%v, R:%v", c.cmd, c.reply, r)
+		}
+	}
+}
+
+func TestDumpRestore(t *testing.T) {
+	_, client := newClient(t)
+	client.Send("HSET", "h", "f", "v")
+	serial, _ := client.Dump("h")
+	if s, _ := client.Restore("h2", 0, serial, false); s != "OK" {
+		t.Errorf("Restore did not work properly. E:%s, R:%s", "OK", s)
+	}
+	v, _ := client.Send("HGET", "h2", "f")
+	if s, _ := redis.String(v); s != "v" {
+		t.Errorf("Restore did not work properly. E:%s, R:%s", "v", s)
+	}
+	if _, err := client.Restore("h3", 0, "payload", false); err == nil {
+		t.Error("Restore did not work properly: invalid payload.")
+	}
+}
+
+func TestPassword(t *testing.T) {
+	srv, client := newClient(t)
+	srv.SetPassword("secret")
+	if _, err := client.Ping(); err == nil {
+		t.Error("SetPassword did not work properly: no AUTH.")
+	}
+	if s, _ := client.Auth("secret"); s != "OK" {
+		t.Errorf("SetPassword did not work properly. E:%s, R:%s", "OK", s)
+	}
+	if s, _ := client.Ping(); s != "PONG" {
+		t.Errorf("SetPassword did not work properly. E:%s, R:%s", "PONG", s)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	srv, client := newClient(t)
+	other, _ := redis.NewClient(srv.URL())
+	defer other.Close()
+
+	client.Send("MULTI")
+	client.Send("INCR", "n")
+	client.Send("INCR", "n")
+	rsp, _ := client.Send("EXEC")
+	if r := strings(rsp); !reflect.DeepEqual(r, []string{"1", "2"}) {
+		t.Errorf("EXEC did not work properly. E:%v, R:%v", []string{"1", "2"}, r)
+	}
+
+	client.Send("WATCH", "n")
+	other.Incr("n")
+	client.Send("MULTI")
+	client.Send("INCR", "n")
+	if rsp, _ := client.Send("EXEC"); rsp != nil {
+		t.Errorf("WATCH did not work properly. E:%v, R:%v", nil, rsp)
+	}
+}
+
+func TestPSubscribe(t *testing.T) {
+	srv, client := newClient(t)
+	sc, err := redis.NewPubSub(srv.URL())
+	if err != nil {
+		t.Fatalf("NewPubSub: %s", err.Error())
+	}
+	defer sc.Close()
+
+	sc.PSubscribe("news.*")
+	sc.Receive()
+	if n, _ := client.Publish("news.tech", "Hi"); n != 1 {
+		t.Errorf("PSubscribe did not work properly. E:%d, R:%d", 1, n)
+	}
+	m := sc.Receive()
+	if !reflect.DeepEqual(m, redis.PMessage{Pattern: "news.*", Channel: "news.tech", Text: "Hi"}) {
+		t.Errorf("PSubscribe did not work properly. R:%#v", m)
+	}
+}
@@ -0,0 +1,231 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"math/rand"
+	"sort"
+)
+
+func sortedSet(set map[string]bool) []string {
+	members := make([]string, 0, len(set))
+	for m := range set {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func sadd(c *client, args []string) interface{} {
+	it, err := c.create(args[0], "set")
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, m := range args[1:] {
+		if !it.set[m] {
+			it.set[m] = true
+			n++
+		}
+	}
+	return n
+}
+
+func srem(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "set")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	n := 0
+	for _, m := range args[1:] {
+		if it.set[m] {
+			delete(it.set, m)
+			n++
+		}
+	}
+	c.cleanup(args[0], it)
+	return n
+}
+
+func smembers(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "set")
+	if err != nil {
+		return err
+	}
+	if it == nil {
+		return []string{}
+	}
+	return sortedSet(it.set)
+}
+
+func sisMember(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "set")
+	if err != nil {
+		return err
+	}
+	if it != nil && it.set[args[1]] {
+		return 1
+	}
+	return 0
+}
+
+func scard(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "set")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	return len(it.set)
+}
+
+// randomMembers returns n distinct random members, or -n members which may repeat if n is negative.
+func randomMembers(set map[string]bool, n int64) []string {
+	members := sortedSet(set)
+	if n < 0 {
+		r := make([]string, -n)
+		for i := range r {
+			r[i] = members[rand.Intn(len(members))]
+		}
+		return r
+	}
+	rand.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+	if n < int64(len(members)) {
+		members = members[:n]
+	}
+	return members
+}
+
+func spop(c *client, args []string) interface{} {
+	count := int64(1)
+	if len(args) > 1 {
+		n, err := parseInt(args[1])
+		if err != nil || n < 0 {
+			return errNotInt
+		}
+		count = n
+	}
+	it, err := c.getKind(args[0], "set")
+	if err != nil {
+		return err
+	}
+	if it == nil {
+		if len(args) > 1 {
+			return []string{}
+		}
+		return nil
+	}
+	r := randomMembers(it.set, count)
+	for _, m := range r {
+		delete(it.set, m)
+	}
+	c.cleanup(args[0], it)
+	if len(args) > 1 {
+		return r
+	}
+	return r[0]
+}
+
+func srandMember(c *client, args []string) interface{} {
+	count := int64(1)
+	if len(args) > 1 {
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+		count = n
+	}
+	it, err := c.getKind(args[0], "set")
+	if err != nil {
+		return err
+	}
+	if it == nil {
+		if len(args) > 1 {
+			return []string{}
+		}
+		return nil
+	}
+	r := randomMembers(it.set, count)
+	if len(args) > 1 {
+		return r
+	}
+	return r[0]
+}
+
+func smove(c *client, args []string) interface{} {
+	src, err := c.getKind(args[0], "set")
+	if err != nil {
+		return err
+	}
+	if _, err := c.getKind(args[1], "set"); err != nil {
+		return err
+	}
+	if src == nil || !src.set[args[2]] {
+		return 0
+	}
+	delete(src.set, args[2])
+	c.cleanup(args[0], src)
+	dst, _ := c.create(args[1], "set")
+	dst.set[args[2]] = true
+	return 1
+}
+
+// combine returns the intersection, the union or the difference of the sets of the keys.
+func combine(c *client, op string, keys []string) (map[string]bool, error) {
+	var r map[string]bool
+	for i, k := range keys {
+		it, err := c.getKind(k, "set")
+		if err != nil {
+			return nil, err
+		}
+		var set map[string]bool
+		if it != nil {
+			set = it.set
+		}
+		if i == 0 {
+			r = make(map[string]bool, len(set))
+			for m := range set {
+				r[m] = true
+			}
+			continue
+		}
+		for m := range r {
+			if op == "inter" && !set[m] || op == "diff" && set[m] {
+				delete(r, m)
+			}
+		}
+		if op == "union" {
+			for m := range set {
+				r[m] = true
+			}
+		}
+	}
+	return r, nil
+}
+
+// combineCmd returns SINTER, SUNION or SDIFF.
+func combineCmd(op string) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		r, err := combine(c, op, args)
+		if err != nil {
+			return err
+		}
+		return sortedSet(r)
+	}
+}
+
+// combineStoreCmd returns SINTERSTORE, SUNIONSTORE or SDIFFSTORE.
+func combineStoreCmd(op string) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		r, err := combine(c, op, args[1:])
+		if err != nil {
+			return err
+		}
+		delete(c.keys(), args[0])
+		if len(r) > 0 {
+			it, _ := c.create(args[0], "set")
+			it.set = r
+		}
+		return len(r)
+	}
+}
@@ -0,0 +1,696 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"strings"
+	"time"
+)
+
+var inf = math.Inf(1)
+
+// getString returns the value of a string key, ok is false if the key does not exist.
+func (c *client) getString(key string) (s string, ok bool, err error) {
+	it, err := c.getKind(key, "string")
+	if it == nil || err != nil {
+		return "", false, err
+	}
+	return it.str, true, nil
+}
+
+// setString sets the value of a key, its time to live is discarded.
+func (c *client) setString(key, value string) *item {
+	it := &item{kind: "string", str: value}
+	c.keys()[key] = it
+	return it
+}
+
+func get(c *client, args []string) interface{} {
+	s, ok, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	return s
+}
+
+func set(c *client, args []string) interface{} {
+	key, value := args[0], args[1]
+	var nx, xx, getOld, keepTTL bool
+	var expire time.Time
+	for i := 2; i < len(args); i++ {
+		switch o := strings.ToUpper(args[i]); o {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GET":
+			getOld = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if i++; i == len(args) || !expire.IsZero() {
+				return errSyntax
+			}
+			n, err := parseInt(args[i])
+			if err != nil {
+				return err
+			}
+			if n <= 0 {
+				return errors.New("ERR invalid expire time in 'set' command")
+			}
+			switch o {
+			case "EX":
+				expire = c.srv.now().Add(time.Duration(n) * time.Second)
+			case "PX":
+				expire = c.srv.now().Add(time.Duration(n) * time.Millisecond)
+			case "EXAT":
+				expire = time.Unix(n, 0)
+			case "PXAT":
+				expire = time.UnixMilli(n)
+			}
+		default:
+			return errSyntax
+		}
+	}
+	if (nx && xx) || (keepTTL && !expire.IsZero()) {
+		return errSyntax
+	}
+	it := c.get(key)
+	var old interface{}
+	if getOld && it != nil {
+		if it.kind != "string" {
+			return errWrongType
+		}
+		old = it.str
+	}
+	if (nx && it != nil) || (xx && it == nil) {
+		if getOld {
+			return old
+		}
+		return nil
+	}
+	n := c.setString(key, value)
+	if keepTTL && it != nil {
+		n.expire = it.expire
+	} else {
+		n.expire = expire
+	}
+	if !n.expire.IsZero() && !n.expire.After(c.srv.now()) {
+		delete(c.keys(), key)
+	}
+	if getOld {
+		return old
+	}
+	return status("OK")
+}
+
+func setNx(c *client, args []string) interface{} {
+	if c.get(args[0]) != nil {
+		return 0
+	}
+	c.setString(args[0], args[1])
+	return 1
+}
+
+// setExCmd returns SETEX or PSETEX.
+func setExCmd(name string, unit time.Duration) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return fmt.Errorf("ERR invalid expire time in '%s' command", name)
+		}
+		c.setString(args[0], args[2]).expire = c.srv.now().Add(time.Duration(n) * unit)
+		return status("OK")
+	}
+}
+
+func getSet(c *client, args []string) interface{} {
+	old := get(c, args[:1])
+	if _, ok := old.(error); ok {
+		return old
+	}
+	c.setString(args[0], args[1])
+	return old
+}
+
+func getDel(c *client, args []string) interface{} {
+	old := get(c, args)
+	if s, ok := old.(string); ok {
+		c.del(args[0])
+		return s
+	}
+	return old
+}
+
+func mget(c *client, args []string) interface{} {
+	r := make([]interface{}, len(args))
+	for i, k := range args {
+		if s, ok, _ := c.getString(k); ok {
+			r[i] = s
+		}
+	}
+	return r
+}
+
+func mset(c *client, args []string) interface{} {
+	if len(args)%2 != 0 {
+		return errors.New("ERR wrong number of arguments for 'mset' command")
+	}
+	for i := 0; i < len(args); i += 2 {
+		c.setString(args[i], args[i+1])
+	}
+	return status("OK")
+}
+
+func msetNx(c *client, args []string) interface{} {
+	if len(args)%2 != 0 {
+		return errors.New("ERR wrong number of arguments for 'msetnx' command")
+	}
+	for i := 0; i < len(args); i += 2 {
+		if c.get(args[i]) != nil {
+			return 0
+		}
+	}
+	for i := 0; i < len(args); i += 2 {
+		c.setString(args[i], args[i+1])
+	}
+	return 1
+}
+
+func appendCmd(c *client, args []string) interface{} {
+	it, err := c.create(args[0], "string")
+	if err != nil {
+		return err
+	}
+	it.str += args[1]
+	return len(it.str)
+}
+
+func strLen(c *client, args []string) interface{} {
+	s, _, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	return len(s)
+}
+
+// incrBy adds n to the integer value of a key.
+func incrBy(c *client, key string, n int64) interface{} {
+	it, err := c.create(key, "string")
+	if err != nil {
+		return err
+	}
+	v := int64(0)
+	if it.str != "" {
+		if v, err = parseInt(it.str); err != nil {
+			return err
+		}
+	}
+	if (n > 0 && v > math.MaxInt64-n) || (n < 0 && v < math.MinInt64-n) {
+		return errors.New("ERR increment or decrement would overflow")
+	}
+	v += n
+	it.str = fmt.Sprint(v)
+	return v
+}
+
+func incr(c *client, args []string) interface{} {
+	return incrBy(c, args[0], 1)
+}
+
+func decr(c *client, args []string) interface{} {
+	return incrBy(c, args[0], -1)
+}
+
+func incrByCmd(c *client, args []string) interface{} {
+	n, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	return incrBy(c, args[0], n)
+}
+
+func decrByCmd(c *client, args []string) interface{} {
+	n, err := parseInt(args[1])
+	if err != nil || n == math.MinInt64 {
+		return errNotInt
+	}
+	return incrBy(c, args[0], -n)
+}
+
+func incrByFloat(c *client, args []string) interface{} {
+	n, err := parseFloat(args[1])
+	if err != nil {
+		return err
+	}
+	it, err := c.create(args[0], "string")
+	if err != nil {
+		return err
+	}
+	v := 0.0
+	if it.str != "" {
+		if v, err = parseFloat(it.str); err != nil {
+			return err
+		}
+	}
+	v += n
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return errors.New("ERR increment would produce NaN or Infinity")
+	}
+	it.str = formatFloat(v)
+	return it.str
+}
+
+// span converts the start and the end of a range of length n, which may be negative, into [start, end).
+func span(start, end int64, n int) (int, int) {
+	if start < 0 {
+		start += int64(n)
+	}
+	if end < 0 {
+		end += int64(n)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end >= int64(n) {
+		end = int64(n) - 1
+	}
+	if start > end || n == 0 {
+		return 0, 0
+	}
+	return int(start), int(end) + 1
+}
+
+func getRange(c *client, args []string) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	end, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	s, _, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	i, j := span(start, end, len(s))
+	return s[i:j]
+}
+
+func setRange(c *client, args []string) interface{} {
+	offset, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	if offset < 0 || offset+int64(len(args[2])) > maxBulkLen {
+		return errors.New("ERR offset is out of range")
+	}
+	s, ok, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	if !ok && args[2] == "" {
+		return 0
+	}
+	b := []byte(s)
+	if n := int(offset) + len(args[2]); n > len(b) {
+		b = append(b, make([]byte, n-len(b))...)
+	}
+	copy(b[offset:], args[2])
+	it, _ := c.create(args[0], "string")
+	it.str = string(b)
+	return len(b)
+}
+
+func getBit(c *client, args []string) interface{} {
+	offset, err := parseInt(args[1])
+	if err != nil || offset < 0 || offset >= maxBulkLen*8 {
+		return errors.New("ERR bit offset is not an integer or out of range")
+	}
+	s, _, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	if int(offset/8) >= len(s) {
+		return 0
+	}
+	return int(s[offset/8]>>(7-offset%8)) & 1
+}
+
+func setBit(c *client, args []string) interface{} {
+	offset, err := parseInt(args[1])
+	if err != nil || offset < 0 || offset >= maxBulkLen*8 {
+		return errors.New("ERR bit offset is not an integer or out of range")
+	}
+	if args[2] != "0" && args[2] != "1" {
+		return errors.New("ERR bit is not an integer or out of range")
+	}
+	it, err := c.create(args[0], "string")
+	if err != nil {
+		return err
+	}
+	b := []byte(it.str)
+	if n := int(offset/8) + 1; n > len(b) {
+		b = append(b, make([]byte, n-len(b))...)
+	}
+	mask := byte(1) << (7 - offset%8)
+	old := 0
+	if b[offset/8]&mask != 0 {
+		old = 1
+	}
+	if args[2] == "1" {
+		b[offset/8] |= mask
+	} else {
+		b[offset/8] &^= mask
+	}
+	it.str = string(b)
+	return old
+}
+
+// bitRange parses the [start end [BYTE|BIT]] range of BITCOUNT and BITPOS into a range of bits [start, end),
+// the end is -1 when it is not given.
+func bitRange(args []string, n int) (start, end int, endGiven bool, err error) {
+	unit := "BYTE"
+	if len(args) > 2 {
+		unit = strings.ToUpper(args[2])
+		if len(args) > 3 || (unit != "BYTE" && unit != "BIT") {
+			return 0, 0, false, errSyntax
+		}
+	}
+	size := n
+	if unit == "BIT" {
+		size = n * 8
+	}
+	s, e := int64(0), int64(size-1)
+	if len(args) > 0 {
+		if s, err = parseInt(args[0]); err != nil {
+			return
+		}
+	}
+	if len(args) > 1 {
+		if e, err = parseInt(args[1]); err != nil {
+			return
+		}
+		endGiven = true
+	}
+	start, end = span(s, e, size)
+	if unit == "BYTE" {
+		start, end = start*8, end*8
+	}
+	return start, end, endGiven, nil
+}
+
+func bitCount(c *client, args []string) interface{} {
+	if len(args) == 2 {
+		return errSyntax
+	}
+	s, _, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	start, end, _, err := bitRange(args[1:], len(s))
+	if err != nil {
+		return err
+	}
+	n := 0
+	for i := start; i < end; i++ {
+		if i%8 == 0 && i+8 <= end {
+			n += bits.OnesCount8(s[i/8])
+			i += 7
+			continue
+		}
+		n += int(s[i/8]>>(7-i%8)) & 1
+	}
+	return n
+}
+
+func bitPos(c *client, args []string) interface{} {
+	if args[1] != "0" && args[1] != "1" {
+		return errors.New("ERR The bit argument must be 1 or 0.")
+	}
+	bit := args[1][0] - '0'
+	s, ok, err := c.getString(args[0])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if bit == 1 {
+			return -1
+		}
+		return 0
+	}
+	start, end, endGiven, err := bitRange(args[2:], len(s))
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return -1
+	}
+	for i := start; i < end; i++ {
+		if (s[i/8]>>(7-i%8))&1 == bit {
+			return i
+		}
+	}
+	// The string is padded with zeros on the right when looking for a clear bit without an end.
+	if bit == 0 && !endGiven {
+		return end
+	}
+	return -1
+}
+
+func bitOp(c *client, args []string) interface{} {
+	op := strings.ToUpper(args[0])
+	if op != "AND" && op != "OR" && op != "XOR" && op != "NOT" {
+		return errSyntax
+	}
+	if op == "NOT" && len(args) != 3 {
+		return errors.New("ERR BITOP NOT must be called with a single source key.")
+	}
+	var srcs []string
+	n := 0
+	for _, k := range args[2:] {
+		s, _, err := c.getString(k)
+		if err != nil {
+			return err
+		}
+		srcs = append(srcs, s)
+		if len(s) > n {
+			n = len(s)
+		}
+	}
+	r := make([]byte, n)
+	for i := range r {
+		at := func(s string) byte {
+			if i < len(s) {
+				return s[i]
+			}
+			return 0
+		}
+		b := at(srcs[0])
+		for _, s := range srcs[1:] {
+			switch op {
+			case "AND":
+				b &= at(s)
+			case "OR":
+				b |= at(s)
+			case "XOR":
+				b ^= at(s)
+			}
+		}
+		if op == "NOT" {
+			b = ^b
+		}
+		r[i] = b
+	}
+	if n == 0 {
+		c.del(args[1])
+		return 0
+	}
+	c.setString(args[1], string(r))
+	return n
+}
+
+// bitfieldOp is an operation of BITFIELD.
+type bitfieldOp struct {
+	op       string
+	signed   bool
+	width    int
+	offset   int64
+	value    int64
+	overflow string
+}
+
+// parseBitfield parses the operations of BITFIELD key [GET encoding offset] [SET encoding offset value]
+// [INCRBY encoding offset increment] [OVERFLOW WRAP|SAT|FAIL].
+func parseBitfield(args []string) ([]bitfieldOp, error) {
+	var ops []bitfieldOp
+	overflow := "WRAP"
+	for i := 0; i < len(args); i++ {
+		op := strings.ToUpper(args[i])
+		n := len(args) - i - 1
+		if op == "OVERFLOW" && n >= 1 {
+			overflow = strings.ToUpper(args[i+1])
+			if overflow != "WRAP" && overflow != "SAT" && overflow != "FAIL" {
+				return nil, errors.New("ERR Invalid OVERFLOW type specified")
+			}
+			i++
+			continue
+		}
+		if !(op == "GET" && n >= 2) && !((op == "SET" || op == "INCRBY") && n >= 3) {
+			return nil, errSyntax
+		}
+		o := bitfieldOp{op: op, overflow: overflow}
+		enc := args[i+1]
+		width, err := parseInt(enc[min(1, len(enc)):])
+		if o.signed = strings.HasPrefix(enc, "i"); err != nil || !(o.signed || strings.HasPrefix(enc, "u")) ||
+			width < 1 || (o.signed && width > 64) || (!o.signed && width > 63) {
+			return nil, errors.New("ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+		}
+		o.width = int(width)
+		off := args[i+2]
+		scale := int64(1)
+		if strings.HasPrefix(off, "#") {
+			off, scale = off[1:], width
+		}
+		o.offset, err = parseInt(off)
+		if o.offset *= scale; err != nil || o.offset < 0 || o.offset+width > maxBulkLen*8 {
+			return nil, errors.New("ERR bit offset is not an integer or out of range")
+		}
+		i += 2
+		if op != "GET" {
+			if o.value, err = parseInt(args[i+1]); err != nil {
+				return nil, err
+			}
+			i++
+		}
+		ops = append(ops, o)
+	}
+	return ops, nil
+}
+
+// bitfieldGet reads an integer of width bits at a bit offset of b, the bits beyond b are zeros.
+func bitfieldGet(b []byte, offset int64, width int, signed bool) int64 {
+	var u uint64
+	for i := int64(0); i < int64(width); i++ {
+		bit := uint64(0)
+		if p := offset + i; p/8 < int64(len(b)) {
+			bit = uint64(b[p/8]>>(7-p%8)) & 1
+		}
+		u = u<<1 | bit
+	}
+	if signed && width < 64 && u>>(width-1) == 1 {
+		return int64(u) - int64(1)<<width
+	}
+	return int64(u)
+}
+
+// bitfieldSet writes the low width bits of v at a bit offset of b, which is extended as needed.
+func bitfieldSet(b []byte, offset int64, width int, v int64) []byte {
+	if n := int((offset + int64(width) + 7) / 8); n > len(b) {
+		b = append(b, make([]byte, n-len(b))...)
+	}
+	for i := int64(0); i < int64(width); i++ {
+		p := offset + i
+		mask := byte(1) << (7 - p%8)
+		if uint64(v)>>(int64(width)-1-i)&1 == 1 {
+			b[p/8] |= mask
+		} else {
+			b[p/8] &^= mask
+		}
+	}
+	return b
+}
+
+// bitfieldOverflow fits v into the range of the encoding by the overflow behavior, ok is false on FAIL.
+func bitfieldOverflow(v *big.Int, width int, signed bool, overflow string) (int64, bool) {
+	lo, hi := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), uint(width))
+	hi.Sub(hi, big.NewInt(1))
+	if signed {
+		lo.Lsh(big.NewInt(-1), uint(width-1))
+		hi.Rsh(hi, 1)
+	}
+	if v.Cmp(lo) >= 0 && v.Cmp(hi) <= 0 {
+		return v.Int64(), true
+	}
+	switch overflow {
+	case "SAT":
+		if v.Cmp(lo) < 0 {
+			return lo.Int64(), true
+		}
+		return hi.Int64(), true
+	case "FAIL":
+		return 0, false
+	}
+	size := new(big.Int).Lsh(big.NewInt(1), uint(width))
+	w := new(big.Int).Mod(v, size)
+	if signed && w.Cmp(hi) > 0 {
+		w.Sub(w, size)
+	}
+	return w.Int64(), true
+}
+
+// bitfieldCmd implements BITFIELD, or BITFIELD_RO which only accepts GET when ro is true.
+func bitfieldCmd(ro bool) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		ops, err := parseBitfield(args[1:])
+		if err != nil {
+			return err
+		}
+		write := false
+		for _, o := range ops {
+			if o.op != "GET" {
+				if ro {
+					return errors.New("ERR BITFIELD_RO only supports the GET subcommand")
+				}
+				write = true
+			}
+		}
+		s, _, err := c.getString(args[0])
+		if err != nil {
+			return err
+		}
+		b := []byte(s)
+		r := []interface{}{}
+		for _, o := range ops {
+			old := bitfieldGet(b, o.offset, o.width, o.signed)
+			if o.op == "GET" {
+				r = append(r, old)
+				continue
+			}
+			v := big.NewInt(o.value)
+			if o.op == "INCRBY" {
+				v.Add(v, big.NewInt(old))
+			}
+			nv, ok := bitfieldOverflow(v, o.width, o.signed, o.overflow)
+			if !ok {
+				r = append(r, nil)
+				continue
+			}
+			b = bitfieldSet(b, o.offset, o.width, nv)
+			if o.op == "SET" {
+				r = append(r, old)
+			} else {
+				r = append(r, nv)
+			}
+		}
+		if write {
+			it, _ := c.create(args[0], "string")
+			it.str = string(b)
+		}
+		return r
+	}
+}
@@ -0,0 +1,392 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redistest
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+)
+
+type zmember struct {
+	member string
+	score  float64
+}
+
+// sortedZset returns the members of a sorted set by score, then by member.
+func sortedZset(zset map[string]float64) []zmember {
+	members := make([]zmember, 0, len(zset))
+	for m, s := range zset {
+		members = append(members, zmember{m, s})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].score != members[j].score {
+			return members[i].score < members[j].score
+		}
+		return members[i].member < members[j].member
+	})
+	return members
+}
+
+func zadd(c *client, args []string) interface{} {
+	var nx, xx, gt, lt, ch, incr bool
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GT":
+			gt = true
+		case "LT":
+			lt = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break options
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return errSyntax
+	}
+	if nx && xx {
+		return errors.New("ERR XX and NX options at the same time are not compatible")
+	}
+	if (gt && lt) || (nx && (gt || lt)) {
+		return errors.New("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if incr && len(pairs) != 2 {
+		return errors.New("ERR INCR option supports a single increment-element pair")
+	}
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		f, err := parseFloat(pairs[2*j])
+		if err != nil {
+			return err
+		}
+		scores[j] = f
+	}
+	it, err := c.getKind(args[0], "zset")
+	if err != nil {
+		return err
+	}
+	if it == nil {
+		if xx {
+			if incr {
+				return nil
+			}
+			return 0
+		}
+		it, _ = c.create(args[0], "zset")
+	}
+	added, changed := 0, 0
+	for j, score := range scores {
+		m := pairs[2*j+1]
+		old, ok := it.zset[m]
+		if incr && ok {
+			score += old
+		}
+		if math.IsNaN(score) {
+			c.cleanup(args[0], it)
+			return errors.New("ERR resulting score is not a number (NaN)")
+		}
+		if (nx && ok) || (xx && !ok) || (ok && gt && score <= old) || (ok && lt && score >= old) {
+			if incr {
+				c.cleanup(args[0], it)
+				return nil
+			}
+			continue
+		}
+		it.zset[m] = score
+		if !ok {
+			added++
+		} else if old != score {
+			changed++
+		}
+		if incr {
+			return formatFloat(score)
+		}
+	}
+	c.cleanup(args[0], it)
+	if ch {
+		return added + changed
+	}
+	return added
+}
+
+func zincrBy(c *client, args []string) interface{} {
+	return zadd(c, []string{args[0], "INCR", args[1], args[2]})
+}
+
+func zscore(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "zset")
+	if err != nil || it == nil {
+		return err
+	}
+	if s, ok := it.zset[args[1]]; ok {
+		return formatFloat(s)
+	}
+	return nil
+}
+
+func zcard(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "zset")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	return len(it.zset)
+}
+
+func zrem(c *client, args []string) interface{} {
+	it, err := c.getKind(args[0], "zset")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	n := 0
+	for _, m := range args[1:] {
+		if _, ok := it.zset[m]; ok {
+			delete(it.zset, m)
+			n++
+		}
+	}
+	c.cleanup(args[0], it)
+	return n
+}
+
+// zrankCmd returns ZRANK or ZREVRANK.
+func zrankCmd(rev bool) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		it, err := c.getKind(args[0], "zset")
+		if err != nil || it == nil {
+			return err
+		}
+		members := sortedZset(it.zset)
+		for i, m := range members {
+			if m.member == args[1] {
+				if rev {
+					return len(members) - 1 - i
+				}
+				return i
+			}
+		}
+		return nil
+	}
+}
+
+// scoreRange is a range of scores of ZRANGEBYSCORE, ZCOUNT and ZREMRANGEBYSCORE, e.g. (1 +inf.
+type scoreRange struct {
+	min, max         float64
+	minExcl, maxExcl bool
+}
+
+func parseScoreRange(min, max string) (r scoreRange, err error) {
+	bound := func(s string) (float64, bool, error) {
+		excl := strings.HasPrefix(s, "(")
+		if excl {
+			s = s[1:]
+		}
+		f, err := parseFloat(s)
+		if err != nil {
+			return 0, false, errors.New("ERR min or max is not a float")
+		}
+		return f, excl, nil
+	}
+	if r.min, r.minExcl, err = bound(min); err != nil {
+		return
+	}
+	r.max, r.maxExcl, err = bound(max)
+	return
+}
+
+func (r scoreRange) contains(f float64) bool {
+	return (f > r.min || !r.minExcl && f == r.min) && (f < r.max || !r.maxExcl && f == r.max)
+}
+
+// zrangeReply returns the members, with their scores if withScores.
+func zrangeReply(members []zmember, withScores bool) []string {
+	r := []string{}
+	for _, m := range members {
+		r = append(r, m.member)
+		if withScores {
+			r = append(r, formatFloat(m.score))
+		}
+	}
+	return r
+}
+
+// zrange implements ZRANGE key start stop [BYSCORE] [REV] [LIMIT offset count] [WITHSCORES].
+func zrange(c *client, args []string) interface{} {
+	var byScore, rev, withScores, limit bool
+	var offset, count int64
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "BYSCORE":
+			byScore = true
+		case "REV":
+			rev = true
+		case "WITHSCORES":
+			withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return errSyntax
+			}
+			var err error
+			if offset, err = parseInt(args[i+1]); err != nil {
+				return err
+			}
+			if count, err = parseInt(args[i+2]); err != nil {
+				return err
+			}
+			limit = true
+			i += 2
+		default:
+			return errSyntax
+		}
+	}
+	if limit && !byScore {
+		return errors.New("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if byScore {
+		return zrangeByScore(c, args[0], args[1], args[2], rev, withScores, limit, offset, count)
+	}
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	end, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(args[0], "zset")
+	if err != nil {
+		return err
+	}
+	if it == nil {
+		return []string{}
+	}
+	members := sortedZset(it.zset)
+	if rev {
+		reverse(members)
+	}
+	i, j := span(start, end, len(members))
+	return zrangeReply(members[i:j], withScores)
+}
+
+func reverse(members []zmember) {
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+}
+
+func zrangeByScore(c *client, key, min, max string, rev, withScores, limit bool, offset, count int64) interface{} {
+	if rev {
+		min, max = max, min
+	}
+	r, err := parseScoreRange(min, max)
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(key, "zset")
+	if err != nil {
+		return err
+	}
+	if it == nil {
+		return []string{}
+	}
+	members := sortedZset(it.zset)
+	if rev {
+		reverse(members)
+	}
+	var in []zmember
+	for _, m := range members {
+		if r.contains(m.score) {
+			in = append(in, m)
+		}
+	}
+	if limit {
+		if offset < 0 || offset >= int64(len(in)) {
+			return []string{}
+		}
+		in = in[offset:]
+		if count >= 0 && count < int64(len(in)) {
+			in = in[:count]
+		}
+	}
+	return zrangeReply(in, withScores)
+}
+
+// zrangeCmd returns ZREVRANGE, ZRANGEBYSCORE or ZREVRANGEBYSCORE, as ZRANGE with the options.
+func zrangeCmd(opts ...string) func(c *client, args []string) interface{} {
+	return func(c *client, args []string) interface{} {
+		a := append(append([]string{}, args[:3]...), opts...)
+		return zrange(c, append(a, args[3:]...))
+	}
+}
+
+func zcount(c *client, args []string) interface{} {
+	r, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(args[0], "zset")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	n := 0
+	for _, s := range it.zset {
+		if r.contains(s) {
+			n++
+		}
+	}
+	return n
+}
+
+func zremRangeByRank(c *client, args []string) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	end, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(args[0], "zset")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	members := sortedZset(it.zset)
+	i, j := span(start, end, len(members))
+	for _, m := range members[i:j] {
+		delete(it.zset, m.member)
+	}
+	c.cleanup(args[0], it)
+	return j - i
+}
+
+func zremRangeByScore(c *client, args []string) interface{} {
+	r, err := parseScoreRange(args[1], args[2])
+	if err != nil {
+		return err
+	}
+	it, err := c.getKind(args[0], "zset")
+	if err != nil || it == nil {
+		return orZero(err)
+	}
+	n := 0
+	for m, s := range it.zset {
+		if r.contains(s) {
+			delete(it.zset, m)
+			n++
+		}
+	}
+	c.cleanup(args[0], it)
+	return n
+}
@@ -0,0 +1,367 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RoutePolicy decides which node of a primary/replica set serves a read-only command.
+type RoutePolicy int
+
+const (
+	// PrimaryOnly sends every command to the primary.
+	PrimaryOnly RoutePolicy = iota
+	// RouteByLatency sends read-only commands to the healthy replica with the lowest PING latency.
+	RouteByLatency
+	// RouteRandomly sends read-only commands to a random healthy replica.
+	RouteRandomly
+)
+
+// ReplicaOptions configures a client created by NewReplicaClient.
+type ReplicaOptions struct {
+	LogOptions
+	// Policy is the routing policy for read-only commands.
+	Policy RoutePolicy
+	// MaxLag excludes the replicas whose last interaction with the primary is older than MaxLag.
+	// Zero disables the check.
+	MaxLag time.Duration
+	// CheckInterval is the interval of the replica health checks, 10 seconds by default.
+	CheckInterval time.Duration
+}
+
+// readOnlyCommands are the commands which may be served by a replica.
+var readOnlyCommands = map[string]bool{
+	"BITCOUNT": true, "BITPOS": true, "DUMP": true, "EXISTS": true, "GET": true,
+	"GETBIT": true, "GETRANGE": true, "HEXISTS": true, "HGET": true, "HGETALL": true,
+	"HKEYS": true, "HLEN": true, "HMGET": true, "HSTRLEN": true, "HVALS": true,
+	"KEYS": true, "LINDEX": true, "LLEN": true, "LRANGE": true, "MGET": true,
+	"PTTL": true, "RANDOMKEY": true, "SCARD": true, "SISMEMBER": true, "SMEMBERS": true,
+	"SRANDMEMBER": true, "STRLEN": true, "SUNION": true, "SINTER": true, "SDIFF": true,
+	"TTL": true, "TYPE": true, "ZCARD": true, "ZCOUNT": true, "ZRANGE": true,
+	"ZRANGEBYSCORE": true, "ZRANK": true, "ZREVRANGE": true, "ZREVRANGEBYSCORE": true,
+	"ZREVRANK": true, "ZSCORE": true, "SCAN": true, "HSCAN": true, "SSCAN": true, "ZSCAN": true,
+}
+
+type replicaNode struct {
+	url     string
+	cn      Conn // serves the commands, guarded by replicaConn.mu
+	pcn     Conn // the connection of the health checks, only used by check
+	healthy bool
+	latency time.Duration
+}
+
+// replicaConn routes read-only commands to the replicas and everything else to the primary.
+type replicaConn struct {
+	mu       sync.Mutex
+	primary  Conn
+	replicas []*replicaNode
+	opt      ReplicaOptions
+	dial     dialFunc
+	log      *slog.Logger
+	done     chan struct{}
+}
+
+// NewReplicaClient returns a client of a primary/replica set.
+// The replicas are discovered by INFO replication on the primary when no replica url is given.
+// Read-only commands are routed by opt.Policy to the healthy replicas and fall back to the primary on error,
+// while write commands and pipelines always go to the primary.
+func NewReplicaClient(primary string, replicas []string, opt ReplicaOptions) (Client, error) {
+	p, err := Dial(primary)
+	if err != nil {
+		return Client{}, err
+	}
+	if len(replicas) == 0 {
+		replicas, err = discoverReplicas(p, primary)
+		if err != nil {
+			p.Close()
+			return Client{}, err
+		}
+	}
+	if opt.CheckInterval <= 0 {
+		opt.CheckInterval = time.Second * 10
+	}
+	rc := &replicaConn{primary: p, opt: opt, log: opt.Logger, done: make(chan struct{})}
+	for _, u := range replicas {
+		rc.replicas = append(rc.replicas, &replicaNode{url: u})
+	}
+	rc.check()
+	go rc.watch()
+	cli := Client{cn: rc}
+	cli.SetLogger(opt.LogOptions)
+	return cli, nil
+}
+
+// discoverReplicas lists the replicas of the primary from INFO replication:
+//
+//	slave0:ip=127.0.0.1,port=6380,state=online,offset=1,lag=0
+func discoverReplicas(p Conn, primary string) ([]string, error) {
+	rsp, err := p.Send("INFO", "replication")
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := rsp.(error); ok {
+		return nil, e
+	}
+	s, err := String(rsp)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(primary)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ParseInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	replicas := []string{}
+	for k, v := range info.Sections["replication"] {
+		if !strings.HasPrefix(k, "slave") || k == "slave_read_only" {
+			continue
+		}
+		f := parseInfoValue(v)
+		if f["ip"] == "" || f["port"] == "" || f["state"] != "online" {
+			continue
+		}
+		replicas = append(replicas, u.Scheme+"://"+f["ip"]+":"+f["port"])
+	}
+	return replicas, nil
+}
+
+func (rc *replicaConn) watch() {
+	t := time.NewTicker(rc.opt.CheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-rc.done:
+			for _, n := range rc.replicas {
+				if n.pcn != nil {
+					n.pcn.Close()
+				}
+			}
+			return
+		case <-t.C:
+			rc.check()
+		}
+	}
+}
+
+// check pings every replica and verifies its replication link and lag.
+// The replicas are probed and dialed without holding rc.mu, only the results are swapped in under it,
+// so a slow replica does not stall the commands.
+func (rc *replicaConn) check() {
+	rc.mu.Lock()
+	dial, log := rc.dial, rc.log
+	rc.mu.Unlock()
+	for _, n := range rc.replicas {
+		latency, err := n.probe(dial, rc.opt.MaxLag)
+		var cn Conn
+		if err == nil {
+			rc.mu.Lock()
+			connected := n.cn != nil
+			rc.mu.Unlock()
+			if !connected {
+				cn, err = dialWith(dial, n.url)
+			}
+		}
+		rc.mu.Lock()
+		healthy := n.healthy
+		n.healthy, n.latency = err == nil, latency
+		select {
+		case <-rc.done:
+			n.healthy = false
+		default:
+			if cn != nil && n.cn == nil {
+				n.cn, cn = cn, nil
+			}
+		}
+		rc.mu.Unlock()
+		if cn != nil {
+			cn.Close()
+		}
+		if healthy && err != nil {
+			logAttr(log, slog.LevelWarn, "redis: replica excluded", "url", n.url, "error", err)
+		} else if !healthy && err == nil {
+			logAttr(log, slog.LevelInfo, "redis: replica included", "url", n.url, "latency", latency)
+		}
+	}
+}
+
+// probe returns the PING latency of a replica and the reason why it is unhealthy or nil.
+func (n *replicaNode) probe(dial dialFunc, maxLag time.Duration) (time.Duration, error) {
+	if n.pcn == nil {
+		cn, err := dialWith(dial, n.url)
+		if err != nil {
+			return 0, err
+		}
+		n.pcn = cn
+	}
+	start := time.Now()
+	_, err := n.pcn.Send("PING")
+	if err != nil {
+		n.pcn.Close()
+		n.pcn = nil
+		return 0, err
+	}
+	latency := time.Since(start)
+	rsp, err := n.pcn.Send("INFO", "replication")
+	if err != nil {
+		n.pcn.Close()
+		n.pcn = nil
+		return latency, err
+	}
+	s, _ := String(rsp)
+	info, err := ParseInfo(s)
+	if err != nil {
+		return latency, err
+	}
+	if r := info.Replication; r.Role != "slave" || r.MasterLinkStatus != "up" {
+		return latency, fmt.Errorf("redis: role %q, master link %q.", r.Role, r.MasterLinkStatus)
+	}
+	if maxLag > 0 {
+		// The lag is not a typed field, a missing lag is unknown rather than zero.
+		v := info.Sections["replication"]["master_last_io_seconds_ago"]
+		lag, err := strconv.Atoi(v)
+		if err != nil {
+			return latency, fmt.Errorf("redis: unknown replication lag %q.", v)
+		}
+		if d := time.Duration(lag) * time.Second; d > maxLag {
+			return latency, fmt.Errorf("redis: replication lag %s.", d)
+		}
+	}
+	return latency, nil
+}
+
+func (n *replicaNode) close() {
+	n.cn.Close()
+	n.cn = nil
+	n.healthy = false
+}
+
+// pick returns a healthy replica by the routing policy or nil if there is none.
+func (rc *replicaConn) pick() *replicaNode {
+	var healthy []*replicaNode
+	for _, n := range rc.replicas {
+		if n.healthy {
+			healthy = append(healthy, n)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	switch rc.opt.Policy {
+	case RouteByLatency:
+		best := healthy[0]
+		for _, n := range healthy[1:] {
+			if n.latency < best.latency {
+				best = n
+			}
+		}
+		return best
+	case RouteRandomly:
+		return healthy[rand.Intn(len(healthy))]
+	default:
+		return nil
+	}
+}
+
+func (rc *replicaConn) Send(cmd string, args ...interface{}) (reply interface{}, err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.opt.Policy != PrimaryOnly && readOnlyCommands[strings.ToUpper(cmd)] {
+		if n := rc.pick(); n != nil {
+			rsp, err := n.cn.Send(cmd, args...)
+			if err != nil {
+				n.close()
+				logAttr(rc.log, slog.LevelWarn, "redis: replica failed, falling back to the primary", "url", n.url, "error", err)
+			} else if _, e := rsp.(error); !e {
+				return rsp, nil
+			}
+		}
+	}
+	return rc.primary.Send(cmd, args...)
+}
+
+func (rc *replicaConn) Pipe(cmd string, args ...interface{}) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.primary.Pipe(cmd, args...)
+}
+
+func (rc *replicaConn) Flush() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.primary.Flush()
+}
+
+func (rc *replicaConn) Receive() (reply interface{}, err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.primary.Receive()
+}
+
+func (rc *replicaConn) Close() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	select {
+	case <-rc.done:
+		return errors.New("redis: client is closed.")
+	default:
+		close(rc.done)
+	}
+	for _, n := range rc.replicas {
+		if n.cn != nil {
+			n.close()
+		}
+	}
+	return rc.primary.Close()
+}
+
+func (rc *replicaConn) notifyDial(fn dialFunc) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.dial = fn
+	if d, ok := rc.primary.(dialNotifier); ok {
+		d.notifyDial(fn)
+	}
+}
+
+func (rc *replicaConn) setLogger(l *slog.Logger) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.log = l
+	if s, ok := rc.primary.(loggerSetter); ok {
+		s.setLogger(l)
+	}
+}
+
+// redial re-establishes the primary connection after a network error.
+func (rc *replicaConn) redial() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if r, ok := rc.primary.(redialer); ok {
+		return r.redial()
+	}
+	return nil
+}
+
+// dialDedicated returns a new connection to the primary.
+func (rc *replicaConn) dialDedicated() (Conn, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if d, ok := rc.primary.(dedicatedDialer); ok {
+		return d.dialDedicated()
+	}
+	return nil, errDedicated
+}
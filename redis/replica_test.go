@@ -0,0 +1,63 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"github.com/qqbuby/goredis/redis"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplicaClient(t *testing.T) {
+	const (
+		key   = "TEST:REPLICA"
+		value = key
+	)
+	// The primary is not a healthy replica of itself, reads must fall back to the primary.
+	rc, err := redis.NewReplicaClient(url, []string{url}, redis.ReplicaOptions{Policy: redis.RouteRandomly})
+	if err != nil {
+		t.Fatalf("NewReplicaClient: %s", err.Error())
+	}
+	defer rc.Close()
+
+	s, _ := rc.Set(key, value)
+	if s != "OK" {
+		t.Errorf("ReplicaClient did not work properly. E:%s, R:%s", "OK", s)
+	}
+	v, _ := rc.Get(key)
+	if v != value {
+		t.Errorf("ReplicaClient did not work properly. E:%s, R:%v", value, v)
+	}
+}
+
+func TestReplicaClientReadsFromReplica(t *testing.T) {
+	const key = "TEST:REPLICA:READ"
+	primary, pcli := newScratchClient(t)
+	replica, rcli := newScratchClient(t)
+	pcli.Set(key, "primary")
+	rcli.Set(key, "replica")
+	if _, err := rcli.Send("REPLICAOF", "127.0.0.1", strings.TrimPrefix(primary.Addr(), "127.0.0.1:")); err != nil {
+		t.Fatalf("REPLICAOF: %s", err.Error())
+	}
+	rc, err := redis.NewReplicaClient(primary.URL(), []string{replica.URL()},
+		redis.ReplicaOptions{Policy: redis.RouteByLatency, MaxLag: time.Second})
+	if err != nil {
+		t.Fatalf("NewReplicaClient: %s", err.Error())
+	}
+	defer rc.Close()
+
+	v, err := rc.Get(key)
+	if v != "replica" {
+		t.Errorf("ReplicaClient did not work properly. E:%s, R:%v %v", "replica", v, err)
+	}
+	rc.Set(key, "written")
+	if v, _ := pcli.Get(key); v != "written" {
+		t.Errorf("ReplicaClient did not work properly. E:%s, R:%v", "written", v)
+	}
+	if v, _ := rcli.Get(key); v != "replica" {
+		t.Errorf("ReplicaClient did not work properly. E:%s, R:%v", "replica", v)
+	}
+}
@@ -0,0 +1,111 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package resp
+
+import (
+	"strconv"
+)
+
+// Command is a command built from typed arguments. The arguments are encoded as they are added,
+// without being boxed in interfaces, and a Command reset for each use reuses its buffer, so a command
+// is built and written without allocation.
+//
+//	cmd := resp.NewCommand("INCRBY").AddString("counter").AddInt(1)
+//	w.Encode(cmd)
+//	...
+//	cmd.Reset("INCRBY").AddString("counter").AddInt(2)
+//
+// A Command must not be modified while it is written.
+type Command struct {
+	name string
+	n    int    // the number of arguments, the name excluded
+	b    []byte // the bulk strings of the arguments
+}
+
+// NewCommand returns an empty command.
+func NewCommand(name string) *Command {
+	return &Command{name: name}
+}
+
+// Reset empties the command and renames it, its buffer is kept.
+func (c *Command) Reset(name string) *Command {
+	c.name, c.n, c.b = name, 0, c.b[:0]
+	return c
+}
+
+// Name returns the name of the command.
+func (c *Command) Name() string {
+	return c.name
+}
+
+// Len returns the number of arguments, the name excluded.
+func (c *Command) Len() int {
+	return c.n
+}
+
+// Args returns a copy of the arguments as []byte values, e.g. for the hooks and the logs.
+func (c *Command) Args() []interface{} {
+	args := make([]interface{}, 0, c.n)
+	b := c.b
+	for len(b) > 0 {
+		i := 1
+		for b[i] != '\r' {
+			i++
+		}
+		n, _ := strconv.Atoi(string(b[1:i]))
+		b = b[i+2:]
+		args = append(args, append([]byte{}, b[:n]...))
+		b = b[n+2:]
+	}
+	return args
+}
+
+// AddString adds a string argument.
+func (c *Command) AddString(s string) *Command {
+	c.b = appendBulkString(c.b, s)
+	c.n++
+	return c
+}
+
+// AddBytes adds a []byte argument, it is copied.
+func (c *Command) AddBytes(p []byte) *Command {
+	c.b = appendBulk(c.b, p)
+	c.n++
+	return c
+}
+
+// AddInt adds an integer argument.
+func (c *Command) AddInt(i int64) *Command {
+	var a [20]byte
+	return c.AddBytes(strconv.AppendInt(a[:0], i, 10))
+}
+
+// AddUint adds an unsigned integer argument.
+func (c *Command) AddUint(i uint64) *Command {
+	var a [20]byte
+	return c.AddBytes(strconv.AppendUint(a[:0], i, 10))
+}
+
+// AddFloat adds a float argument in its shortest representation.
+func (c *Command) AddFloat(f float64) *Command {
+	var a [32]byte
+	return c.AddBytes(strconv.AppendFloat(a[:0], f, 'g', -1, 64))
+}
+
+// AddBool adds a bool argument, 1 or 0.
+func (c *Command) AddBool(v bool) *Command {
+	if v {
+		return c.AddString("1")
+	}
+	return c.AddString("0")
+}
+
+// Encode writes a command built by Command.
+func (w *Writer) Encode(c *Command) error {
+	w.buf = appendHeader(w.buf[:0], Array, int64(1+c.n))
+	w.buf = appendBulkString(w.buf, c.name)
+	w.write(w.buf)
+	return w.write(c.b)
+}
@@ -0,0 +1,492 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package resp
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// Reader decodes the RESP values of a stream.
+type Reader struct {
+	br *bufio.Reader
+}
+
+// NewReader returns a Reader of r, r is buffered unless it is a *bufio.Reader.
+func NewReader(r io.Reader) *Reader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Reader{br: br}
+}
+
+// Buffered returns the number of bytes read from the stream but not decoded yet.
+func (r *Reader) Buffered() int {
+	return r.br.Buffered()
+}
+
+// Peek returns the type of the next value without reading it.
+func (r *Reader) Peek() (Type, error) {
+	b, err := r.br.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return Type(b[0]), nil
+}
+
+// ReadValue reads a value. It returns io.EOF at the end of the stream, io.ErrUnexpectedEOF
+// when the stream ends within a value and a *ProtocolError for a malformed value.
+//
+//   - []byte for the Simple Strings, Integers, Bulk Strings, Doubles and Big Numbers,
+//     the Verbatim Strings without their format, and 1 or 0 for the Booleans
+//   - Error for the Simple Errors and Blob Errors, the error is the value and not the returned err
+//   - []interface{} for the Arrays, Sets and Pushes, and for the Maps with their keys and values alternately
+//   - nil for the Null, the Null Bulk String and the Null Array
+//
+// The Attributes are skipped, the value they are attached to is returned.
+func (r *Reader) ReadValue() (interface{}, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	t, m := Type(line[0]), line[1:]
+	switch t {
+	case SimpleString, Integer, Double, BigNumber:
+		return append([]byte{}, m...), nil // m is only valid until the next read of br.
+	case SimpleError:
+		return Error(m), nil
+	case Null:
+		if len(m) != 0 {
+			return nil, protocolError(line)
+		}
+		return nil, nil
+	case Boolean:
+		switch string(m) {
+		case "t":
+			return []byte{'1'}, nil
+		case "f":
+			return []byte{'0'}, nil
+		}
+		return nil, protocolError(line)
+	case BulkString, BlobError, VerbatimString:
+		b, err := r.readBulk(line)
+		if err != nil || b == nil {
+			return nil, err
+		}
+		switch t {
+		case BlobError:
+			return Error(b), nil
+		case VerbatimString:
+			if len(b) < 4 || b[3] != ':' {
+				return nil, &ProtocolError{Line: string(b)}
+			}
+			return b[4:], nil
+		}
+		return b, nil
+	case Array, Set, Push, Map, Attribute:
+		n, err := length(line, maxLen)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		if t == Map || t == Attribute {
+			n *= 2
+		}
+		a := make([]interface{}, 0, min(n, 1024)) // n is not trusted before the elements are read.
+		for i := 0; i < n; i++ {
+			v, err := r.ReadValue()
+			if err != nil {
+				return nil, unexpected(err)
+			}
+			a = append(a, v)
+		}
+		if t == Attribute {
+			v, err := r.ReadValue()
+			return v, unexpected(err)
+		}
+		return a, nil
+	default:
+		return nil, protocolError(line)
+	}
+}
+
+// ReadInto reads a value which is not an aggregate into dst, reusing its capacity, and returns its type:
+// the bulk strings are read directly into dst. For a null, dst is emptied and Null is returned; for an error
+// reply, its type and the Error are returned. An aggregate is not read and a *TypeError is returned.
+func (r *Reader) ReadInto(dst *[]byte) (Type, error) {
+	t, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	switch t {
+	case Array, Set, Push, Map:
+		if !r.nullArray() {
+			return 0, &TypeError{Op: "ReadInto", Type: t}
+		}
+	case BulkString, BlobError, VerbatimString:
+		return r.readBulkInto(dst)
+	}
+	line, err := r.readLine()
+	if err != nil {
+		return 0, err
+	}
+	m := line[1:]
+	switch t {
+	case SimpleString, Integer, Double, BigNumber:
+		*dst = append((*dst)[:0], m...)
+		return t, nil
+	case SimpleError:
+		return t, Error(m)
+	case Boolean:
+		switch string(m) {
+		case "t":
+			*dst = append((*dst)[:0], '1')
+			return t, nil
+		case "f":
+			*dst = append((*dst)[:0], '0')
+			return t, nil
+		}
+	case Null:
+		if len(m) == 0 {
+			*dst = (*dst)[:0]
+			return Null, nil
+		}
+	case Array: // *-1
+		*dst = (*dst)[:0]
+		return Null, nil
+	}
+	return 0, protocolError(line)
+}
+
+// readBulkInto reads a bulk string, a blob error or a verbatim string into dst.
+func (r *Reader) readBulkInto(dst *[]byte) (Type, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return 0, err
+	}
+	t := Type(line[0])
+	n, err := length(line, maxBulkLen)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		*dst = (*dst)[:0]
+		return Null, nil
+	}
+	b := *dst
+	if cap(b) < n {
+		b = make([]byte, n)
+	}
+	b = b[:n]
+	*dst = b
+	if _, err := io.ReadFull(r.br, b); err != nil {
+		return 0, unexpected(err)
+	}
+	if err := r.readCRLF(); err != nil {
+		return 0, err
+	}
+	switch t {
+	case BlobError:
+		return t, Error(b)
+	case VerbatimString:
+		if n < 4 || b[3] != ':' {
+			return 0, &ProtocolError{Line: string(b)}
+		}
+		*dst = b[:copy(b, b[4:])]
+	}
+	return t, nil
+}
+
+// ReadArrayLen reads the header of an aggregate and returns its number of elements, the elements are read next.
+// The elements of a map are its keys and values alternately. It returns -1 for a null, and -1 and the Error
+// for an error reply. A value which is not an aggregate is not read and a *TypeError is returned.
+func (r *Reader) ReadArrayLen() (int, error) {
+	t, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	switch t {
+	case Array, Set, Push, Map, Null, SimpleError:
+	case BulkString:
+		if b, _ := r.br.Peek(4); string(b) != "$-1\r" {
+			return 0, &TypeError{Op: "ReadArrayLen", Type: t}
+		}
+	case BlobError:
+		var b []byte
+		_, err := r.readBulkInto(&b)
+		return -1, err
+	default:
+		return 0, &TypeError{Op: "ReadArrayLen", Type: t}
+	}
+	line, err := r.readLine()
+	if err != nil {
+		return 0, err
+	}
+	switch t {
+	case SimpleError:
+		return -1, Error(line[1:])
+	case Null:
+		if len(line) != 1 {
+			return 0, protocolError(line)
+		}
+		return -1, nil
+	}
+	n, err := length(line, maxLen)
+	if err != nil {
+		return 0, err
+	}
+	if t == Map && n > 0 {
+		n *= 2
+	}
+	return n, nil
+}
+
+// ReadStream reads the header of a bulk string and returns the reader of its size bytes, read as they are
+// received; the reader returns io.EOF once the bulk string is read entirely, which must be done before the
+// next read of r. It returns a nil reader and -1 for a null, and the Error for an error reply. A value
+// which is not a bulk string is not read and a *TypeError is returned.
+func (r *Reader) ReadStream() (io.Reader, int64, error) {
+	t, err := r.next()
+	if err != nil {
+		return nil, 0, err
+	}
+	switch t {
+	case BulkString, SimpleError, Null:
+	case BlobError:
+		var b []byte
+		_, err := r.readBulkInto(&b)
+		return nil, -1, err
+	default:
+		return nil, 0, &TypeError{Op: "ReadStream", Type: t}
+	}
+	line, err := r.readLine()
+	if err != nil {
+		return nil, 0, err
+	}
+	switch t {
+	case SimpleError:
+		return nil, -1, Error(line[1:])
+	case Null:
+		return nil, -1, nil
+	}
+	n, err := length(line, maxBulkLen)
+	if err != nil || n < 0 {
+		return nil, -1, err
+	}
+	return &stream{r: r, left: int64(n)}, int64(n), nil
+}
+
+// stream reads the data of a bulk string and its CRLF.
+type stream struct {
+	r    *Reader
+	left int64
+	err  error
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.left == 0 {
+		if s.err = s.r.readCRLF(); s.err == nil {
+			s.err = io.EOF
+		}
+		return 0, s.err
+	}
+	if int64(len(p)) > s.left {
+		p = p[:s.left]
+	}
+	n, err := s.r.br.Read(p)
+	s.left -= int64(n)
+	if err != nil {
+		s.err = unexpected(err)
+	}
+	return n, s.err
+}
+
+// Skip reads a value and discards it, an aggregate with all its elements.
+func (r *Reader) Skip() error {
+	for left := 1; left > 0; left-- {
+		line, err := r.readLine()
+		if err != nil {
+			return unexpected(err)
+		}
+		switch Type(line[0]) {
+		case BulkString, BlobError, VerbatimString:
+			n, err := length(line, maxBulkLen)
+			if err != nil {
+				return err
+			}
+			if n >= 0 {
+				if _, err := r.br.Discard(n); err != nil {
+					return unexpected(err)
+				}
+				if err := r.readCRLF(); err != nil {
+					return err
+				}
+			}
+		case Array, Set, Push, Map, Attribute:
+			n, err := length(line, maxLen)
+			if err != nil {
+				return err
+			}
+			switch Type(line[0]) {
+			case Map:
+				n *= 2
+			case Attribute:
+				n = n*2 + 1 // the attribute is followed by the value it is attached to.
+			}
+			left += max(n, 0)
+		}
+	}
+	return nil
+}
+
+// next skips the attributes and returns the type of the next value.
+func (r *Reader) next() (Type, error) {
+	for {
+		t, err := r.Peek()
+		if err != nil || t != Attribute {
+			return t, err
+		}
+		line, err := r.readLine()
+		if err != nil {
+			return 0, err
+		}
+		n, err := length(line, maxLen)
+		if err != nil {
+			return 0, err
+		}
+		for i := 0; i < n*2; i++ {
+			if err := r.Skip(); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// nullArray reports whether the next value is a null array, *-1.
+func (r *Reader) nullArray() bool {
+	b, _ := r.br.Peek(4)
+	return string(b) == "*-1\r"
+}
+
+// readCRLF reads the CRLF ending a bulk string.
+func (r *Reader) readCRLF() error {
+	c, err := r.br.ReadByte()
+	if err == nil && c == '\r' {
+		c, err = r.br.ReadByte()
+		if err == nil && c == '\n' {
+			return nil
+		}
+	}
+	if err != nil {
+		return unexpected(err)
+	}
+	return &ProtocolError{Line: string(c)}
+}
+
+// ReadCommand reads a command, an array of bulk strings as sent by the clients and written in the AOF files.
+func (r *Reader) ReadCommand() ([][]byte, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if Type(line[0]) != Array {
+		return nil, protocolError(line)
+	}
+	n, err := length(line, maxLen)
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		return nil, protocolError(line)
+	}
+	args := make([][]byte, 0, min(n, 1024))
+	for i := 0; i < n; i++ {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		if Type(line[0]) != BulkString {
+			return nil, protocolError(line)
+		}
+		b, err := r.readBulk(line)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			return nil, &ProtocolError{Line: "$-1"}
+		}
+		args = append(args, b)
+	}
+	return args, nil
+}
+
+// readLine reads a non empty line without its CRLF, the line is valid until the next read.
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.br.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// A line longer than the buffer of the reader.
+		b := append([]byte{}, line...)
+		for err == bufio.ErrBufferFull && len(b) <= maxBulkLen {
+			line, err = r.br.ReadSlice('\n')
+			b = append(b, line...)
+		}
+		if err == bufio.ErrBufferFull {
+			return nil, protocolError(b[:64])
+		}
+		line = b
+	}
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	n := len(line)
+	if n < 3 || line[n-2] != '\r' {
+		return nil, protocolError(line)
+	}
+	return line[:n-2], nil
+}
+
+// readBulk reads the data of the bulk string of the header line, it returns nil for a null bulk string.
+func (r *Reader) readBulk(line []byte) ([]byte, error) {
+	n, err := length(line, maxBulkLen)
+	if err != nil || n < 0 {
+		return nil, err
+	}
+	b := make([]byte, n+2)
+	if _, err := io.ReadFull(r.br, b); err != nil {
+		return nil, unexpected(err)
+	}
+	if b[n] != '\r' || b[n+1] != '\n' {
+		return nil, &ProtocolError{Line: string(b[n:])}
+	}
+	return b[:n], nil
+}
+
+// length parses the length of a header line, -1 is a null.
+func length(line []byte, max int) (int, error) {
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n < -1 || n > max {
+		return 0, protocolError(line)
+	}
+	return n, nil
+}
+
+func protocolError(line []byte) error {
+	return &ProtocolError{Line: string(line)}
+}
+
+// unexpected reports the end of the stream within a value.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+// Package resp encodes and decodes the Redis serialization protocol, RESP2 and RESP3, over any
+// io.Reader or io.Writer: network connections, captured traffic or AOF files.
+//
+//	r := resp.NewReader(f) // an appendonly.aof
+//	for {
+//		cmd, err := r.ReadCommand()
+//		if err != nil {
+//			break
+//		}
+//		...
+//	}
+//
+// A Reader returns the values as the Conn of the package redis does, the RESP3 types are converted
+// to their RESP2 equivalents as Redis does for the RESP2 clients; Peek reports the type of the next value.
+// A Writer writes the commands of a client and the replies of a server.
+package resp
+
+import (
+	"fmt"
+	"io"
+)
+
+// Type is the type of a RESP value, its first byte.
+type Type byte
+
+const (
+	SimpleString   Type = '+'
+	SimpleError    Type = '-'
+	Integer        Type = ':'
+	BulkString     Type = '$'
+	Array          Type = '*'
+	Null           Type = '_' // RESP3
+	Double         Type = ',' // RESP3
+	Boolean        Type = '#' // RESP3
+	BlobError      Type = '!' // RESP3
+	VerbatimString Type = '=' // RESP3
+	BigNumber      Type = '(' // RESP3
+	Map            Type = '%' // RESP3
+	Set            Type = '~' // RESP3
+	Push           Type = '>' // RESP3
+	Attribute      Type = '|' // RESP3
+)
+
+// maxBulkLen is the maximum length of a bulk string, 512MB as Redis.
+const maxBulkLen = 512 << 20
+
+// maxLen is the maximum number of elements of an aggregate.
+const maxLen = 1 << 30
+
+// ProtocolError is a malformed value, the stream cannot be read any further.
+type ProtocolError struct {
+	// Line is the line of the malformed value.
+	Line string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("resp: protocol error: %q.", e.Line)
+}
+
+// Stream is an argument of a command written as a bulk string of Size bytes copied from R,
+// so a large value is written without being held in memory.
+type Stream struct {
+	R    io.Reader
+	Size int64
+}
+
+// Error is an error reply, a Simple Error or a Blob Error.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// TypeError is returned when the next value is not of the type expected by a read, the value is not read.
+type TypeError struct {
+	// Op is the read, e.g. ReadInto.
+	Op string
+	// Type is the type of the value.
+	Type Type
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("resp: %s of a value of type %q.", e.Op, byte(e.Type))
+}
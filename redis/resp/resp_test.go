@@ -0,0 +1,512 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package resp_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/qqbuby/goredis/redis/resp"
+	"io"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteCommand(t *testing.T) {
+	var b bytes.Buffer
+	w := resp.NewWriter(&b)
+	w.WriteCommand("SET", "k", []byte("a\r\nb"), 1, int64(-2), uint8(3), 1.5, float32(0.1), true, false, nil, errors.New("e"))
+	const expected = "*12\r\n$3\r\nSET\r\n$1\r\nk\r\n$4\r\na\r\nb\r\n$1\r\n1\r\n$2\r\n-2\r\n$1\r\n3\r\n$3\r\n1.5\r\n" +
+		"$3\r\n0.1\r\n$1\r\n1\r\n$1\r\n0\r\n$0\r\n\r\n$1\r\ne\r\n"
+	if b.String() != expected {
+		t.Errorf("WriteCommand did not work properly. E:%q, R:%q", expected, b.String())
+	}
+}
+
+func TestReadValue(t *testing.T) {
+	cases := []struct {
+		in    string
+		value interface{}
+	}{
+		{"+OK\r\n", []byte("OK")},
+		{"-ERR unknown command\r\n", resp.Error("ERR unknown command")},
+		{":-42\r\n", []byte("-42")},
+		{"$5\r\na\nb\rc\r\n", []byte("a\nb\rc")},
+		{"$0\r\n\r\n", []byte{}},
+		{"$-1\r\n", nil},
+		{"*-1\r\n", nil},
+		{"*0\r\n", []interface{}{}},
+		{"*2\r\n$1\r\na\r\n*1\r\n:1\r\n", []interface{}{[]byte("a"), []interface{}{[]byte("1")}}},
+		{"_\r\n", nil},
+		{",3.14\r\n", []byte("3.14")},
+		{"#t\r\n", []byte("1")},
+		{"#f\r\n", []byte("0")},
+		{"!9\r\nSYNTAX\r\nx\r\n", resp.Error("SYNTAX\r\nx")},
+		{"=7\r\ntxt:abc\r\n", []byte("abc")},
+		{"(3492890328409238509324850943850943825024385\r\n", []byte("3492890328409238509324850943850943825024385")},
+		{"%1\r\n+k\r\n:1\r\n", []interface{}{[]byte("k"), []byte("1")}},
+		{"~1\r\n+m\r\n", []interface{}{[]byte("m")}},
+		{">2\r\n+message\r\n+hi\r\n", []interface{}{[]byte("message"), []byte("hi")}},
+		{"|1\r\n+ttl\r\n:3600\r\n+v\r\n", []byte("v")},
+	}
+	for _, c := range cases {
+		v, err := resp.NewReader(strings.NewReader(c.in)).ReadValue()
+		if err != nil {
+			t.Errorf("ReadValue(%q) did not work properly: %s", c.in, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(v, c.value) {
+			t.Errorf("ReadValue(%q) did not work properly. E:%#v, R:%#v", c.in, c.value, v)
+		}
+	}
+}
+
+func TestReadValueError(t *testing.T) {
+	cases := []struct {
+		in  string
+		err error
+	}{
+		{"", io.EOF},
+		{"+OK", io.ErrUnexpectedEOF},
+		{"$3\r\nab", io.ErrUnexpectedEOF},
+		{"*2\r\n+a\r\n", io.ErrUnexpectedEOF},
+		{"+OK\n", &resp.ProtocolError{Line: "+OK\n"}},
+		{"$3\r\nabcd\r\n", &resp.ProtocolError{Line: "d\r"}},
+		{"$-2\r\n", &resp.ProtocolError{Line: "$-2"}},
+		{"?\r\n", &resp.ProtocolError{Line: "?"}},
+	}
+	for _, c := range cases {
+		_, err := resp.NewReader(strings.NewReader(c.in)).ReadValue()
+		if !reflect.DeepEqual(err, c.err) {
+			t.Errorf("ReadValue(%q) did not work properly. E:%v, R:%v", c.in, c.err, err)
+		}
+	}
+}
+
+// TestReadCommand reads the commands of an AOF file.
+func TestReadCommand(t *testing.T) {
+	const aof = "*2\r\n$6\r\nSELECT\r\n$1\r\n0\r\n*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$3\r\nv\r\n\r\n"
+	r := resp.NewReader(strings.NewReader(aof))
+	var cmds [][]string
+	for {
+		args, err := r.ReadCommand()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadCommand: %s", err.Error())
+		}
+		var cmd []string
+		for _, a := range args {
+			cmd = append(cmd, string(a))
+		}
+		cmds = append(cmds, cmd)
+	}
+	expected := [][]string{{"SELECT", "0"}, {"SET", "k", "v\r\n"}}
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("ReadCommand did not work properly. E:%q, R:%q", expected, cmds)
+	}
+	if _, err := resp.NewReader(strings.NewReader("+OK\r\n")).ReadCommand(); err == nil {
+		t.Error("ReadCommand did not work properly: a simple string is not a command.")
+	}
+}
+
+// TestReadInto scans an array element by element into a reused buffer.
+func TestReadInto(t *testing.T) {
+	const in = "|1\r\n+ttl\r\n:1\r\n*7\r\n$3\r\nabc\r\n$-1\r\n:42\r\n=7\r\ntxt:xyz\r\n#t\r\n*2\r\n+a\r\n+b\r\n-ERR x\r\n"
+	r := resp.NewReader(strings.NewReader(in))
+	n, err := r.ReadArrayLen()
+	if n != 7 || err != nil {
+		t.Fatalf("ReadArrayLen did not work properly. E:%d, R:%d, %v", 7, n, err)
+	}
+	cases := []struct {
+		t resp.Type
+		v string
+	}{
+		{resp.BulkString, "abc"},
+		{resp.Null, ""},
+		{resp.Integer, "42"},
+		{resp.VerbatimString, "xyz"},
+		{resp.Boolean, "1"},
+	}
+	buf := make([]byte, 0, 16)
+	for _, c := range cases {
+		typ, err := r.ReadInto(&buf)
+		if typ != c.t || string(buf) != c.v || err != nil {
+			t.Errorf("ReadInto did not work properly. E:%q %q, R:%q %q %v", c.t, c.v, typ, buf, err)
+		}
+	}
+	if _, err := r.ReadInto(&buf); err == nil {
+		t.Error("ReadInto did not work properly: an aggregate is not read.")
+	}
+	if err := r.Skip(); err != nil {
+		t.Errorf("Skip did not work properly: %s", err.Error())
+	}
+	if typ, err := r.ReadInto(&buf); typ != resp.SimpleError || err != resp.Error("ERR x") {
+		t.Errorf("ReadInto did not work properly. R:%q %v", typ, err)
+	}
+
+	data := []byte("*2\r\n$5\r\nhello\r\n:1\r\n")
+	src := bytes.NewReader(data)
+	br := bufio.NewReader(src)
+	r = resp.NewReader(br)
+	if n := testing.AllocsPerRun(100, func() {
+		src.Reset(data)
+		br.Reset(src)
+		r.ReadArrayLen()
+		r.ReadInto(&buf)
+		r.ReadInto(&buf)
+	}); n != 0 {
+		t.Errorf("ReadInto did not work properly. E:%d allocs, R:%v allocs", 0, n)
+	}
+}
+
+func TestReadArrayLen(t *testing.T) {
+	cases := []struct {
+		in  string
+		n   int
+		err error
+	}{
+		{"%2\r\n", 4, nil},
+		{"*-1\r\n", -1, nil},
+		{"$-1\r\n", -1, nil},
+		{"_\r\n", -1, nil},
+		{"-WRONGTYPE x\r\n", -1, resp.Error("WRONGTYPE x")},
+	}
+	for _, c := range cases {
+		n, err := resp.NewReader(strings.NewReader(c.in)).ReadArrayLen()
+		if n != c.n || err != c.err {
+			t.Errorf("ReadArrayLen(%q) did not work properly. E:%d %v, R:%d %v", c.in, c.n, c.err, n, err)
+		}
+	}
+	r := resp.NewReader(strings.NewReader("+OK\r\n"))
+	if _, err := r.ReadArrayLen(); err == nil {
+		t.Error("ReadArrayLen did not work properly: a simple string is not an aggregate.")
+	}
+	if v, _ := r.ReadValue(); string(v.([]byte)) != "OK" {
+		t.Errorf("ReadArrayLen did not work properly: the simple string is read. R:%v", v)
+	}
+}
+
+func TestStream(t *testing.T) {
+	var b bytes.Buffer
+	w := resp.NewWriter(&b)
+	w.WriteCommand("SET", "k", resp.Stream{R: strings.NewReader("a\r\nbc"), Size: 4})
+	w.WriteBulkString("next")
+	const expected = "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$4\r\na\r\nb\r\n$4\r\nnext\r\n"
+	if b.String() != expected {
+		t.Errorf("Stream did not work properly. E:%q, R:%q", expected, b.String())
+	}
+
+	r := resp.NewReader(strings.NewReader("$11\r\nhello world\r\n$-1\r\n-ERR x\r\n"))
+	s, n, err := r.ReadStream()
+	if err != nil {
+		t.Fatalf("ReadStream: %s", err.Error())
+	}
+	v, err := io.ReadAll(s)
+	if n != 11 || string(v) != "hello world" || err != nil {
+		t.Errorf("ReadStream did not work properly. E:%q, R:%d %q %v", "hello world", n, v, err)
+	}
+	if s, n, err := r.ReadStream(); s != nil || n != -1 || err != nil {
+		t.Errorf("ReadStream did not work properly: null. R:%d %v", n, err)
+	}
+	if _, _, err := r.ReadStream(); err != resp.Error("ERR x") {
+		t.Errorf("ReadStream did not work properly. E:%v, R:%v", resp.Error("ERR x"), err)
+	}
+
+	w = resp.NewWriter(failWriter{})
+	if err := w.WriteStream(strings.NewReader("ab"), 3); err != io.ErrClosedPipe {
+		t.Errorf("WriteStream did not work properly. E:%v, R:%v", io.ErrClosedPipe, err)
+	}
+	w = resp.NewWriter(&b)
+	if err := w.WriteStream(strings.NewReader("ab"), 3); err != io.ErrUnexpectedEOF {
+		t.Errorf("WriteStream did not work properly. E:%v, R:%v", io.ErrUnexpectedEOF, err)
+	}
+	if err := w.Flush(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Flush did not work properly. E:%v, R:%v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+// TestWriteReply writes the replies of a server and reads them back.
+func TestWriteReply(t *testing.T) {
+	var b bytes.Buffer
+	w := resp.NewWriter(&b)
+	w.WriteStatus("O\r\nK")
+	w.WriteError("ERR bad\nthing")
+	w.WriteInt(7)
+	w.WriteBulk([]byte("\r\n"))
+	w.WriteNull()
+	w.WriteNullArray()
+	w.WriteValue([]interface{}{"a", 1, nil, []string{"b"}, 2.5, struct{}{}})
+	w.WriteMap(1)
+	w.WriteBulkString("k")
+	w.WriteDouble(-1.25)
+	w.WriteBool(true)
+	w.WriteBigNumber(big.NewInt(-3))
+	w.WriteBlobError("ERR\r\nx")
+	w.WriteVerbatim("txt", "v")
+	w.WritePush(1)
+	w.WriteNil()
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %s", err.Error())
+	}
+
+	expected := []interface{}{
+		[]byte("O  K"),
+		resp.Error("ERR bad thing"),
+		[]byte("7"),
+		[]byte("\r\n"),
+		nil,
+		nil,
+		[]interface{}{[]byte("a"), []byte("1"), nil, []interface{}{[]byte("b")}, []byte("2.5"), resp.Error("ERR unsupported reply type struct {}")},
+		[]interface{}{[]byte("k"), []byte("-1.25")},
+		[]byte("1"),
+		[]byte("-3"),
+		resp.Error("ERR\r\nx"),
+		[]byte("v"),
+		[]interface{}{nil},
+	}
+	r := resp.NewReader(&b)
+	for i, e := range expected {
+		v, err := r.ReadValue()
+		if err != nil {
+			t.Fatalf("ReadValue: %s", err.Error())
+		}
+		if !reflect.DeepEqual(v, e) {
+			t.Errorf("Writer did not work properly, reply %d. E:%#v, R:%#v", i, e, v)
+		}
+	}
+	if _, err := r.ReadValue(); err != io.EOF {
+		t.Errorf("Writer did not work properly. E:%v, R:%v", io.EOF, err)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestWriteError(t *testing.T) {
+	w := resp.NewWriter(failWriter{})
+	if err := w.WriteCommand("PING"); err != io.ErrClosedPipe {
+		t.Errorf("WriteCommand did not work properly. E:%v, R:%v", io.ErrClosedPipe, err)
+	}
+	if err := w.WriteInt(1); err != io.ErrClosedPipe {
+		t.Errorf("WriteInt did not work properly. E:%v, R:%v", io.ErrClosedPipe, err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	var b bytes.Buffer
+	w := resp.NewWriter(&b)
+	cmd := resp.NewCommand("ZADD").AddString("z").AddFloat(1.5).AddBytes([]byte("m")).AddInt(-1).AddUint(2).AddBool(true)
+	w.Encode(cmd)
+	var expected bytes.Buffer
+	resp.NewWriter(&expected).WriteCommand("ZADD", "z", 1.5, []byte("m"), -1, uint(2), true)
+	if b.String() != expected.String() {
+		t.Errorf("Encode did not work properly. E:%q, R:%q", expected.String(), b.String())
+	}
+	args := []interface{}{[]byte("z"), []byte("1.5"), []byte("m"), []byte("-1"), []byte("2"), []byte("1")}
+	if cmd.Len() != 6 || !reflect.DeepEqual(cmd.Args(), args) {
+		t.Errorf("Args did not work properly. E:%q, R:%q", args, cmd.Args())
+	}
+
+	b.Reset()
+	w.Encode(cmd.Reset("GET").AddString("k"))
+	if b.String() != "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n" {
+		t.Errorf("Reset did not work properly. R:%q", b.String())
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		w.Encode(cmd.Reset("INCRBY").AddString("counter").AddInt(1000))
+	}); n != 0 {
+		t.Errorf("Encode did not work properly. E:%d allocs, R:%v allocs", 0, n)
+	}
+}
+
+var (
+	benchKey   = "key:000001"
+	benchValue = []byte("value:000001")
+)
+
+func newBenchWriter() *resp.Writer {
+	return resp.NewWriter(bufio.NewWriter(io.Discard))
+}
+
+func BenchmarkWriteCommandSet(b *testing.B) {
+	w := newBenchWriter()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.WriteCommand("SET", benchKey, benchValue)
+	}
+}
+
+func BenchmarkEncodeSet(b *testing.B) {
+	w := newBenchWriter()
+	cmd := resp.NewCommand("SET")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Encode(cmd.Reset("SET").AddString(benchKey).AddBytes(benchValue))
+	}
+}
+
+func BenchmarkWriteCommandGet(b *testing.B) {
+	w := newBenchWriter()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.WriteCommand("GET", benchKey)
+	}
+}
+
+func BenchmarkEncodeGet(b *testing.B) {
+	w := newBenchWriter()
+	cmd := resp.NewCommand("GET")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Encode(cmd.Reset("GET").AddString(benchKey))
+	}
+}
+
+// BenchmarkWriteCommandMSet writes an MSET of 10 keys with integer values.
+func BenchmarkWriteCommandMSet(b *testing.B) {
+	w := newBenchWriter()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		args := make([]interface{}, 0, 20)
+		for j := 0; j < 10; j++ {
+			args = append(args, benchKey, i*10+j)
+		}
+		w.WriteCommand("MSET", args...)
+	}
+}
+
+func BenchmarkEncodeMSet(b *testing.B) {
+	w := newBenchWriter()
+	cmd := resp.NewCommand("MSET")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cmd.Reset("MSET")
+		for j := 0; j < 10; j++ {
+			cmd.AddString(benchKey).AddInt(int64(i*10 + j))
+		}
+		w.Encode(cmd)
+	}
+}
+
+// prevWriter is the command encoder of resp.Writer before the short bulk strings were copied with their header:
+// each header, argument and CRLF is a write of its own. Its benchmarks are the baseline of those of
+// WriteCommand and Encode.
+type prevWriter struct {
+	w   io.Writer
+	buf []byte
+	e   error
+}
+
+func newPrevWriter() *prevWriter {
+	return &prevWriter{w: bufio.NewWriter(io.Discard), buf: make([]byte, 0, 64)}
+}
+
+func (w *prevWriter) WriteCommand(cmd string, args ...interface{}) error {
+	w.header(resp.Array, int64(1+len(args)))
+	w.writeBulkString(cmd)
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			w.writeBulkString(v)
+		case []byte:
+			w.header(resp.BulkString, int64(len(v)))
+			w.write(v)
+			w.writeString("\r\n")
+		case int:
+			w.bulkNumber(strconv.AppendInt(w.buf[:0], int64(v), 10))
+		case int64:
+			w.bulkNumber(strconv.AppendInt(w.buf[:0], v, 10))
+		default:
+			w.writeBulkString(fmt.Sprint(v))
+		}
+	}
+	return w.e
+}
+
+func (w *prevWriter) bulkNumber(n []byte) error {
+	b := append(n, '$')
+	b = strconv.AppendInt(b, int64(len(n)), 10)
+	b = append(b, '\r', '\n')
+	b = append(b, n...)
+	b = append(b, '\r', '\n')
+	w.buf = b[:0]
+	return w.write(b[len(n):])
+}
+
+func (w *prevWriter) header(t resp.Type, n int64) error {
+	b := append(w.buf[:0], byte(t))
+	b = strconv.AppendInt(b, n, 10)
+	b = append(b, '\r', '\n')
+	w.buf = b[:0]
+	return w.write(b)
+}
+
+func (w *prevWriter) writeBulkString(s string) error {
+	w.header(resp.BulkString, int64(len(s)))
+	w.writeString(s)
+	return w.writeString("\r\n")
+}
+
+func (w *prevWriter) write(b []byte) error {
+	if w.e == nil {
+		_, w.e = w.w.Write(b)
+	}
+	return w.e
+}
+
+func (w *prevWriter) writeString(s string) error {
+	if w.e == nil {
+		_, w.e = io.WriteString(w.w, s)
+	}
+	return w.e
+}
+
+func TestPrevWriter(t *testing.T) {
+	var b bytes.Buffer
+	pw := &prevWriter{w: &b, buf: make([]byte, 0, 64)}
+	pw.WriteCommand("MSET", benchKey, benchValue, "k", 42)
+	var e bytes.Buffer
+	resp.NewWriter(&e).WriteCommand("MSET", benchKey, benchValue, "k", 42)
+	if b.String() != e.String() {
+		t.Errorf("prevWriter did not work properly. E:%q, R:%q", e.String(), b.String())
+	}
+}
+
+func BenchmarkPrevWriteCommandSet(b *testing.B) {
+	w := newPrevWriter()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.WriteCommand("SET", benchKey, benchValue)
+	}
+}
+
+func BenchmarkPrevWriteCommandGet(b *testing.B) {
+	w := newPrevWriter()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.WriteCommand("GET", benchKey)
+	}
+}
+
+func BenchmarkPrevWriteCommandMSet(b *testing.B) {
+	w := newPrevWriter()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		args := make([]interface{}, 0, 20)
+		for j := 0; j < 10; j++ {
+			args = append(args, benchKey, i*10+j)
+		}
+		w.WriteCommand("MSET", args...)
+	}
+}
@@ -0,0 +1,346 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package resp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Writer encodes the RESP values to a stream. The writes are not buffered,
+// a network connection is usually wrapped by a bufio.Writer flushed by Flush.
+// After a write error, the Writer writes nothing more and returns the error.
+type Writer struct {
+	w   io.Writer
+	buf []byte // the scratch buffer of the headers and the short bulk strings, reused by every write
+	e   error  // the first write error
+}
+
+// NewWriter returns a Writer of w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, buf: make([]byte, 0, maxCopy+32)}
+}
+
+// Flush flushes the stream when it has a Flush method, e.g. a bufio.Writer.
+// After a write error, nothing is flushed and the error is returned.
+func (w *Writer) Flush() error {
+	if w.e != nil {
+		return w.e
+	}
+	if f, ok := w.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// WriteCommand writes a command as an array of bulk strings. An argument is written as
+// a string, a []byte, an integer, a float (the shortest representation), a bool (1 or 0),
+// nil (an empty string), a Stream or otherwise as formatted by fmt.Print.
+func (w *Writer) WriteCommand(cmd string, args ...interface{}) error {
+	w.WriteArray(1 + len(args))
+	w.WriteBulkString(cmd)
+	for _, arg := range args {
+		w.WriteArg(arg)
+	}
+	return w.e
+}
+
+// WriteArg writes an argument of a command as a bulk string.
+func (w *Writer) WriteArg(arg interface{}) error {
+	// The numbers are formatted on the stack, no argument but those formatted by fmt allocates.
+	var a [32]byte
+	switch v := arg.(type) {
+	case string:
+		return w.WriteBulkString(v)
+	case []byte:
+		return w.WriteBulk(v)
+	case int:
+		return w.number(strconv.AppendInt(a[:0], int64(v), 10))
+	case int8:
+		return w.number(strconv.AppendInt(a[:0], int64(v), 10))
+	case int16:
+		return w.number(strconv.AppendInt(a[:0], int64(v), 10))
+	case int32:
+		return w.number(strconv.AppendInt(a[:0], int64(v), 10))
+	case int64:
+		return w.number(strconv.AppendInt(a[:0], v, 10))
+	case uint:
+		return w.number(strconv.AppendUint(a[:0], uint64(v), 10))
+	case uint8:
+		return w.number(strconv.AppendUint(a[:0], uint64(v), 10))
+	case uint16:
+		return w.number(strconv.AppendUint(a[:0], uint64(v), 10))
+	case uint32:
+		return w.number(strconv.AppendUint(a[:0], uint64(v), 10))
+	case uint64:
+		return w.number(strconv.AppendUint(a[:0], v, 10))
+	case float32:
+		return w.number(strconv.AppendFloat(a[:0], float64(v), 'g', -1, 32))
+	case float64:
+		return w.number(strconv.AppendFloat(a[:0], v, 'g', -1, 64))
+	case bool:
+		if v {
+			return w.WriteBulkString("1")
+		}
+		return w.WriteBulkString("0")
+	case nil:
+		return w.WriteBulkString("")
+	case Stream:
+		return w.WriteStream(v.R, v.Size)
+	default:
+		return w.WriteBulkString(fmt.Sprint(v))
+	}
+}
+
+// number writes a number formatted on the stack of the caller, it is copied as it must not escape.
+func (w *Writer) number(n []byte) error {
+	w.buf = appendBulk(w.buf[:0], n)
+	return w.write(w.buf)
+}
+
+// write writes b unless a previous write failed, the first error is kept.
+func (w *Writer) write(b []byte) error {
+	if w.e == nil {
+		_, w.e = w.w.Write(b)
+	}
+	return w.e
+}
+
+func (w *Writer) writeString(s string) error {
+	if w.e == nil {
+		_, w.e = io.WriteString(w.w, s)
+	}
+	return w.e
+}
+
+// noCRLF replaces the line breaks which would end a simple string or an error early.
+var noCRLF = strings.NewReplacer("\r", " ", "\n", " ")
+
+func (w *Writer) line(t Type, s string) error {
+	b := append(w.buf[:0], byte(t))
+	b = append(b, noCRLF.Replace(s)...)
+	w.buf = append(b, '\r', '\n')
+	return w.write(w.buf)
+}
+
+func (w *Writer) header(t Type, n int64) error {
+	w.buf = appendHeader(w.buf[:0], t, n)
+	return w.write(w.buf)
+}
+
+// maxCopy is the maximum length of the bulk strings copied with their header to the scratch buffer
+// to be written at once, the longer ones are written as they are.
+const maxCopy = 512
+
+func appendHeader(b []byte, t Type, n int64) []byte {
+	b = append(b, byte(t))
+	b = strconv.AppendInt(b, n, 10)
+	return append(b, '\r', '\n')
+}
+
+func appendBulk(b, p []byte) []byte {
+	b = appendHeader(b, BulkString, int64(len(p)))
+	b = append(b, p...)
+	return append(b, '\r', '\n')
+}
+
+func appendBulkString(b []byte, s string) []byte {
+	b = appendHeader(b, BulkString, int64(len(s)))
+	b = append(b, s...)
+	return append(b, '\r', '\n')
+}
+
+// WriteStatus writes a simple string, e.g. +OK. The line breaks of s are replaced by spaces.
+func (w *Writer) WriteStatus(s string) error {
+	return w.line(SimpleString, s)
+}
+
+// WriteError writes a simple error, its message starts with the error kind, e.g. "ERR syntax error".
+// The line breaks of msg are replaced by spaces.
+func (w *Writer) WriteError(msg string) error {
+	return w.line(SimpleError, msg)
+}
+
+// WriteInt writes an integer.
+func (w *Writer) WriteInt(n int64) error {
+	return w.header(Integer, n)
+}
+
+// WriteBulk writes a bulk string.
+func (w *Writer) WriteBulk(b []byte) error {
+	if len(b) <= maxCopy {
+		w.buf = appendBulk(w.buf[:0], b)
+		return w.write(w.buf)
+	}
+	w.header(BulkString, int64(len(b)))
+	w.write(b)
+	return w.writeString("\r\n")
+}
+
+// WriteBulkString writes a bulk string.
+func (w *Writer) WriteBulkString(s string) error {
+	if len(s) <= maxCopy {
+		w.buf = appendBulkString(w.buf[:0], s)
+		return w.write(w.buf)
+	}
+	w.header(BulkString, int64(len(s)))
+	w.writeString(s)
+	return w.writeString("\r\n")
+}
+
+// WriteStream writes a bulk string of size bytes copied from r. When r has less than size bytes,
+// io.ErrUnexpectedEOF is returned and the Writer is broken as by a write error.
+func (w *Writer) WriteStream(r io.Reader, size int64) error {
+	w.header(BulkString, size)
+	if w.e == nil {
+		if _, err := io.CopyN(w.w, r, size); err != nil {
+			w.e = unexpected(err)
+		}
+	}
+	return w.writeString("\r\n")
+}
+
+// WriteNull writes a null bulk string, $-1.
+func (w *Writer) WriteNull() error {
+	return w.writeString("$-1\r\n")
+}
+
+// WriteNullArray writes a null array, *-1.
+func (w *Writer) WriteNullArray() error {
+	return w.writeString("*-1\r\n")
+}
+
+// WriteArray writes the header of an array of n elements, followed by its n elements.
+func (w *Writer) WriteArray(n int) error {
+	return w.header(Array, int64(n))
+}
+
+// WriteNil writes the RESP3 null, _.
+func (w *Writer) WriteNil() error {
+	return w.writeString("_\r\n")
+}
+
+// WriteDouble writes a RESP3 double.
+func (w *Writer) WriteDouble(f float64) error {
+	var s string
+	switch {
+	case math.IsInf(f, 1):
+		s = "inf"
+	case math.IsInf(f, -1):
+		s = "-inf"
+	case math.IsNaN(f):
+		s = "nan"
+	default:
+		s = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return w.line(Double, s)
+}
+
+// WriteBool writes a RESP3 boolean.
+func (w *Writer) WriteBool(b bool) error {
+	if b {
+		return w.line(Boolean, "t")
+	}
+	return w.line(Boolean, "f")
+}
+
+// WriteBigNumber writes a RESP3 big number.
+func (w *Writer) WriteBigNumber(n *big.Int) error {
+	return w.line(BigNumber, n.String())
+}
+
+// WriteBlobError writes a RESP3 blob error, its message may contain line breaks.
+func (w *Writer) WriteBlobError(msg string) error {
+	w.header(BlobError, int64(len(msg)))
+	w.writeString(msg)
+	return w.writeString("\r\n")
+}
+
+// WriteVerbatim writes a RESP3 verbatim string, format is 3 bytes, e.g. txt or mkd.
+func (w *Writer) WriteVerbatim(format, s string) error {
+	w.header(VerbatimString, int64(len(format)+1+len(s)))
+	w.writeString(format + ":")
+	w.writeString(s)
+	return w.writeString("\r\n")
+}
+
+// WriteMap writes the header of a RESP3 map of n pairs, followed by its keys and values alternately.
+func (w *Writer) WriteMap(n int) error {
+	return w.header(Map, int64(n))
+}
+
+// WriteSet writes the header of a RESP3 set of n elements.
+func (w *Writer) WriteSet(n int) error {
+	return w.header(Set, int64(n))
+}
+
+// WritePush writes the header of a RESP3 push of n elements.
+func (w *Writer) WritePush(n int) error {
+	return w.header(Push, int64(n))
+}
+
+// WriteAttribute writes the header of a RESP3 attribute of n pairs, followed by its keys and values
+// alternately and by the value it is attached to.
+func (w *Writer) WriteAttribute(n int) error {
+	return w.header(Attribute, int64(n))
+}
+
+// WriteValue writes a Go value as a RESP2 reply: nil as a null bulk string, a string or a []byte
+// as a bulk string, an integer, a float as a bulk string, a bool as 1 or 0, an error, or a slice
+// of those as an array. An unsupported value is written as an error, the stream stays well-formed.
+func (w *Writer) WriteValue(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		return w.WriteNull()
+	case string:
+		return w.WriteBulkString(v)
+	case []byte:
+		return w.WriteBulk(v)
+	case int:
+		return w.WriteInt(int64(v))
+	case int32:
+		return w.WriteInt(int64(v))
+	case int64:
+		return w.WriteInt(v)
+	case uint32:
+		return w.WriteInt(int64(v))
+	case float32, float64:
+		return w.WriteArg(v)
+	case bool:
+		if v {
+			return w.WriteInt(1)
+		}
+		return w.WriteInt(0)
+	case error:
+		return w.WriteError(v.Error())
+	case []string:
+		w.WriteArray(len(v))
+		for _, e := range v {
+			w.WriteBulkString(e)
+		}
+	case [][]byte:
+		w.WriteArray(len(v))
+		for _, e := range v {
+			w.WriteBulk(e)
+		}
+	case []int64:
+		w.WriteArray(len(v))
+		for _, e := range v {
+			w.WriteInt(e)
+		}
+	case []interface{}:
+		w.WriteArray(len(v))
+		for _, e := range v {
+			w.WriteValue(e)
+		}
+	default:
+		return w.WriteError(fmt.Sprintf("ERR unsupported reply type %T", v))
+	}
+	return w.e
+}
@@ -0,0 +1,143 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures the automatic retry of the commands sent by a Client.
+// Only the read-only commands and the commands listed in Idempotent are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a command, the first one included.
+	// A value less than 2 disables the retry.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the exponential backoff between two attempts.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Retryable classifies the failed attempts, IsRetryable is used when it is nil.
+	Retryable func(reply interface{}, err error) bool
+	// Idempotent lists the write commands which are safe to retry, e.g. SET or DEL.
+	Idempotent []string
+}
+
+// DefaultRetryPolicy is the retry policy of a Client without SetRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond * 8,
+	MaxBackoff:  time.Millisecond * 512,
+}
+
+// retryErrorPrefixes are the error replies of a server which is restarting, failing over or resharding.
+var retryErrorPrefixes = []string{"LOADING", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN", "READONLY"}
+
+// redialer is implemented by the connections which can re-establish themselves after a network error.
+type redialer interface {
+	redial() error
+}
+
+// IsRetryable reports whether a failed attempt may succeed later:
+// a network error or an error reply of LOADING, TRYAGAIN, CLUSTERDOWN, MASTERDOWN or READONLY.
+func IsRetryable(reply interface{}, err error) bool {
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF || err == syscall.ECONNRESET || err == syscall.EPIPE {
+			return true
+		}
+		_, ok := err.(net.Error)
+		return ok
+	}
+	if e, ok := reply.(error); ok {
+		for _, p := range retryErrorPrefixes {
+			if strings.HasPrefix(e.Error(), p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetRetryPolicy sets the retry policy of the client.
+func (cli *Client) SetRetryPolicy(p RetryPolicy) {
+	cli.retry = &p
+}
+
+func (cli *Client) retryPolicy() *RetryPolicy {
+	if cli.retry == nil {
+		return &DefaultRetryPolicy
+	}
+	return cli.retry
+}
+
+// canRetry reports whether the command is read-only or declared idempotent.
+func (p *RetryPolicy) canRetry(cmd string) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+	cmd = strings.ToUpper(cmd)
+	if readOnlyCommands[cmd] || cmd == "PING" || cmd == "ECHO" {
+		return true
+	}
+	for _, c := range p.Idempotent {
+		if strings.ToUpper(c) == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryable(reply interface{}, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(reply, err)
+	}
+	return IsRetryable(reply, err)
+}
+
+// backoff returns the delay before the attempt+1 retry: an exponential backoff with jitter.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 0; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sendRetry sends the command and retries it by the retry policy of the client,
+// the connection is re-established before a retry when it failed on a network error.
+func (cli *Client) sendRetry(cmd string, args ...interface{}) (reply interface{}, err error) {
+	return cli.retryWith(cmd, func() (interface{}, error) {
+		return cli.cn.Send(cmd, args...)
+	})
+}
+
+// retryWith sends a command by send and retries it by the retry policy of the client.
+func (cli *Client) retryWith(cmd string, send func() (interface{}, error)) (reply interface{}, err error) {
+	p := cli.retryPolicy()
+	if !p.canRetry(cmd) {
+		return send()
+	}
+	for attempt := 0; ; attempt++ {
+		reply, err = send()
+		if attempt+1 >= p.MaxAttempts || !p.retryable(reply, err) {
+			return reply, err
+		}
+		time.Sleep(p.backoff(attempt))
+		if err != nil {
+			if r, ok := cli.cn.(redialer); ok {
+				r.redial()
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"errors"
+	"github.com/qqbuby/goredis/redis"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		reply     interface{}
+		err       error
+		retryable bool
+	}{
+		{nil, io.EOF, true},
+		{errors.New("LOADING Redis is loading the dataset in memory"), nil, true},
+		{errors.New("TRYAGAIN Multiple keys request during rehashing of slot"), nil, true},
+		{errors.New("CLUSTERDOWN The cluster is down"), nil, true},
+		{errors.New("WRONGTYPE Operation against a key holding the wrong kind of value"), nil, false},
+		{[]byte("OK"), nil, false},
+	}
+	for _, c := range cases {
+		if r := redis.IsRetryable(c.reply, c.err); r != c.retryable {
+			t.Errorf("IsRetryable did not work properly. (%v, %v) E:%t, R:%t", c.reply, c.err, c.retryable, r)
+		}
+	}
+}
+
+func TestSetRetryPolicy(t *testing.T) {
+	const (
+		key   = "TEST:RETRY"
+		value = key
+	)
+	client, e := redis.NewClient(url)
+	if e != nil {
+		t.Fatalf("SetRetryPolicy: %s", e.Error())
+	}
+	defer client.Close()
+
+	attempts := 0
+	client.SetRetryPolicy(redis.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond * 4,
+		Retryable: func(reply interface{}, err error) bool {
+			attempts++
+			return true
+		},
+	})
+	client.Set(key, value)
+	v, _ := client.Get(key)
+	if v != value || attempts != 2 {
+		t.Errorf("SetRetryPolicy did not work properly. E:%s, R:%v, Attempts:%d", value, v, attempts)
+	}
+}
@@ -0,0 +1,419 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RingOptions configures a client created by NewRingClient.
+type RingOptions struct {
+	LogOptions
+	// HeartbeatInterval is the interval of the PING health checks of the shards, 500 milliseconds by default.
+	HeartbeatInterval time.Duration
+	// HeartbeatFailures is the number of consecutive failures after which a shard is removed from the ring, 3 by default.
+	HeartbeatFailures int
+}
+
+// noKeyCommands are the commands without a key. Send fans DBSIZE, FLUSHALL, FLUSHDB and KEYS out to every
+// live shard and merges their replies, the other ones, e.g. INFO, RANDOMKEY or SCAN, and all the pipelined
+// ones are only sent to the first live shard.
+var noKeyCommands = map[string]bool{
+	"AUTH": true, "DBSIZE": true, "ECHO": true, "FLUSHALL": true, "FLUSHDB": true,
+	"INFO": true, "KEYS": true, "PING": true, "QUIT": true, "RANDOMKEY": true,
+	"SCAN": true, "SELECT": true, "TIME": true, "WAIT": true,
+}
+
+// fanOutCommands are the commands without a key which Send sends to every live shard.
+var fanOutCommands = map[string]bool{"DBSIZE": true, "FLUSHALL": true, "FLUSHDB": true, "KEYS": true}
+
+// txCommands are the commands of a transaction, which a ring rejects: the keys of a transaction may be
+// on different shards while MULTI and EXEC are sent to one.
+var txCommands = map[string]bool{"DISCARD": true, "EXEC": true, "MULTI": true, "UNWATCH": true, "WATCH": true}
+
+var errRingTx = errors.New("redis: transactions are not supported by a ring client.")
+
+type ringShard struct {
+	name     string
+	url      string
+	cn       Conn // serves the commands, guarded by ringConn.mu
+	hcn      Conn // the connection of the heartbeats, only used by check
+	failures int
+	pending  int
+}
+
+func (s *ringShard) live(max int) bool {
+	return s.failures < max
+}
+
+func (rc *ringConn) fail(s *ringShard, err error) {
+	if s.failures+1 == rc.opt.HeartbeatFailures {
+		logAttr(rc.log, slog.LevelWarn, "redis: ring shard removed", "shard", s.name, "url", s.url, "error", err)
+	}
+	s.failures++
+	if s.cn != nil {
+		s.cn.Close()
+		s.cn = nil
+	}
+}
+
+// ringConn distributes the commands over independent shards by rendezvous hashing of their key.
+type ringConn struct {
+	mu     sync.Mutex
+	shards []*ringShard
+	piped  []*ringShard
+	opt    RingOptions
+	dial   dialFunc
+	log    *slog.Logger
+	done   chan struct{}
+}
+
+// NewRingClient returns a client which distributes the keys over the named shards (name -> url)
+// by rendezvous hashing. Only the {hash tag} of a key is hashed when it has one, so all the keys of
+// a multi-key command must share a hash tag. The shards are health-checked by PING,
+// a failing shard is removed from the ring until it answers again.
+// DBSIZE, FLUSHALL, FLUSHDB and KEYS are sent to every live shard, the other commands without a key
+// only to the first live shard. The transactions, i.e. MULTI, EXEC, DISCARD, WATCH, UNWATCH and TxPipeline,
+// are rejected.
+func NewRingClient(shards map[string]string, opt RingOptions) (Client, error) {
+	if len(shards) == 0 {
+		return Client{}, errors.New("redis: no ring shard.")
+	}
+	if opt.HeartbeatInterval <= 0 {
+		opt.HeartbeatInterval = time.Millisecond * 500
+	}
+	if opt.HeartbeatFailures <= 0 {
+		opt.HeartbeatFailures = 3
+	}
+	rc := &ringConn{opt: opt, log: opt.Logger, done: make(chan struct{})}
+	for name, u := range shards {
+		s := &ringShard{name: name, url: u}
+		if cn, err := Dial(u); err == nil {
+			s.cn = cn
+		} else {
+			s.failures = opt.HeartbeatFailures
+		}
+		rc.shards = append(rc.shards, s)
+	}
+	sort.Slice(rc.shards, func(i, j int) bool { return rc.shards[i].name < rc.shards[j].name })
+	go rc.heartbeat()
+	cli := Client{cn: rc}
+	cli.SetLogger(opt.LogOptions)
+	return cli, nil
+}
+
+func (rc *ringConn) heartbeat() {
+	t := time.NewTicker(rc.opt.HeartbeatInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-rc.done:
+			for _, s := range rc.shards {
+				if s.hcn != nil {
+					s.hcn.Close()
+				}
+			}
+			return
+		case <-t.C:
+			rc.check()
+		}
+	}
+}
+
+// check pings every shard on its heartbeat connection and dials the shards which are down.
+// The shards are pinged and dialed without holding rc.mu, only the results are swapped in under it,
+// so a shard which is down does not stall the commands of the other ones.
+// The shards in the middle of a pipeline keep their state until the pipeline is received.
+func (rc *ringConn) check() {
+	rc.mu.Lock()
+	dial := rc.dial
+	rc.mu.Unlock()
+	for _, s := range rc.shards {
+		err := s.ping(dial)
+		var cn Conn
+		if err == nil {
+			rc.mu.Lock()
+			connected := s.cn != nil
+			rc.mu.Unlock()
+			if !connected {
+				cn, err = dialWith(dial, s.url)
+			}
+		}
+		rc.mu.Lock()
+		select {
+		case <-rc.done:
+		default:
+			if s.pending > 0 {
+				break
+			}
+			if err != nil {
+				rc.fail(s, err)
+				break
+			}
+			if s.cn == nil {
+				s.cn, cn = cn, nil
+			}
+			if !s.live(rc.opt.HeartbeatFailures) {
+				logAttr(rc.log, slog.LevelInfo, "redis: ring shard added", "shard", s.name, "url", s.url)
+			}
+			s.failures = 0
+		}
+		rc.mu.Unlock()
+		if cn != nil {
+			cn.Close()
+		}
+	}
+}
+
+// ping sends PING on the heartbeat connection of the shard, which is dialed if needed.
+func (s *ringShard) ping(dial dialFunc) error {
+	if s.hcn == nil {
+		cn, err := dialWith(dial, s.url)
+		if err != nil {
+			return err
+		}
+		s.hcn = cn
+	}
+	rsp, err := s.hcn.Send("PING")
+	if err != nil {
+		s.hcn.Close()
+		s.hcn = nil
+		return err
+	}
+	err, _ = rsp.(error)
+	return err
+}
+
+func (rc *ringConn) notifyDial(fn dialFunc) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.dial = fn
+}
+
+func (rc *ringConn) setLogger(l *slog.Logger) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.log = l
+}
+
+// hashKey returns the part of the key to hash: the content of the first non-empty {...} or the whole key.
+func hashKey(key string) string {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			return key[i+1 : i+1+j]
+		}
+	}
+	return key
+}
+
+// fmix64 is the finalizer of MurmurHash3, FNV alone hardly mixes the names of the shards, e.g. shard1 and shard2,
+// into the hash of the key, which sends all the keys to the same shard.
+func fmix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// shard returns the live shard of the command by its key.
+func (rc *ringConn) shard(cmd string, args []interface{}) (*ringShard, error) {
+	var key string
+	pos := 0
+	switch c := strings.ToUpper(cmd); {
+	case noKeyCommands[c]:
+		pos = -1
+	case c == "BITOP":
+		pos = 1
+	}
+	if pos >= 0 && pos < len(args) {
+		switch k := args[pos].(type) {
+		case string:
+			key = k
+		case []byte:
+			key = string(k)
+		default:
+			key = fmt.Sprint(k)
+		}
+		key = hashKey(key)
+	} else {
+		pos = -1
+	}
+
+	var best *ringShard
+	var max uint64
+	for _, s := range rc.shards {
+		if !s.live(rc.opt.HeartbeatFailures) || s.cn == nil {
+			continue
+		}
+		if pos < 0 {
+			return s, nil
+		}
+		h := fnv.New64a()
+		h.Write([]byte(s.name))
+		h.Write([]byte(key))
+		if v := fmix64(h.Sum64()); best == nil || v > max {
+			best, max = s, v
+		}
+	}
+	if best == nil {
+		return nil, errors.New("redis: all ring shards are down.")
+	}
+	return best, nil
+}
+
+func (rc *ringConn) Send(cmd string, args ...interface{}) (reply interface{}, err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if txCommands[strings.ToUpper(cmd)] {
+		return nil, errRingTx
+	}
+	if fanOutCommands[strings.ToUpper(cmd)] {
+		return rc.fanOut(cmd, args)
+	}
+	s, err := rc.shard(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	reply, err = s.cn.Send(cmd, args...)
+	if err != nil {
+		rc.fail(s, err)
+	}
+	return reply, err
+}
+
+// fanOut sends a command to every live shard and merges the replies: the keys of KEYS are concatenated,
+// the sizes of DBSIZE are added up, otherwise the first error reply or the last reply is returned.
+func (rc *ringConn) fanOut(cmd string, args []interface{}) (interface{}, error) {
+	var keys []interface{}
+	var size int64
+	var last, failed interface{}
+	sent := false
+	for _, s := range rc.shards {
+		if !s.live(rc.opt.HeartbeatFailures) || s.cn == nil {
+			continue
+		}
+		rsp, err := s.cn.Send(cmd, args...)
+		if err != nil {
+			rc.fail(s, err)
+			return nil, err
+		}
+		sent = true
+		if _, ok := rsp.(error); ok && failed == nil {
+			failed = rsp
+		}
+		switch v := rsp.(type) {
+		case []interface{}:
+			keys = append(keys, v...)
+		case []byte:
+			if n, err := Int64(v); err == nil {
+				size += n
+			}
+		}
+		last = rsp
+	}
+	switch {
+	case !sent:
+		return nil, errors.New("redis: all ring shards are down.")
+	case failed != nil:
+		return failed, nil
+	case strings.ToUpper(cmd) == "KEYS":
+		return append([]interface{}{}, keys...), nil
+	case strings.ToUpper(cmd) == "DBSIZE":
+		return []byte(strconv.FormatInt(size, 10)), nil
+	}
+	return last, nil
+}
+
+// checkPipeline rejects the pipelines with a command of a transaction.
+func (rc *ringConn) checkPipeline(cmds []*CmdInfo) error {
+	for _, c := range cmds {
+		if txCommands[strings.ToUpper(c.Name)] {
+			return errRingTx
+		}
+	}
+	return nil
+}
+
+// Pipe queues the command on its shard, the replies are received in the order of the commands.
+func (rc *ringConn) Pipe(cmd string, args ...interface{}) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if txCommands[strings.ToUpper(cmd)] {
+		return errRingTx
+	}
+	s, err := rc.shard(cmd, args)
+	if err != nil {
+		return err
+	}
+	if err := s.cn.Pipe(cmd, args...); err != nil {
+		rc.fail(s, err)
+		return err
+	}
+	s.pending++
+	rc.piped = append(rc.piped, s)
+	return nil
+}
+
+func (rc *ringConn) Flush() (err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, s := range rc.shards {
+		if s.pending == 0 || s.cn == nil {
+			continue
+		}
+		if e := s.cn.Flush(); e != nil {
+			rc.fail(s, e)
+			err = e
+		}
+	}
+	return err
+}
+
+func (rc *ringConn) Receive() (reply interface{}, err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.piped) == 0 {
+		return nil, errors.New("redis: no pending reply.")
+	}
+	s := rc.piped[0]
+	rc.piped = rc.piped[1:]
+	s.pending--
+	if s.cn == nil {
+		return nil, fmt.Errorf("redis: ring shard %s is down.", s.name)
+	}
+	reply, err = s.cn.Receive()
+	if err != nil {
+		rc.fail(s, err)
+	}
+	return reply, err
+}
+
+func (rc *ringConn) Close() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	select {
+	case <-rc.done:
+		return errors.New("redis: client is closed.")
+	default:
+		close(rc.done)
+	}
+	for _, s := range rc.shards {
+		if s.cn != nil {
+			s.cn.Close()
+			s.cn = nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"fmt"
+	"github.com/qqbuby/goredis/redis"
+	"testing"
+)
+
+func TestRingClient(t *testing.T) {
+	const (
+		key   = "TEST:RING"
+		value = key
+	)
+	ring, err := redis.NewRingClient(map[string]string{"shard1": url, "shard2": url}, redis.RingOptions{})
+	if err != nil {
+		t.Fatalf("NewRingClient: %s", err.Error())
+	}
+	defer ring.Close()
+
+	s, _ := ring.Set(key, value)
+	if s != "OK" {
+		t.Errorf("RingClient did not work properly. E:%s, R:%s", "OK", s)
+	}
+	v, _ := ring.Get(key)
+	if v != value {
+		t.Errorf("RingClient did not work properly. E:%s, R:%v", value, v)
+	}
+	n, _ := ring.Del(key)
+	if n != 1 {
+		t.Errorf("RingClient did not work properly. E:%d, R:%d", 1, n)
+	}
+}
+
+func TestRingClientDown(t *testing.T) {
+	ring, err := redis.NewRingClient(map[string]string{"down": "tcp://127.0.0.1:1"}, redis.RingOptions{})
+	if err != nil {
+		t.Fatalf("NewRingClient: %s", err.Error())
+	}
+	defer ring.Close()
+
+	if _, err := ring.Get("TEST:RING:DOWN"); err == nil {
+		t.Error("RingClient did not work properly: all shards are down.")
+	}
+}
+
+func TestRingClientShards(t *testing.T) {
+	srv1, cli1 := newScratchClient(t)
+	srv2, cli2 := newScratchClient(t)
+	ring, err := redis.NewRingClient(map[string]string{"shard1": srv1.URL(), "shard2": srv2.URL()}, redis.RingOptions{})
+	if err != nil {
+		t.Fatalf("NewRingClient: %s", err.Error())
+	}
+	defer ring.Close()
+
+	const n = 64
+	counts := [2]int{}
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("TEST:RING:SHARDS:%d", i)
+		if s, err := ring.Set(key, i); s != "OK" || err != nil {
+			t.Fatalf("RingClient did not work properly. E:%s, R:%s %v", "OK", s, err)
+		}
+		n1, _ := cli1.Exists(key)
+		n2, _ := cli2.Exists(key)
+		if n1+n2 != 1 {
+			t.Errorf("RingClient did not work properly. E:%s on one shard, R:%d, %d", key, n1, n2)
+		}
+		counts[0] += n1
+		counts[1] += n2
+	}
+	if counts[0] == 0 || counts[1] == 0 {
+		t.Errorf("RingClient did not work properly. E:keys on both shards, R:%v", counts)
+	}
+	size, err := ring.DbSize()
+	if size != n || err != nil {
+		t.Errorf("RingClient did not work properly. E:%d, R:%d %v", n, size, err)
+	}
+	keys, err := ring.Keys("TEST:RING:SHARDS:*")
+	if len(keys) != n || err != nil {
+		t.Errorf("RingClient did not work properly. E:%d keys, R:%d %v", n, len(keys), err)
+	}
+	if s, err := ring.FlushDb(false); s != "OK" || err != nil {
+		t.Errorf("RingClient did not work properly. E:%s, R:%s %v", "OK", s, err)
+	}
+	size1, _ := cli1.DbSize()
+	size2, _ := cli2.DbSize()
+	if size1 != 0 || size2 != 0 {
+		t.Errorf("RingClient did not work properly. E:empty shards, R:%d, %d", size1, size2)
+	}
+}
+
+func TestRingClientTx(t *testing.T) {
+	srv1, _ := newScratchClient(t)
+	srv2, _ := newScratchClient(t)
+	ring, err := redis.NewRingClient(map[string]string{"shard1": srv1.URL(), "shard2": srv2.URL()}, redis.RingOptions{})
+	if err != nil {
+		t.Fatalf("NewRingClient: %s", err.Error())
+	}
+	defer ring.Close()
+
+	tx := ring.TxPipeline()
+	for i := 0; i < 8; i++ {
+		tx.Queue("SET", fmt.Sprintf("TEST:RING:TX:%d", i), i)
+	}
+	if _, err := tx.Exec(); err == nil {
+		t.Error("RingClient did not work properly: a transaction was sent.")
+	}
+	p := ring.Pipeline()
+	p.Queue("SET", "TEST:RING:TX", 1)
+	p.Queue("WATCH", "TEST:RING:TX")
+	if _, err := p.Exec(); err == nil {
+		t.Error("RingClient did not work properly: WATCH was pipelined.")
+	}
+	if _, err := ring.Send("MULTI"); err == nil {
+		t.Error("RingClient did not work properly: MULTI was sent.")
+	}
+	size, err := ring.DbSize()
+	if size != 0 || err != nil {
+		t.Errorf("RingClient did not work properly. E:%d, R:%d %v", 0, size, err)
+	}
+	if s, err := ring.Set("TEST:RING:TX", 1); s != "OK" || err != nil {
+		t.Errorf("RingClient did not work properly. E:%s, R:%s %v", "OK", s, err)
+	}
+}
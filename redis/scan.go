@@ -0,0 +1,394 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// fieldSpec is a field of a struct scanned or flattened, name is the name of its tag, or of the field by default.
+type fieldSpec struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+type structSpec struct {
+	fields []*fieldSpec
+	m      map[string]*fieldSpec
+}
+
+var structSpecs sync.Map // map[reflect.Type]*structSpec
+
+// structSpecOf returns the fields of a struct type. The fields of the embedded structs are fields of
+// the struct, unless it has a field of the same name.
+func structSpecOf(t reflect.Type) *structSpec {
+	if ss, ok := structSpecs.Load(t); ok {
+		return ss.(*structSpec)
+	}
+	ss := &structSpec{m: make(map[string]*fieldSpec)}
+	depth := make(map[string]int)
+	compileStructSpec(t, nil, depth, ss)
+	v, _ := structSpecs.LoadOrStore(t, ss)
+	return v.(*structSpec)
+}
+
+func compileStructSpec(t reflect.Type, index []int, depth map[string]int, ss *structSpec) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("redis")
+		if tag == "-" {
+			continue
+		}
+		fi := append(append(make([]int, 0, len(index)+1), index...), i)
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct && !isScalar(f.Type) {
+			compileStructSpec(f.Type, fi, depth, ss)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		fs := &fieldSpec{name: f.Name, index: fi}
+		if tag != "" {
+			name, opts, _ := strings.Cut(tag, ",")
+			if name != "" {
+				fs.name = name
+			}
+			fs.omitEmpty = opts == "omitempty"
+		}
+		if d, ok := depth[fs.name]; ok && d <= len(index) {
+			continue
+		}
+		if old, ok := ss.m[fs.name]; ok {
+			for j, v := range ss.fields {
+				if v == old {
+					ss.fields = append(ss.fields[:j], ss.fields[j+1:]...)
+					break
+				}
+			}
+		}
+		depth[fs.name] = len(index)
+		ss.m[fs.name] = fs
+		ss.fields = append(ss.fields, fs)
+	}
+}
+
+// isScalar reports whether the values of a struct type are scanned from a single reply, e.g. time.Time.
+func isScalar(t reflect.Type) bool {
+	return t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// ScanStruct scans a RESP Array of field names and values, e.g. the reply of HGETALL, into the struct pointed by dest.
+// A value is scanned into the field whose tag is its name, e.g. `redis:"name"`, or, without a tag, the field
+// of its name. The fields without a value, and the values without a field, are left as they are.
+//
+// The fields are strings, []byte values, numbers, bools, time.Time values, parsed by time.Time.UnmarshalText
+// or from Unix seconds, encoding.TextUnmarshaler values, or pointers to them. The fields of the embedded
+// structs are scanned as the fields of the struct, the tag `redis:"-"` skips a field.
+func ScanStruct(src interface{}, dest interface{}) error {
+	a, err := Values(src)
+	if err != nil {
+		return err
+	}
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr || d.IsNil() || d.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("redis.ScanStruct: dest must be a non-nil pointer to a struct, not %T.", dest)
+	}
+	if len(a)%2 != 0 {
+		return fmt.Errorf("redis.ScanStruct: the array has an odd number of elements, %d.", len(a))
+	}
+	d = d.Elem()
+	ss := structSpecOf(d.Type())
+	for i := 0; i < len(a); i += 2 {
+		name, ok := a[i].([]byte)
+		if !ok {
+			return elementError("ScanStruct", i, convertError("ScanStruct", a[i]))
+		}
+		fs := ss.m[string(name)]
+		if fs == nil {
+			continue
+		}
+		if err := scanValue(d.FieldByIndex(fs.index), a[i+1]); err != nil {
+			return fmt.Errorf("redis.ScanStruct: field %s: %w", fs.name, err)
+		}
+	}
+	return nil
+}
+
+// ScanSlice scans a RESP Array, e.g. the reply of MGET or of SORT with GET, into the slice pointed by dest.
+// For a slice of structs, or of pointers to structs, each struct is scanned from as many consecutive
+// values as fieldNames, the names of the fields of the values, or as the struct has fields without fieldNames:
+//
+//	var users []User
+//	reply, err := client.Send("SORT", "users", "BY", "nosort", "GET", "user:*->name", "GET", "user:*->age")
+//	...
+//	err = redis.ScanSlice(reply, &users, "name", "age")
+//
+// The values are scanned as by ScanStruct, a null value is a zero value.
+func ScanSlice(src interface{}, dest interface{}, fieldNames ...string) error {
+	a, err := Values(src)
+	if err != nil {
+		return err
+	}
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr || d.IsNil() || d.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("redis.ScanSlice: dest must be a non-nil pointer to a slice, not %T.", dest)
+	}
+	d = d.Elem()
+	t := d.Type().Elem()
+	ptr := t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && !isScalar(t.Elem())
+	if ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || isScalar(t) {
+		if len(fieldNames) > 0 {
+			return fmt.Errorf("redis.ScanSlice: fieldNames given for a slice of %s.", t)
+		}
+		s := reflect.MakeSlice(d.Type(), len(a), len(a))
+		for i, v := range a {
+			if err := scanValue(s.Index(i), v); err != nil {
+				return elementError("ScanSlice", i, err)
+			}
+		}
+		d.Set(s)
+		return nil
+	}
+
+	ss := structSpecOf(t)
+	fields := ss.fields
+	if len(fieldNames) > 0 {
+		fields = make([]*fieldSpec, len(fieldNames))
+		for i, name := range fieldNames {
+			if fields[i] = ss.m[name]; fields[i] == nil {
+				return fmt.Errorf("redis.ScanSlice: %s has no field %s.", t, name)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("redis.ScanSlice: %s has no fields.", t)
+	}
+	if len(a)%len(fields) != 0 {
+		return fmt.Errorf("redis.ScanSlice: the array has %d elements, not a multiple of %d.", len(a), len(fields))
+	}
+	n := len(a) / len(fields)
+	s := reflect.MakeSlice(d.Type(), n, n)
+	for i := 0; i < n; i++ {
+		e := s.Index(i)
+		if ptr {
+			e.Set(reflect.New(t))
+			e = e.Elem()
+		}
+		for j, fs := range fields {
+			if err := scanValue(e.FieldByIndex(fs.index), a[i*len(fields)+j]); err != nil {
+				return fmt.Errorf("redis.ScanSlice: element %d: field %s: %w", i*len(fields)+j, fs.name, err)
+			}
+		}
+	}
+	d.Set(s)
+	return nil
+}
+
+// scanMap scans an array of keys and values into the map d, the keys and the values are scanned by scanValue.
+func scanMap(d reflect.Value, p interface{}) error {
+	m, err := Map(p, String, func(v interface{}) (interface{}, error) {
+		return v, nil
+	})
+	if err != nil {
+		return err
+	}
+	t := d.Type()
+	dm := reflect.MakeMapWithSize(t, len(m))
+	for k, v := range m {
+		kv := reflect.New(t.Key()).Elem()
+		if err := scanValue(kv, []byte(k)); err != nil {
+			return fmt.Errorf("redis: key %s: %w", k, err)
+		}
+		vv := reflect.New(t.Elem()).Elem()
+		if err := scanValue(vv, v); err != nil {
+			return fmt.Errorf("redis: key %s: %w", k, err)
+		}
+		dm.SetMapIndex(kv, vv)
+	}
+	d.Set(dm)
+	return nil
+}
+
+// scanValue scans a reply into d, a null reply is a zero value.
+func scanValue(d reflect.Value, p interface{}) error {
+	var b []byte
+	switch v := p.(type) {
+	case []byte:
+		b = v
+	case nil:
+		d.Set(reflect.Zero(d.Type()))
+		return nil
+	case error:
+		return v
+	default:
+		if d.Kind() == reflect.Interface {
+			d.Set(reflect.ValueOf(p))
+			return nil
+		}
+		return fmt.Errorf("redis: cannot scan %s reply into %s.", replyType(p), d.Type())
+	}
+
+	if d.Kind() == reflect.Ptr {
+		if d.IsNil() {
+			d.Set(reflect.New(d.Type().Elem()))
+		}
+		d = d.Elem()
+	}
+	if d.Type() == timeType && isDigits(b) {
+		sec, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return err
+		}
+		d.Set(reflect.ValueOf(time.Unix(sec, 0)))
+		return nil
+	}
+	if u, ok := d.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText(b)
+	}
+
+	switch d.Kind() {
+	case reflect.String:
+		d.SetString(string(b))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(string(b), 10, d.Type().Bits())
+		if err != nil {
+			return err
+		}
+		d.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(string(b), 10, d.Type().Bits())
+		if err != nil {
+			return err
+		}
+		d.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(string(b), d.Type().Bits())
+		if err != nil {
+			return err
+		}
+		d.SetFloat(f)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(string(b))
+		if err != nil {
+			return err
+		}
+		d.SetBool(v)
+	case reflect.Interface:
+		d.Set(reflect.ValueOf(append([]byte{}, b...)))
+	case reflect.Slice:
+		if d.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("redis: cannot scan a string reply into %s.", d.Type())
+		}
+		d.SetBytes(append([]byte{}, b...))
+	default:
+		return fmt.Errorf("redis: cannot scan a string reply into %s.", d.Type())
+	}
+	return nil
+}
+
+func isDigits(b []byte) bool {
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(b) > 0
+}
+
+// Args builds the arguments of a command, e.g. from a struct:
+//
+//	client.Send("HSET", redis.Args{"user:1"}.AddFlat(&user)...)
+type Args []interface{}
+
+// Add returns args with values appended.
+func (args Args) Add(values ...interface{}) Args {
+	return append(args, values...)
+}
+
+// AddFlat returns args with v appended after flattening it:
+//   - a struct, or a pointer to a struct, is flattened into the names and the values of its fields, named as by
+//     ScanStruct. The fields tagged with omitempty whose values are zero, and the nil pointers, are skipped.
+//   - a map is flattened into its keys and values.
+//   - a slice, other than a []byte, or an array is flattened into its elements.
+//
+// Other values, and the time.Time values and the encoding.TextMarshaler values which are flattened into their texts,
+// are appended as they are.
+func (args Args) AddFlat(v interface{}) Args {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Struct:
+		if !isScalar(rv.Type()) {
+			return args.addStruct(rv)
+		}
+	case reflect.Ptr:
+		if !rv.IsNil() && rv.Elem().Kind() == reflect.Struct && !isScalar(rv.Elem().Type()) {
+			return args.addStruct(rv.Elem())
+		}
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			args = append(args, argValue(iter.Key()), argValue(iter.Value()))
+		}
+		return args
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			args = append(args, argValue(rv.Index(i)))
+		}
+		return args
+	}
+	return append(args, argValue(rv))
+}
+
+func (args Args) addStruct(v reflect.Value) Args {
+	for _, fs := range structSpecOf(v.Type()).fields {
+		f := v.FieldByIndex(fs.index)
+		if fs.omitEmpty && f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		args = append(args, fs.name, argValue(f))
+	}
+	return args
+}
+
+// argValue returns the argument of a value, the text of an encoding.TextMarshaler.
+func argValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	p := v.Interface()
+	if m, ok := p.(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return b
+		}
+	}
+	return p
+}
@@ -0,0 +1,110 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package redis_test
+
+import (
+	"github.com/qqbuby/goredis/redis"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type scanBase struct {
+	ID      int64     `redis:"id"`
+	Created time.Time `redis:"created"`
+}
+
+type scanUser struct {
+	scanBase
+	Name    string  `redis:"name"`
+	Age     *int    `redis:"age,omitempty"`
+	Score   float64 `redis:"score"`
+	Admin   bool    `redis:"admin"`
+	IP      net.IP  `redis:"ip"`
+	Ignored string  `redis:"-"`
+	Note    string
+}
+
+func strs(v ...string) []interface{} {
+	a := make([]interface{}, len(v))
+	for i, s := range v {
+		a[i] = []byte(s)
+	}
+	return a
+}
+
+func TestScanStruct(t *testing.T) {
+	var u scanUser
+	reply := strs("id", "7", "created", "1500000000", "name", "roy", "age", "30", "score", "1.5",
+		"admin", "1", "ip", "10.0.0.1", "Ignored", "x", "Note", "n", "unknown", "u")
+	if err := redis.ScanStruct(reply, &u); err != nil {
+		t.Fatalf("ScanStruct: %s", err.Error())
+	}
+	if u.ID != 7 || !u.Created.Equal(time.Unix(1500000000, 0)) || u.Name != "roy" || u.Age == nil || *u.Age != 30 ||
+		u.Score != 1.5 || !u.Admin || !u.IP.Equal(net.ParseIP("10.0.0.1")) || u.Ignored != "" || u.Note != "n" {
+		t.Errorf("ScanStruct did not work properly. R:%+v", u)
+	}
+
+	if err := redis.ScanStruct(strs("age", "old"), &u); err == nil {
+		t.Errorf("ScanStruct did not work properly. E:%s, R:%v", "an error", err)
+	}
+	if err := redis.ScanStruct(nil, &u); err != redis.Nil {
+		t.Errorf("ScanStruct did not work properly. E:%v, R:%v", redis.Nil, err)
+	}
+	if err := redis.ScanStruct(strs("id", "1"), u); err == nil {
+		t.Errorf("ScanStruct did not work properly. E:%s, R:%v", "an error", err)
+	}
+}
+
+func TestScanSlice(t *testing.T) {
+	var users []*scanUser
+	if err := redis.ScanSlice(strs("a", "1", "b", "2"), &users, "name", "id"); err != nil {
+		t.Fatalf("ScanSlice: %s", err.Error())
+	}
+	if len(users) != 2 || users[0].Name != "a" || users[0].ID != 1 || users[1].Name != "b" || users[1].ID != 2 {
+		t.Errorf("ScanSlice did not work properly. R:%+v", users)
+	}
+
+	var ints []int
+	if err := redis.ScanSlice([]interface{}{[]byte("1"), nil, []byte("3")}, &ints); err != nil || !reflect.DeepEqual(ints, []int{1, 0, 3}) {
+		t.Errorf("ScanSlice did not work properly. E:%v, R:%v %v", []int{1, 0, 3}, ints, err)
+	}
+	if err := redis.ScanSlice(strs("a", "1", "b"), &users, "name", "id"); err == nil {
+		t.Errorf("ScanSlice did not work properly. E:%s, R:%v", "an error", err)
+	}
+	if err := redis.ScanSlice(strs("a"), &users, "nothing"); err == nil {
+		t.Errorf("ScanSlice did not work properly. E:%s, R:%v", "an error", err)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	created := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	args := redis.Args{"key"}.AddFlat(&scanUser{scanBase: scanBase{ID: 1, Created: created}, Name: "roy", Note: "n"})
+	text, _ := created.MarshalText()
+	expected := redis.Args{"key", "id", int64(1), "created", text, "name", "roy", "score", float64(0), "admin", false, "ip", []byte(""), "Note", "n"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("AddFlat did not work properly. E:%v, R:%v", expected, args)
+	}
+	if args := (redis.Args{}).Add("a").AddFlat([]int{1, 2}).AddFlat(map[string]int{"k": 3}).AddFlat([]byte("b")); !reflect.DeepEqual(args, redis.Args{"a", 1, 2, "k", 3, []byte("b")}) {
+		t.Errorf("AddFlat did not work properly. E:%v, R:%v", redis.Args{"a", 1, 2, "k", 3, []byte("b")}, args)
+	}
+
+	key := "TEST:ARGS"
+	defer client.Del(key)
+	age := 30
+	user := scanUser{scanBase: scanBase{ID: 2, Created: created}, Name: "roy", Age: &age, Score: 2.5, Admin: true, IP: net.ParseIP("::1")}
+	if _, err := client.Send("HSET", redis.Args{key}.AddFlat(user)...); err != nil {
+		t.Fatalf("HSET: %s", err.Error())
+	}
+	reply, err := client.Send("HGETALL", key)
+	if err != nil {
+		t.Fatalf("HGETALL: %s", err.Error())
+	}
+	var got scanUser
+	if err := redis.ScanStruct(reply, &got); err != nil || !reflect.DeepEqual(got, user) {
+		t.Errorf("AddFlat and ScanStruct did not work properly. E:%+v, R:%+v %v", user, got, err)
+	}
+}
@@ -0,0 +1,83 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package server
+
+import (
+	"bytes"
+	"github.com/qqbuby/goredis/redis/resp"
+)
+
+// ReplyWriter writes the replies of a command. A handler may write any number of replies,
+// e.g. none for a command without reply or one per channel for SUBSCRIBE; an array is written
+// as its header, WriteArray(n), followed by its n elements.
+type ReplyWriter interface {
+	// WriteStatus writes a simple string, e.g. +OK.
+	WriteStatus(s string)
+	// WriteError writes an error, its message starts with the error kind, e.g. "ERR syntax error".
+	WriteError(msg string)
+	// WriteInt writes an integer.
+	WriteInt(n int64)
+	// WriteBulk writes a bulk string.
+	WriteBulk(b []byte)
+	// WriteBulkString writes a bulk string.
+	WriteBulkString(s string)
+	// WriteNull writes a null bulk string, $-1.
+	WriteNull()
+	// WriteNullArray writes a null array, *-1.
+	WriteNullArray()
+	// WriteArray writes the header of an array of n elements.
+	WriteArray(n int)
+	// WriteValue writes a Go value as resp.Writer does: nil, a string or a []byte as a bulk string,
+	// an integer, an error, or a slice of those as an array.
+	WriteValue(v interface{})
+}
+
+// replyBuffer is the ReplyWriter of a command, its replies are written to the connection after the handler returns.
+type replyBuffer struct {
+	b bytes.Buffer
+	w *resp.Writer
+}
+
+func newReplyBuffer() *replyBuffer {
+	rb := &replyBuffer{}
+	rb.w = resp.NewWriter(&rb.b)
+	return rb
+}
+
+func (rb *replyBuffer) WriteStatus(s string) {
+	rb.w.WriteStatus(s)
+}
+
+func (rb *replyBuffer) WriteError(msg string) {
+	rb.w.WriteError(msg)
+}
+
+func (rb *replyBuffer) WriteInt(n int64) {
+	rb.w.WriteInt(n)
+}
+
+func (rb *replyBuffer) WriteBulk(b []byte) {
+	rb.w.WriteBulk(b)
+}
+
+func (rb *replyBuffer) WriteBulkString(s string) {
+	rb.w.WriteBulkString(s)
+}
+
+func (rb *replyBuffer) WriteNull() {
+	rb.w.WriteNull()
+}
+
+func (rb *replyBuffer) WriteNullArray() {
+	rb.w.WriteNullArray()
+}
+
+func (rb *replyBuffer) WriteArray(n int) {
+	rb.w.WriteArray(n)
+}
+
+func (rb *replyBuffer) WriteValue(v interface{}) {
+	rb.w.WriteValue(v)
+}
@@ -0,0 +1,207 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxArgs    = 1024 * 1024 // the maximum number of arguments of a command
+	maxBulkLen = 512 << 20   // the maximum length of an argument, 512MB as Redis
+	maxInline  = 64 << 10    // the maximum length of an inline command
+	bulkChunk  = 64 << 10    // the initial capacity of a big argument, which grows with the data received
+)
+
+// Request is a command received by the server.
+type Request struct {
+	// Name is the command name in upper case, e.g. GET.
+	Name string
+	// Args are the arguments of the command, the name excluded.
+	Args [][]byte
+	// Conn is the connection the command is received on.
+	Conn *Conn
+}
+
+// Context returns the context of the connection, it is done when the connection is closed.
+func (r *Request) Context() context.Context {
+	return r.Conn.ctx
+}
+
+// protocolError is a malformed request, the connection is closed after its error reply.
+type protocolError string
+
+func (e protocolError) Error() string {
+	return "ERR Protocol error: " + string(e)
+}
+
+// readRequest reads a command, either a RESP array of bulk strings or an inline command
+// such as those typed in telnet. It returns nil for an empty inline command and, as Redis,
+// for an array of zero or a negative length, e.g. *-1.
+func readRequest(br *bufio.Reader) ([][]byte, error) {
+	line, err := readLine(br, maxInline)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return splitArgs(string(line))
+	}
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n > maxArgs {
+		return nil, protocolError("invalid multibulk length")
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	// The arguments are not preallocated from n, a client could announce a million of them and send none.
+	var args [][]byte
+	for i := 0; i < n; i++ {
+		line, err := readLine(br, maxInline)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, protocolError("expected '$', got '" + string(line) + "'")
+		}
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil || size < 0 || size > maxBulkLen {
+			return nil, protocolError("invalid bulk length")
+		}
+		b, err := readBulk(br, size)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, b)
+	}
+	return args, nil
+}
+
+// readBulk reads a bulk string of size bytes and its CRLF. The bulk is not allocated from its announced size,
+// a client could announce 512MB and send none, it grows as its chunks are received.
+func readBulk(br *bufio.Reader, size int) ([]byte, error) {
+	n := size + 2
+	b := make([]byte, 0, min(n, bulkChunk))
+	for len(b) < n {
+		if len(b) == cap(b) {
+			b = slices.Grow(b, min(n-len(b), len(b)))
+		}
+		m, err := io.ReadFull(br, b[len(b):min(cap(b), n)])
+		b = b[:len(b)+m]
+		if err != nil {
+			if err == io.EOF && len(b) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	if b[size] != '\r' || b[size+1] != '\n' {
+		return nil, protocolError("invalid bulk terminator")
+	}
+	return b[:size], nil
+}
+
+// readLine reads a line without its CRLF, the line is valid until the next read.
+func readLine(br *bufio.Reader, max int) ([]byte, error) {
+	line, err := br.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// A line longer than the buffer of the reader.
+		b := append([]byte{}, line...)
+		for err == bufio.ErrBufferFull && len(b) <= max {
+			line, err = br.ReadSlice('\n')
+			b = append(b, line...)
+		}
+		if len(b) > max {
+			return nil, protocolError("too big inline request")
+		}
+		line = b
+	}
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	line = line[:len(line)-1]
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+// splitArgs splits an inline command into its arguments, an argument may be quoted:
+// "hello\nworld" with the escapes \n \r \t \b \a \\ \" and \xHH, or 'it\'s' with the escape \'.
+func splitArgs(line string) ([][]byte, error) {
+	var args [][]byte
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return args, nil
+		}
+		var arg []byte
+		switch line[0] {
+		case '"', '\'':
+			q := line[0]
+			i := 1
+			for ; ; i++ {
+				if i >= len(line) {
+					return nil, protocolError("unbalanced quotes in request")
+				}
+				c := line[i]
+				if c == q {
+					break
+				}
+				if c != '\\' || i+1 >= len(line) {
+					arg = append(arg, c)
+					continue
+				}
+				i++
+				if q == '\'' {
+					if line[i] != '\'' {
+						arg = append(arg, '\\')
+					}
+					arg = append(arg, line[i])
+					continue
+				}
+				switch line[i] {
+				case 'n':
+					arg = append(arg, '\n')
+				case 'r':
+					arg = append(arg, '\r')
+				case 't':
+					arg = append(arg, '\t')
+				case 'b':
+					arg = append(arg, '\b')
+				case 'a':
+					arg = append(arg, '\a')
+				case 'x':
+					if h, err := strconv.ParseUint(line[i+1:min(i+3, len(line))], 16, 8); err == nil && i+3 <= len(line) {
+						arg = append(arg, byte(h))
+						i += 2
+						continue
+					}
+					arg = append(arg, 'x')
+				default:
+					arg = append(arg, line[i])
+				}
+			}
+			line = line[i+1:]
+			if line != "" && line[0] != ' ' && line[0] != '\t' {
+				return nil, protocolError("unbalanced quotes in request")
+			}
+		default:
+			i := strings.IndexAny(line, " \t")
+			if i < 0 {
+				i = len(line)
+			}
+			arg, line = []byte(line[:i]), line[i:]
+		}
+		args = append(args, arg)
+	}
+}
@@ -0,0 +1,433 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+// Package server is a framework for the servers speaking RESP: mocks, proxies or custom services.
+// It reads the commands of the connections, RESP arrays or inline commands, dispatches them to a Handler
+// by command name and writes the replies of the handler; the pipelined commands are served in order.
+//
+//	mux := server.NewServeMux()
+//	mux.HandleFunc("PING", func(w server.ReplyWriter, r *server.Request) {
+//		w.WriteStatus("PONG")
+//	})
+//	srv := &server.Server{Addr: "127.0.0.1:6380", Handler: mux}
+//	go srv.ListenAndServe()
+//	...
+//	srv.Shutdown(ctx)
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Shutdown or Close.
+var ErrServerClosed = errors.New("server: Server closed.")
+
+// ErrConnClosed is returned by Conn.Push after the connection is closed.
+var ErrConnClosed = errors.New("server: connection is closed.")
+
+// Handler serves the commands.
+type Handler interface {
+	ServeRESP(w ReplyWriter, r *Request)
+}
+
+// HandlerFunc is a function serving the commands.
+type HandlerFunc func(w ReplyWriter, r *Request)
+
+func (f HandlerFunc) ServeRESP(w ReplyWriter, r *Request) {
+	f(w, r)
+}
+
+// ServeMux dispatches the commands to the handlers registered by command name,
+// the unknown commands are replied with an error.
+type ServeMux struct {
+	mu sync.RWMutex
+	m  map[string]Handler
+}
+
+// NewServeMux returns an empty ServeMux.
+func NewServeMux() *ServeMux {
+	return &ServeMux{m: make(map[string]Handler)}
+}
+
+// Handle registers the handler of a command, the command name is case insensitive.
+func (mux *ServeMux) Handle(name string, h Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.m[strings.ToUpper(name)] = h
+}
+
+// HandleFunc registers the handler function of a command.
+func (mux *ServeMux) HandleFunc(name string, fn func(w ReplyWriter, r *Request)) {
+	mux.Handle(name, HandlerFunc(fn))
+}
+
+func (mux *ServeMux) ServeRESP(w ReplyWriter, r *Request) {
+	mux.mu.RLock()
+	h := mux.m[r.Name]
+	mux.mu.RUnlock()
+	if h == nil {
+		w.WriteError(fmt.Sprintf("ERR unknown command '%s'", r.Name))
+		return
+	}
+	h.ServeRESP(w, r)
+}
+
+// Server serves the RESP connections, its zero value is ready to serve on ":6379".
+type Server struct {
+	// Addr is the TCP address of ListenAndServe, ":6379" if empty.
+	Addr string
+	// Handler serves the commands, every command is unknown if it is nil.
+	Handler Handler
+	// IdleTimeout closes the connections which send no command for IdleTimeout. Zero means no timeout.
+	IdleTimeout time.Duration
+	// OnConnect is called for a new connection before its first command is read, e.g. to set its Value.
+	OnConnect func(c *Conn)
+	// OnDisconnect is called when a connection is closed.
+	OnDisconnect func(c *Conn)
+	// Logger logs the connections and the protocol errors, nil disables the logging.
+	Logger *slog.Logger
+
+	mu        sync.Mutex
+	listeners map[net.Listener]bool
+	conns     map[*Conn]bool
+	nextID    int64
+	closing   atomic.Bool
+}
+
+// ListenAndServe listens on the TCP address Addr and serves its connections.
+func (srv *Server) ListenAndServe() error {
+	if srv.closing.Load() {
+		return ErrServerClosed
+	}
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":6379"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(l)
+}
+
+// ListenAndServe listens on a TCP address and serves its connections with a handler.
+func ListenAndServe(addr string, h Handler) error {
+	srv := &Server{Addr: addr, Handler: h}
+	return srv.ListenAndServe()
+}
+
+// Serve serves the connections of a listener, each on its own goroutine, until Shutdown or Close.
+// The listener is closed when Serve returns.
+func (srv *Server) Serve(l net.Listener) error {
+	srv.mu.Lock()
+	if srv.closing.Load() {
+		srv.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	if srv.listeners == nil {
+		srv.listeners = make(map[net.Listener]bool)
+	}
+	srv.listeners[l] = true
+	srv.mu.Unlock()
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.listeners, l)
+		srv.mu.Unlock()
+		l.Close()
+	}()
+
+	var delay time.Duration
+	for {
+		cn, err := l.Accept()
+		if err != nil {
+			if srv.closing.Load() {
+				return ErrServerClosed
+			}
+			if temporary(err) {
+				// Back off on the temporary errors, e.g. too many open files.
+				if delay = max(delay*2, time.Millisecond*5); delay > time.Second {
+					delay = time.Second
+				}
+				logAttr(srv.Logger, slog.LevelWarn, "server: accept failed", "error", err, "retry", delay)
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		c := srv.newConn(cn)
+		if c == nil {
+			cn.Close()
+			return ErrServerClosed
+		}
+		go c.serve()
+	}
+}
+
+// temporary reports whether an error of Accept is temporary: a timeout, too many open files
+// or a connection aborted before it is accepted.
+func temporary(err error) bool {
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) || errors.Is(err, syscall.ECONNABORTED)
+}
+
+func (srv *Server) newConn(cn net.Conn) *Conn {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.closing.Load() {
+		return nil
+	}
+	if srv.conns == nil {
+		srv.conns = make(map[*Conn]bool)
+	}
+	srv.nextID++
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Conn{srv: srv, id: srv.nextID, cn: cn, bw: bufio.NewWriter(cn), ctx: ctx, cancel: cancel}
+	c.idle.Store(true)
+	srv.conns[c] = true
+	return c
+}
+
+// Close closes the listeners and the connections immediately, the commands being served are interrupted.
+func (srv *Server) Close() error {
+	srv.closing.Store(true)
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for l := range srv.listeners {
+		l.Close()
+	}
+	for c := range srv.conns {
+		c.cn.Close()
+	}
+	return nil
+}
+
+// Shutdown closes the listeners, then closes each connection once it has replied to the commands it has sent,
+// and waits until all the connections are closed. When ctx is done first, the connections left are closed
+// as by Close and the error of ctx is returned.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.closing.Store(true)
+	srv.mu.Lock()
+	for l := range srv.listeners {
+		l.Close()
+	}
+	srv.mu.Unlock()
+
+	t := time.NewTicker(time.Millisecond * 10)
+	defer t.Stop()
+	for {
+		if srv.closeIdle() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			srv.Close()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// closeIdle closes the connections waiting for a command, it reports whether no connection is left.
+func (srv *Server) closeIdle() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for c := range srv.conns {
+		if c.idle.Load() {
+			c.cn.Close()
+		}
+	}
+	return len(srv.conns) == 0
+}
+
+// Conn is a connection of a server.
+type Conn struct {
+	srv    *Server
+	id     int64
+	cn     net.Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+	idle   atomic.Bool
+	value  atomic.Value
+
+	mu       sync.Mutex // guards bw and the fields below
+	bw       *bufio.Writer
+	serving  bool
+	deferred []byte
+	quit     bool
+	closed   bool
+}
+
+// ID returns the id of the connection, unique for the server.
+func (c *Conn) ID() int64 {
+	return c.id
+}
+
+// RemoteAddr returns the address of the client.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.cn.RemoteAddr()
+}
+
+// LocalAddr returns the address of the server the client is connected to.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.cn.LocalAddr()
+}
+
+// Value returns the per-connection state set by SetValue.
+func (c *Conn) Value() interface{} {
+	v, _ := c.value.Load().(valueBox)
+	return v.v
+}
+
+// SetValue sets the per-connection state of the handlers.
+func (c *Conn) SetValue(v interface{}) {
+	c.value.Store(valueBox{v})
+}
+
+type valueBox struct {
+	v interface{}
+}
+
+// Close closes the connection after the replies of the command being served, e.g. for QUIT.
+func (c *Conn) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.serving {
+		c.quit = true
+		return
+	}
+	c.cn.Close()
+}
+
+// Push writes out-of-band replies, e.g. Pub/Sub messages, from any goroutine. When a command of the connection
+// is being served, they are written after its replies.
+func (c *Conn) Push(fn func(w ReplyWriter)) error {
+	w := newReplyBuffer()
+	fn(w)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrConnClosed
+	}
+	if c.serving {
+		c.deferred = append(c.deferred, w.b.Bytes()...)
+		return nil
+	}
+	c.bw.Write(w.b.Bytes())
+	return c.bw.Flush()
+}
+
+func (c *Conn) serve() {
+	srv := c.srv
+	logAttr(srv.Logger, slog.LevelDebug, "server: connected", "id", c.id, "addr", c.RemoteAddr())
+	defer c.close()
+	// A panic of a handler closes its connection only, as net/http.
+	defer func() {
+		if e := recover(); e != nil {
+			logAttr(srv.Logger, slog.LevelError, "server: panic serving connection", "id", c.id, "panic", e, "stack", string(debug.Stack()))
+		}
+	}()
+	if srv.OnConnect != nil {
+		srv.OnConnect(c)
+	}
+	br := bufio.NewReader(c.cn)
+	for {
+		if srv.IdleTimeout > 0 {
+			c.cn.SetReadDeadline(time.Now().Add(srv.IdleTimeout))
+		}
+		c.idle.Store(br.Buffered() == 0)
+		if srv.closing.Load() && br.Buffered() == 0 {
+			return
+		}
+		args, err := readRequest(br)
+		c.idle.Store(false)
+		if err != nil {
+			if e, ok := err.(protocolError); ok {
+				logAttr(srv.Logger, slog.LevelWarn, "server: protocol error", "id", c.id, "error", err)
+				c.mu.Lock()
+				c.bw.WriteString("-" + e.Error() + "\r\n")
+				c.bw.Flush()
+				c.mu.Unlock()
+			} else if err != io.EOF && !srv.closing.Load() {
+				logAttr(srv.Logger, slog.LevelDebug, "server: read failed", "id", c.id, "error", err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if !c.exec(args, br.Buffered() == 0) {
+			return
+		}
+	}
+}
+
+// exec serves a command, its replies are flushed when no pipelined command is buffered.
+// It reports whether the connection is still open.
+func (c *Conn) exec(args [][]byte, flush bool) bool {
+	r := &Request{Name: strings.ToUpper(string(args[0])), Args: args[1:], Conn: c}
+	w := newReplyBuffer()
+	c.mu.Lock()
+	c.serving = true
+	c.mu.Unlock()
+
+	h := c.srv.Handler
+	if h == nil {
+		h = &ServeMux{}
+	}
+	h.ServeRESP(w, r)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.serving = false
+	pushed := len(c.deferred) > 0
+	c.bw.Write(w.b.Bytes())
+	c.bw.Write(c.deferred)
+	c.deferred = nil
+	if flush || pushed || c.quit {
+		if err := c.bw.Flush(); err != nil {
+			return false
+		}
+	}
+	return !c.quit
+}
+
+func (c *Conn) close() {
+	c.mu.Lock()
+	c.closed = true
+	c.bw.Flush()
+	c.mu.Unlock()
+	c.cn.Close()
+	c.cancel()
+	srv := c.srv
+	if srv.OnDisconnect != nil {
+		srv.OnDisconnect(c)
+	}
+	srv.mu.Lock()
+	delete(srv.conns, c)
+	srv.mu.Unlock()
+	logAttr(srv.Logger, slog.LevelDebug, "server: disconnected", "id", c.id, "addr", c.RemoteAddr())
+}
+
+func logAttr(l *slog.Logger, level slog.Level, msg string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.Log(context.Background(), level, msg, args...)
+}
@@ -0,0 +1,303 @@
+// The MIT License (MIT)
+
+// Copyright (c) 2016 Roy Xu
+
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"github.com/qqbuby/goredis/redis"
+	"github.com/qqbuby/goredis/redis/server"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func start(t *testing.T, srv *server.Server) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %s", err.Error())
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(l)
+	}()
+	t.Cleanup(func() {
+		srv.Close()
+		if err := <-done; err != server.ErrServerClosed {
+			t.Errorf("Serve did not work properly. E:%v, R:%v", server.ErrServerClosed, err)
+		}
+	})
+	return l.Addr().String()
+}
+
+func newMux() *server.ServeMux {
+	mux := server.NewServeMux()
+	mux.HandleFunc("PING", func(w server.ReplyWriter, r *server.Request) {
+		w.WriteStatus("PONG")
+	})
+	mux.HandleFunc("ECHO", func(w server.ReplyWriter, r *server.Request) {
+		if len(r.Args) != 1 {
+			w.WriteError("ERR wrong number of arguments for 'echo' command")
+			return
+		}
+		w.WriteBulk(r.Args[0])
+	})
+	mux.HandleFunc("QUIT", func(w server.ReplyWriter, r *server.Request) {
+		w.WriteStatus("OK")
+		r.Conn.Close()
+	})
+	return mux
+}
+
+func TestServeMux(t *testing.T) {
+	addr := start(t, &server.Server{Handler: newMux()})
+	client, err := redis.NewClient("tcp://" + addr)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer client.Close()
+
+	if s, _ := client.Ping(); s != "PONG" {
+		t.Errorf("ServeMux did not work properly. E:%s, R:%s", "PONG", s)
+	}
+	if s, _ := client.Echo("Hello world!"); s != "Hello world!" {
+		t.Errorf("ServeMux did not work properly. E:%s, R:%s", "Hello world!", s)
+	}
+	if rsp, _ := client.Send("GET", "key"); rsp == nil || rsp.(error).Error() != "ERR unknown command 'GET'" {
+		t.Errorf("ServeMux did not work properly. E:%s, R:%v", "ERR unknown command 'GET'", rsp)
+	}
+}
+
+// TestInline sends pipelined inline and RESP commands as a telnet session would.
+func TestInline(t *testing.T) {
+	addr := start(t, &server.Server{Handler: newMux()})
+	cn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial: %s", err.Error())
+	}
+	defer cn.Close()
+
+	io.WriteString(cn, "PING\r\n\r\necho \"a b\\x21\"\n*2\r\n$4\r\nECHO\r\n$1\r\nc\r\nquit\r\n")
+	b, _ := io.ReadAll(bufio.NewReader(cn))
+	const expected = "+PONG\r\n$4\r\na b!\r\n$1\r\nc\r\n+OK\r\n"
+	if string(b) != expected {
+		t.Errorf("Inline did not work properly. E:%q, R:%q", expected, b)
+	}
+}
+
+func TestProtocolError(t *testing.T) {
+	addr := start(t, &server.Server{Handler: newMux()})
+	cn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial: %s", err.Error())
+	}
+	defer cn.Close()
+
+	io.WriteString(cn, "*1\r\n+PING\r\n")
+	b, _ := io.ReadAll(cn)
+	const expected = "-ERR Protocol error: expected '$', got '+PING'\r\n"
+	if string(b) != expected {
+		t.Errorf("ProtocolError did not work properly. E:%q, R:%q", expected, b)
+	}
+}
+
+func TestEmptyArray(t *testing.T) {
+	addr := start(t, &server.Server{Handler: newMux()})
+	cn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial: %s", err.Error())
+	}
+	defer cn.Close()
+
+	io.WriteString(cn, "*-1\r\n*0\r\nPING\r\nQUIT\r\n")
+	b, _ := io.ReadAll(cn)
+	const expected = "+PONG\r\n+OK\r\n"
+	if string(b) != expected {
+		t.Errorf("EmptyArray did not work properly. E:%q, R:%q", expected, b)
+	}
+}
+
+func TestHandlerPanic(t *testing.T) {
+	mux := newMux()
+	mux.HandleFunc("PANIC", func(w server.ReplyWriter, r *server.Request) {
+		panic("PANIC")
+	})
+	addr := start(t, &server.Server{Handler: mux})
+	cn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial: %s", err.Error())
+	}
+	defer cn.Close()
+
+	io.WriteString(cn, "PANIC\r\nPING\r\n")
+	if b, _ := io.ReadAll(cn); len(b) != 0 {
+		t.Errorf("HandlerPanic did not work properly. E:%q, R:%q", "", b)
+	}
+	client, err := redis.NewClient("tcp://" + addr)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer client.Close()
+	if s, _ := client.Ping(); s != "PONG" {
+		t.Errorf("HandlerPanic did not work properly. E:%s, R:%s", "PONG", s)
+	}
+}
+
+func TestConnState(t *testing.T) {
+	mux := server.NewServeMux()
+	mux.HandleFunc("INCR", func(w server.ReplyWriter, r *server.Request) {
+		n := r.Conn.Value().(*int)
+		*n++
+		w.WriteInt(int64(*n))
+	})
+	srv := &server.Server{Handler: mux, OnConnect: func(c *server.Conn) {
+		c.SetValue(new(int))
+	}}
+	addr := start(t, srv)
+	for i := 0; i < 2; i++ {
+		client, err := redis.NewClient("tcp://" + addr)
+		if err != nil {
+			t.Fatalf("NewClient: %s", err.Error())
+		}
+		client.Send("INCR")
+		rsp, _ := client.Send("INCR")
+		if n, _ := redis.Int(rsp); n != 2 {
+			t.Errorf("Conn.Value did not work properly. E:%d, R:%d", 2, n)
+		}
+		client.Close()
+	}
+}
+
+func TestPush(t *testing.T) {
+	conns := make(chan *server.Conn, 1)
+	mux := server.NewServeMux()
+	mux.HandleFunc("SUBSCRIBE", func(w server.ReplyWriter, r *server.Request) {
+		w.WriteValue([]interface{}{"subscribe", r.Args[0], 1})
+		conns <- r.Conn
+	})
+	addr := start(t, &server.Server{Handler: mux})
+	sc, err := redis.NewPubSub("tcp://" + addr)
+	if err != nil {
+		t.Fatalf("NewPubSub: %s", err.Error())
+	}
+	defer sc.Close()
+
+	sc.Subscribe("c1")
+	sc.Receive()
+	(<-conns).Push(func(w server.ReplyWriter) {
+		w.WriteValue([]interface{}{"message", "c1", "Hi"})
+	})
+	if m := sc.Receive(); m != (redis.Message{Channel: "c1", Text: "Hi"}) {
+		t.Errorf("Push did not work properly. R:%v", m)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	started := make(chan bool)
+	mux := server.NewServeMux()
+	mux.HandleFunc("SLOW", func(w server.ReplyWriter, r *server.Request) {
+		started <- true
+		time.Sleep(time.Millisecond * 50)
+		w.WriteStatus("OK")
+	})
+	srv := &server.Server{Handler: mux}
+	addr := start(t, srv)
+	idle, err := redis.NewClient("tcp://" + addr)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer idle.Close()
+	busy, _ := redis.NewClient("tcp://" + addr)
+	defer busy.Close()
+
+	reply := make(chan interface{})
+	go func() {
+		rsp, _ := busy.Send("SLOW")
+		reply <- rsp
+	}()
+	<-started
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown did not work properly: %s", err.Error())
+	}
+	if s, _ := redis.String(<-reply); s != "OK" {
+		t.Errorf("Shutdown did not work properly. E:%s, R:%s", "OK", s)
+	}
+	if _, err := idle.Send("SLOW"); err == nil {
+		t.Error("Shutdown did not work properly: the idle connection is not closed.")
+	}
+}
+
+// failingListener fails its first Accept with err.
+type failingListener struct {
+	net.Listener
+	err error
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	if err := l.err; err != nil {
+		l.err = nil
+		return nil, err
+	}
+	return l.Listener.Accept()
+}
+
+func TestAcceptTemporary(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %s", err.Error())
+	}
+	srv := &server.Server{Handler: newMux()}
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(&failingListener{Listener: l, err: &net.OpError{Op: "accept", Net: "tcp", Err: syscall.EMFILE}})
+	}()
+	defer func() {
+		srv.Close()
+		if err := <-done; err != server.ErrServerClosed {
+			t.Errorf("Serve did not work properly. E:%v, R:%v", server.ErrServerClosed, err)
+		}
+	}()
+
+	client, err := redis.NewClient("tcp://" + l.Addr().String())
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer client.Close()
+	if s, _ := client.Ping(); s != "PONG" {
+		t.Errorf("Serve did not work properly. E:%s, R:%s", "PONG", s)
+	}
+}
+
+func TestBigBulk(t *testing.T) {
+	addr := start(t, &server.Server{Handler: newMux()})
+	client, err := redis.NewClient("tcp://" + addr)
+	if err != nil {
+		t.Fatalf("NewClient: %s", err.Error())
+	}
+	defer client.Close()
+	s := strings.Repeat("x", 200<<10)
+	if r, _ := client.Echo(s); r != s {
+		t.Errorf("BigBulk did not work properly. E:%d bytes, R:%d bytes", len(s), len(r))
+	}
+
+	// The announced length of a bulk is not allocated before it is received.
+	cn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial: %s", err.Error())
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	io.WriteString(cn, "*2\r\n$4\r\nECHO\r\n$500000000\r\nab")
+	time.Sleep(time.Millisecond * 100)
+	runtime.ReadMemStats(&after)
+	cn.Close()
+	if n := after.TotalAlloc - before.TotalAlloc; n > 16<<20 {
+		t.Errorf("BigBulk did not work properly: %d bytes allocated.", n)
+	}
+}